@@ -52,6 +52,7 @@ func (*PingRequest) Descriptor() ([]byte, []int) {
 type PingReply struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Node          string                 `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -92,9 +93,18 @@ func (x *PingReply) GetStatus() string {
 	return ""
 }
 
+func (x *PingReply) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
 type DeployRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Yaml          string                 `protobuf:"bytes,1,opt,name=yaml,proto3" json:"yaml,omitempty"`
+	BaseDir       string                 `protobuf:"bytes,2,opt,name=base_dir,json=baseDir,proto3" json:"base_dir,omitempty"`
+	Replace       bool                   `protobuf:"varint,3,opt,name=replace,proto3" json:"replace,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -135,9 +145,24 @@ func (x *DeployRequest) GetYaml() string {
 	return ""
 }
 
+func (x *DeployRequest) GetBaseDir() string {
+	if x != nil {
+		return x.BaseDir
+	}
+	return ""
+}
+
+func (x *DeployRequest) GetReplace() bool {
+	if x != nil {
+		return x.Replace
+	}
+	return false
+}
+
 type DeployReply struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version       uint32                 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -178,6 +203,13 @@ func (x *DeployReply) GetId() string {
 	return ""
 }
 
+func (x *DeployReply) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
 type PauseRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -264,60 +296,1081 @@ func (x *PauseReply) GetOk() bool {
 	return false
 }
 
-var File_v1_control_proto protoreflect.FileDescriptor
+type ResumeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_v1_control_proto_rawDesc = "" +
-	"\n" +
-	"\x10v1/control.proto\x12\tquanta.v1\"\r\n" +
-	"\vPingRequest\"#\n" +
-	"\tPingReply\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\tR\x06status\"#\n" +
-	"\rDeployRequest\x12\x12\n" +
-	"\x04yaml\x18\x01 \x01(\tR\x04yaml\"\x1d\n" +
-	"\vDeployReply\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"\x1e\n" +
-	"\fPauseRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"\x1c\n" +
-	"\n" +
-	"PauseReply\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok2\xc4\x01\n" +
-	"\aControl\x124\n" +
-	"\x04Ping\x12\x16.quanta.v1.PingRequest\x1a\x14.quanta.v1.PingReply\x12B\n" +
-	"\x0eDeployPipeline\x12\x18.quanta.v1.DeployRequest\x1a\x16.quanta.v1.DeployReply\x12?\n" +
-	"\rPausePipeline\x12\x17.quanta.v1.PauseRequest\x1a\x15.quanta.v1.PauseReplyB\x18Z\x16quanta/api/proto/v1;pbb\x06proto3"
+func (x *ResumeRequest) Reset() {
+	*x = ResumeRequest{}
+	mi := &file_v1_control_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
-var (
-	file_v1_control_proto_rawDescOnce sync.Once
-	file_v1_control_proto_rawDescData []byte
-)
+func (x *ResumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
-func file_v1_control_proto_rawDescGZIP() []byte {
-	file_v1_control_proto_rawDescOnce.Do(func() {
-		file_v1_control_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_v1_control_proto_rawDesc), len(file_v1_control_proto_rawDesc)))
-	})
-	return file_v1_control_proto_rawDescData
+func (*ResumeRequest) ProtoMessage() {}
+
+func (x *ResumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-var file_v1_control_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
-var file_v1_control_proto_goTypes = []any{
-	(*PingRequest)(nil),
-	(*PingReply)(nil),
-	(*DeployRequest)(nil),
-	(*DeployReply)(nil),
-	(*PauseRequest)(nil),
-	(*PauseReply)(nil),
+func (*ResumeRequest) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{6}
 }
-var file_v1_control_proto_depIdxs = []int32{
-	0,
-	2,
-	4,
-	1,
-	3,
-	5,
-	3,
-	0,
-	0,
-	0,
+
+func (x *ResumeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ResumeReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeReply) Reset() {
+	*x = ResumeReply{}
+	mi := &file_v1_control_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeReply) ProtoMessage() {}
+
+func (x *ResumeReply) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*ResumeReply) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ResumeReply) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_v1_control_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteReply) Reset() {
+	*x = DeleteReply{}
+	mi := &file_v1_control_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteReply) ProtoMessage() {}
+
+func (x *DeleteReply) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*DeleteReply) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeleteReply) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+type RollbackRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version       uint32                 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RollbackRequest) Reset() {
+	*x = RollbackRequest{}
+	mi := &file_v1_control_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RollbackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollbackRequest) ProtoMessage() {}
+
+func (x *RollbackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*RollbackRequest) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RollbackRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RollbackRequest) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type RollbackReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version       uint32                 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RollbackReply) Reset() {
+	*x = RollbackReply{}
+	mi := &file_v1_control_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RollbackReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollbackReply) ProtoMessage() {}
+
+func (x *RollbackReply) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*RollbackReply) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RollbackReply) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RollbackReply) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type HistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryRequest) Reset() {
+	*x = HistoryRequest{}
+	mi := &file_v1_control_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryRequest) ProtoMessage() {}
+
+func (x *HistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *HistoryRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type PipelineHistory struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Current       uint32                 `protobuf:"varint,2,opt,name=current,proto3" json:"current,omitempty"`
+	Paused        bool                   `protobuf:"varint,3,opt,name=paused,proto3" json:"paused,omitempty"`
+	Versions      []*PipelineVersion     `protobuf:"bytes,4,rep,name=versions,proto3" json:"versions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PipelineHistory) Reset() {
+	*x = PipelineHistory{}
+	mi := &file_v1_control_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PipelineHistory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PipelineHistory) ProtoMessage() {}
+
+func (x *PipelineHistory) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*PipelineHistory) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PipelineHistory) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PipelineHistory) GetCurrent() uint32 {
+	if x != nil {
+		return x.Current
+	}
+	return 0
+}
+
+func (x *PipelineHistory) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *PipelineHistory) GetVersions() []*PipelineVersion {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type PipelineVersion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       uint32                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	DeployedAtMs  int64                  `protobuf:"varint,2,opt,name=deployed_at_ms,json=deployedAtMs,proto3" json:"deployed_at_ms,omitempty"`
+	DeployedBy    string                 `protobuf:"bytes,3,opt,name=deployed_by,json=deployedBy,proto3" json:"deployed_by,omitempty"`
+	RollbackOf    uint32                 `protobuf:"varint,4,opt,name=rollback_of,json=rollbackOf,proto3" json:"rollback_of,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PipelineVersion) Reset() {
+	*x = PipelineVersion{}
+	mi := &file_v1_control_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PipelineVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PipelineVersion) ProtoMessage() {}
+
+func (x *PipelineVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*PipelineVersion) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PipelineVersion) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *PipelineVersion) GetDeployedAtMs() int64 {
+	if x != nil {
+		return x.DeployedAtMs
+	}
+	return 0
+}
+
+func (x *PipelineVersion) GetDeployedBy() string {
+	if x != nil {
+		return x.DeployedBy
+	}
+	return ""
+}
+
+func (x *PipelineVersion) GetRollbackOf() uint32 {
+	if x != nil {
+		return x.RollbackOf
+	}
+	return 0
+}
+
+type ListPipelinesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPipelinesRequest) Reset() {
+	*x = ListPipelinesRequest{}
+	mi := &file_v1_control_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPipelinesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPipelinesRequest) ProtoMessage() {}
+
+func (x *ListPipelinesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*ListPipelinesRequest) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{15}
+}
+
+type ListPipelinesReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pipelines     []*PipelineStatus      `protobuf:"bytes,1,rep,name=pipelines,proto3" json:"pipelines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPipelinesReply) Reset() {
+	*x = ListPipelinesReply{}
+	mi := &file_v1_control_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPipelinesReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPipelinesReply) ProtoMessage() {}
+
+func (x *ListPipelinesReply) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*ListPipelinesReply) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListPipelinesReply) GetPipelines() []*PipelineStatus {
+	if x != nil {
+		return x.Pipelines
+	}
+	return nil
+}
+
+type PipelineStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PipelineStatusRequest) Reset() {
+	*x = PipelineStatusRequest{}
+	mi := &file_v1_control_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PipelineStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PipelineStatusRequest) ProtoMessage() {}
+
+func (x *PipelineStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*PipelineStatusRequest) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PipelineStatusRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type PipelineStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State         string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Stages        []*StageStatus         `protobuf:"bytes,3,rep,name=stages,proto3" json:"stages,omitempty"`
+	Lag           int64                  `protobuf:"varint,4,opt,name=lag,proto3" json:"lag,omitempty"`
+	InFlight      int64                  `protobuf:"varint,5,opt,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty"`
+	LastError     string                 `protobuf:"bytes,6,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	StartedAtMs   int64                  `protobuf:"varint,7,opt,name=started_at_ms,json=startedAtMs,proto3" json:"started_at_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PipelineStatus) Reset() {
+	*x = PipelineStatus{}
+	mi := &file_v1_control_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PipelineStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PipelineStatus) ProtoMessage() {}
+
+func (x *PipelineStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*PipelineStatus) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PipelineStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PipelineStatus) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *PipelineStatus) GetStages() []*StageStatus {
+	if x != nil {
+		return x.Stages
+	}
+	return nil
+}
+
+func (x *PipelineStatus) GetLag() int64 {
+	if x != nil {
+		return x.Lag
+	}
+	return 0
+}
+
+func (x *PipelineStatus) GetInFlight() int64 {
+	if x != nil {
+		return x.InFlight
+	}
+	return 0
+}
+
+func (x *PipelineStatus) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *PipelineStatus) GetStartedAtMs() int64 {
+	if x != nil {
+		return x.StartedAtMs
+	}
+	return 0
+}
+
+type StageStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Healthy       bool                   `protobuf:"varint,2,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageStatus) Reset() {
+	*x = StageStatus{}
+	mi := &file_v1_control_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageStatus) ProtoMessage() {}
+
+func (x *StageStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*StageStatus) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *StageStatus) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *StageStatus) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *StageStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type TapRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Point         string                 `protobuf:"bytes,2,opt,name=point,proto3" json:"point,omitempty"`
+	Filter        *TapFilter             `protobuf:"bytes,3,opt,name=filter,proto3" json:"filter,omitempty"`
+	SampleRate    float64                `protobuf:"fixed64,4,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	Buffer        uint32                 `protobuf:"varint,5,opt,name=buffer,proto3" json:"buffer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TapRequest) Reset() {
+	*x = TapRequest{}
+	mi := &file_v1_control_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TapRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TapRequest) ProtoMessage() {}
+
+func (x *TapRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*TapRequest) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *TapRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TapRequest) GetPoint() string {
+	if x != nil {
+		return x.Point
+	}
+	return ""
+}
+
+func (x *TapRequest) GetFilter() *TapFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *TapRequest) GetSampleRate() float64 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *TapRequest) GetBuffer() uint32 {
+	if x != nil {
+		return x.Buffer
+	}
+	return 0
+}
+
+type TapFilter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           []byte                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TapFilter) Reset() {
+	*x = TapFilter{}
+	mi := &file_v1_control_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TapFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TapFilter) ProtoMessage() {}
+
+func (x *TapFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*TapFilter) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *TapFilter) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *TapFilter) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+type TapFrame struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Point         string                 `protobuf:"bytes,1,opt,name=point,proto3" json:"point,omitempty"`
+	Frame         *Frame                 `protobuf:"bytes,2,opt,name=frame,proto3" json:"frame,omitempty"`
+	Dropped       uint64                 `protobuf:"varint,3,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TapFrame) Reset() {
+	*x = TapFrame{}
+	mi := &file_v1_control_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TapFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TapFrame) ProtoMessage() {}
+
+func (x *TapFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_control_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*TapFrame) Descriptor() ([]byte, []int) {
+	return file_v1_control_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *TapFrame) GetPoint() string {
+	if x != nil {
+		return x.Point
+	}
+	return ""
+}
+
+func (x *TapFrame) GetFrame() *Frame {
+	if x != nil {
+		return x.Frame
+	}
+	return nil
+}
+
+func (x *TapFrame) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+var File_v1_control_proto protoreflect.FileDescriptor
+
+const file_v1_control_proto_rawDesc = "" +
+	"\n" +
+	"\x10v1/control.proto\x12\tquanta.v1\x1a\x0ev1/frame.proto\"\r\n" +
+	"\vPingRequest\"7\n" +
+	"\tPingReply\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x12\n" +
+	"\x04node\x18\x02 \x01(\tR\x04node\"X\n" +
+	"\rDeployRequest\x12\x12\n" +
+	"\x04yaml\x18\x01 \x01(\tR\x04yaml\x12\x19\n" +
+	"\bbase_dir\x18\x02 \x01(\tR\abaseDir\x12\x18\n" +
+	"\areplace\x18\x03 \x01(\bR\areplace\"7\n" +
+	"\vDeployReply\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\rR\aversion\"\x1e\n" +
+	"\fPauseRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x1c\n" +
+	"\n" +
+	"PauseReply\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"\x1f\n" +
+	"\rResumeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x1d\n" +
+	"\vResumeReply\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"\x1f\n" +
+	"\rDeleteRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x1d\n" +
+	"\vDeleteReply\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\";\n" +
+	"\x0fRollbackRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\rR\aversion\"9\n" +
+	"\rRollbackReply\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\rR\aversion\" \n" +
+	"\x0eHistoryRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x8b\x01\n" +
+	"\x0fPipelineHistory\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\acurrent\x18\x02 \x01(\rR\acurrent\x12\x16\n" +
+	"\x06paused\x18\x03 \x01(\bR\x06paused\x126\n" +
+	"\bversions\x18\x04 \x03(\v2\x1a.quanta.v1.PipelineVersionR\bversions\"\x93\x01\n" +
+	"\x0fPipelineVersion\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\rR\aversion\x12$\n" +
+	"\x0edeployed_at_ms\x18\x02 \x01(\x03R\fdeployedAtMs\x12\x1f\n" +
+	"\vdeployed_by\x18\x03 \x01(\tR\n" +
+	"deployedBy\x12\x1f\n" +
+	"\vrollback_of\x18\x04 \x01(\rR\n" +
+	"rollbackOf\"\x16\n" +
+	"\x14ListPipelinesRequest\"M\n" +
+	"\x12ListPipelinesReply\x127\n" +
+	"\tpipelines\x18\x01 \x03(\v2\x19.quanta.v1.PipelineStatusR\tpipelines\"'\n" +
+	"\x15PipelineStatusRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xd8\x01\n" +
+	"\x0ePipelineStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x12.\n" +
+	"\x06stages\x18\x03 \x03(\v2\x16.quanta.v1.StageStatusR\x06stages\x12\x10\n" +
+	"\x03lag\x18\x04 \x01(\x03R\x03lag\x12\x1b\n" +
+	"\tin_flight\x18\x05 \x01(\x03R\binFlight\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x06 \x01(\tR\tlastError\x12\"\n" +
+	"\rstarted_at_ms\x18\a \x01(\x03R\vstartedAtMs\"Q\n" +
+	"\vStageStatus\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\ahealthy\x18\x02 \x01(\bR\ahealthy\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\x99\x01\n" +
+	"\n" +
+	"TapRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05point\x18\x02 \x01(\tR\x05point\x12,\n" +
+	"\x06filter\x18\x03 \x01(\v2\x14.quanta.v1.TapFilterR\x06filter\x12\x1f\n" +
+	"\vsample_rate\x18\x04 \x01(\x01R\n" +
+	"sampleRate\x12\x16\n" +
+	"\x06buffer\x18\x05 \x01(\rR\x06buffer\"\x96\x01\n" +
+	"\tTapFilter\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\fR\x03key\x12;\n" +
+	"\aheaders\x18\x02 \x03(\v2!.quanta.v1.TapFilter.HeadersEntryR\aheaders\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"b\n" +
+	"\bTapFrame\x12\x14\n" +
+	"\x05point\x18\x01 \x01(\tR\x05point\x12&\n" +
+	"\x05frame\x18\x02 \x01(\v2\x10.quanta.v1.FrameR\x05frame\x12\x18\n" +
+	"\adropped\x18\x03 \x01(\x04R\adropped2\xbb\x05\n" +
+	"\aControl\x124\n" +
+	"\x04Ping\x12\x16.quanta.v1.PingRequest\x1a\x14.quanta.v1.PingReply\x12B\n" +
+	"\x0eDeployPipeline\x12\x18.quanta.v1.DeployRequest\x1a\x16.quanta.v1.DeployReply\x12?\n" +
+	"\rPausePipeline\x12\x17.quanta.v1.PauseRequest\x1a\x15.quanta.v1.PauseReply\x12B\n" +
+	"\x0eResumePipeline\x12\x18.quanta.v1.ResumeRequest\x1a\x16.quanta.v1.ResumeReply\x12O\n" +
+	"\rListPipelines\x12\x1f.quanta.v1.ListPipelinesRequest\x1a\x1d.quanta.v1.ListPipelinesReply\x12P\n" +
+	"\x11GetPipelineStatus\x12 .quanta.v1.PipelineStatusRequest\x1a\x19.quanta.v1.PipelineStatus\x12B\n" +
+	"\x0eDeletePipeline\x12\x18.quanta.v1.DeleteRequest\x1a\x16.quanta.v1.DeleteReply\x123\n" +
+	"\x03Tap\x12\x15.quanta.v1.TapRequest\x1a\x13.quanta.v1.TapFrame0\x01\x12H\n" +
+	"\x10RollbackPipeline\x12\x1a.quanta.v1.RollbackRequest\x1a\x18.quanta.v1.RollbackReply\x12K\n" +
+	"\x12GetPipelineHistory\x12\x19.quanta.v1.HistoryRequest\x1a\x1a.quanta.v1.PipelineHistoryB\x18Z\x16quanta/api/proto/v1;pbb\x06proto3"
+
+var (
+	file_v1_control_proto_rawDescOnce sync.Once
+	file_v1_control_proto_rawDescData []byte
+)
+
+func file_v1_control_proto_rawDescGZIP() []byte {
+	file_v1_control_proto_rawDescOnce.Do(func() {
+		file_v1_control_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_v1_control_proto_rawDesc), len(file_v1_control_proto_rawDesc)))
+	})
+	return file_v1_control_proto_rawDescData
+}
+
+var file_v1_control_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
+var file_v1_control_proto_goTypes = []any{
+	(*PingRequest)(nil),
+	(*PingReply)(nil),
+	(*DeployRequest)(nil),
+	(*DeployReply)(nil),
+	(*PauseRequest)(nil),
+	(*PauseReply)(nil),
+	(*ResumeRequest)(nil),
+	(*ResumeReply)(nil),
+	(*DeleteRequest)(nil),
+	(*DeleteReply)(nil),
+	(*RollbackRequest)(nil),
+	(*RollbackReply)(nil),
+	(*HistoryRequest)(nil),
+	(*PipelineHistory)(nil),
+	(*PipelineVersion)(nil),
+	(*ListPipelinesRequest)(nil),
+	(*ListPipelinesReply)(nil),
+	(*PipelineStatusRequest)(nil),
+	(*PipelineStatus)(nil),
+	(*StageStatus)(nil),
+	(*TapRequest)(nil),
+	(*TapFilter)(nil),
+	(*TapFrame)(nil),
+	nil,
+	(*Frame)(nil),
+}
+var file_v1_control_proto_depIdxs = []int32{
+	14,
+	18,
+	19,
+	21,
+	23,
+	24,
+	0,
+	2,
+	4,
+	6,
+	15,
+	17,
+	8,
+	20,
+	10,
+	12,
+	1,
+	3,
+	5,
+	7,
+	16,
+	18,
+	9,
+	22,
+	11,
+	13,
+	16,
+	6,
+	6,
+	6,
 	0,
 }
 
@@ -326,13 +1379,14 @@ func file_v1_control_proto_init() {
 	if File_v1_control_proto != nil {
 		return
 	}
+	file_v1_frame_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_v1_control_proto_rawDesc), len(file_v1_control_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   24,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -10,15 +10,29 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Control_Ping_FullMethodName           = "/quanta.v1.Control/Ping"
-	Control_DeployPipeline_FullMethodName = "/quanta.v1.Control/DeployPipeline"
-	Control_PausePipeline_FullMethodName  = "/quanta.v1.Control/PausePipeline"
+	Control_Ping_FullMethodName               = "/quanta.v1.Control/Ping"
+	Control_DeployPipeline_FullMethodName     = "/quanta.v1.Control/DeployPipeline"
+	Control_PausePipeline_FullMethodName      = "/quanta.v1.Control/PausePipeline"
+	Control_ResumePipeline_FullMethodName     = "/quanta.v1.Control/ResumePipeline"
+	Control_ListPipelines_FullMethodName      = "/quanta.v1.Control/ListPipelines"
+	Control_GetPipelineStatus_FullMethodName  = "/quanta.v1.Control/GetPipelineStatus"
+	Control_DeletePipeline_FullMethodName     = "/quanta.v1.Control/DeletePipeline"
+	Control_Tap_FullMethodName                = "/quanta.v1.Control/Tap"
+	Control_RollbackPipeline_FullMethodName   = "/quanta.v1.Control/RollbackPipeline"
+	Control_GetPipelineHistory_FullMethodName = "/quanta.v1.Control/GetPipelineHistory"
 )
 
 type ControlClient interface {
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingReply, error)
 	DeployPipeline(ctx context.Context, in *DeployRequest, opts ...grpc.CallOption) (*DeployReply, error)
 	PausePipeline(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseReply, error)
+	ResumePipeline(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*ResumeReply, error)
+	ListPipelines(ctx context.Context, in *ListPipelinesRequest, opts ...grpc.CallOption) (*ListPipelinesReply, error)
+	GetPipelineStatus(ctx context.Context, in *PipelineStatusRequest, opts ...grpc.CallOption) (*PipelineStatus, error)
+	DeletePipeline(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteReply, error)
+	Tap(ctx context.Context, in *TapRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TapFrame], error)
+	RollbackPipeline(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackReply, error)
+	GetPipelineHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*PipelineHistory, error)
 }
 
 type controlClient struct {
@@ -59,10 +73,95 @@ func (c *controlClient) PausePipeline(ctx context.Context, in *PauseRequest, opt
 	return out, nil
 }
 
+func (c *controlClient) ResumePipeline(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*ResumeReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResumeReply)
+	err := c.cc.Invoke(ctx, Control_ResumePipeline_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) ListPipelines(ctx context.Context, in *ListPipelinesRequest, opts ...grpc.CallOption) (*ListPipelinesReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPipelinesReply)
+	err := c.cc.Invoke(ctx, Control_ListPipelines_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) GetPipelineStatus(ctx context.Context, in *PipelineStatusRequest, opts ...grpc.CallOption) (*PipelineStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PipelineStatus)
+	err := c.cc.Invoke(ctx, Control_GetPipelineStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) DeletePipeline(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteReply)
+	err := c.cc.Invoke(ctx, Control_DeletePipeline_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) Tap(ctx context.Context, in *TapRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TapFrame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Control_ServiceDesc.Streams[0], Control_Tap_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TapRequest, TapFrame]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Control_TapClient = grpc.ServerStreamingClient[TapFrame]
+
+func (c *controlClient) RollbackPipeline(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RollbackReply)
+	err := c.cc.Invoke(ctx, Control_RollbackPipeline_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) GetPipelineHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*PipelineHistory, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PipelineHistory)
+	err := c.cc.Invoke(ctx, Control_GetPipelineHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 type ControlServer interface {
 	Ping(context.Context, *PingRequest) (*PingReply, error)
 	DeployPipeline(context.Context, *DeployRequest) (*DeployReply, error)
 	PausePipeline(context.Context, *PauseRequest) (*PauseReply, error)
+	ResumePipeline(context.Context, *ResumeRequest) (*ResumeReply, error)
+	ListPipelines(context.Context, *ListPipelinesRequest) (*ListPipelinesReply, error)
+	GetPipelineStatus(context.Context, *PipelineStatusRequest) (*PipelineStatus, error)
+	DeletePipeline(context.Context, *DeleteRequest) (*DeleteReply, error)
+	Tap(*TapRequest, grpc.ServerStreamingServer[TapFrame]) error
+	RollbackPipeline(context.Context, *RollbackRequest) (*RollbackReply, error)
+	GetPipelineHistory(context.Context, *HistoryRequest) (*PipelineHistory, error)
 	mustEmbedUnimplementedControlServer()
 }
 
@@ -77,6 +176,27 @@ func (UnimplementedControlServer) DeployPipeline(context.Context, *DeployRequest
 func (UnimplementedControlServer) PausePipeline(context.Context, *PauseRequest) (*PauseReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PausePipeline not implemented")
 }
+func (UnimplementedControlServer) ResumePipeline(context.Context, *ResumeRequest) (*ResumeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumePipeline not implemented")
+}
+func (UnimplementedControlServer) ListPipelines(context.Context, *ListPipelinesRequest) (*ListPipelinesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPipelines not implemented")
+}
+func (UnimplementedControlServer) GetPipelineStatus(context.Context, *PipelineStatusRequest) (*PipelineStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPipelineStatus not implemented")
+}
+func (UnimplementedControlServer) DeletePipeline(context.Context, *DeleteRequest) (*DeleteReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePipeline not implemented")
+}
+func (UnimplementedControlServer) Tap(*TapRequest, grpc.ServerStreamingServer[TapFrame]) error {
+	return status.Errorf(codes.Unimplemented, "method Tap not implemented")
+}
+func (UnimplementedControlServer) RollbackPipeline(context.Context, *RollbackRequest) (*RollbackReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackPipeline not implemented")
+}
+func (UnimplementedControlServer) GetPipelineHistory(context.Context, *HistoryRequest) (*PipelineHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPipelineHistory not implemented")
+}
 func (UnimplementedControlServer) mustEmbedUnimplementedControlServer() {}
 func (UnimplementedControlServer) testEmbeddedByValue()                 {}
 
@@ -146,6 +266,124 @@ func _Control_PausePipeline_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Control_ResumePipeline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).ResumePipeline(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_ResumePipeline_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).ResumePipeline(ctx, req.(*ResumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_ListPipelines_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPipelinesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).ListPipelines(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_ListPipelines_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).ListPipelines(ctx, req.(*ListPipelinesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_GetPipelineStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PipelineStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).GetPipelineStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_GetPipelineStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).GetPipelineStatus(ctx, req.(*PipelineStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_DeletePipeline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).DeletePipeline(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_DeletePipeline_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).DeletePipeline(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_Tap_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TapRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ControlServer).Tap(m, &grpc.GenericServerStream[TapRequest, TapFrame]{ServerStream: stream})
+}
+
+type Control_TapServer = grpc.ServerStreamingServer[TapFrame]
+
+func _Control_RollbackPipeline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).RollbackPipeline(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_RollbackPipeline_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).RollbackPipeline(ctx, req.(*RollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_GetPipelineHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).GetPipelineHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_GetPipelineHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).GetPipelineHistory(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var Control_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "quanta.v1.Control",
 	HandlerType: (*ControlServer)(nil),
@@ -162,7 +400,37 @@ var Control_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PausePipeline",
 			Handler:    _Control_PausePipeline_Handler,
 		},
+		{
+			MethodName: "ResumePipeline",
+			Handler:    _Control_ResumePipeline_Handler,
+		},
+		{
+			MethodName: "ListPipelines",
+			Handler:    _Control_ListPipelines_Handler,
+		},
+		{
+			MethodName: "GetPipelineStatus",
+			Handler:    _Control_GetPipelineStatus_Handler,
+		},
+		{
+			MethodName: "DeletePipeline",
+			Handler:    _Control_DeletePipeline_Handler,
+		},
+		{
+			MethodName: "RollbackPipeline",
+			Handler:    _Control_RollbackPipeline_Handler,
+		},
+		{
+			MethodName: "GetPipelineHistory",
+			Handler:    _Control_GetPipelineHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Tap",
+			Handler:       _Control_Tap_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "v1/control.proto",
 }
@@ -283,6 +283,7 @@ type Frame struct {
 	Headers       map[string][]byte      `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	Ts            *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=ts,proto3" json:"ts,omitempty"`
 	Checkpoint    *CheckpointToken       `protobuf:"bytes,5,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+	Route         *Route                 `protobuf:"bytes,6,opt,name=route,proto3" json:"route,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -351,6 +352,64 @@ func (x *Frame) GetCheckpoint() *CheckpointToken {
 	return nil
 }
 
+func (x *Frame) GetRoute() *Route {
+	if x != nil {
+		return x.Route
+	}
+	return nil
+}
+
+type Route struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sink          string                 `protobuf:"bytes,1,opt,name=sink,proto3" json:"sink,omitempty"`
+	Topic         string                 `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Route) Reset() {
+	*x = Route{}
+	mi := &file_v1_frame_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Route) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Route) ProtoMessage() {}
+
+func (x *Route) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_frame_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*Route) Descriptor() ([]byte, []int) {
+	return file_v1_frame_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Route) GetSink() string {
+	if x != nil {
+		return x.Sink
+	}
+	return ""
+}
+
+func (x *Route) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
 var File_v1_frame_proto protoreflect.FileDescriptor
 
 const file_v1_frame_proto_rawDesc = "" +
@@ -370,7 +429,7 @@ const file_v1_frame_proto_rawDesc = "" +
 	"\x03sqs\x18\x02 \x01(\v2\x14.quanta.v1.SqsHandleH\x00R\x03sqs\x12*\n" +
 	"\x04http\x18\x03 \x01(\v2\x14.quanta.v1.HttpAckIDH\x00R\x04http\x12\x12\n" +
 	"\x03raw\x18\x0f \x01(\fH\x00R\x03rawB\x06\n" +
-	"\x04kind\"\x8c\x02\n" +
+	"\x04kind\"\xb4\x02\n" +
 	"\x05Frame\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\fR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\fR\x05value\x127\n" +
@@ -378,10 +437,14 @@ const file_v1_frame_proto_rawDesc = "" +
 	"\x02ts\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12:\n" +
 	"\n" +
 	"checkpoint\x18\x05 \x01(\v2\x1a.quanta.v1.CheckpointTokenR\n" +
-	"checkpoint\x1a:\n" +
+	"checkpoint\x12&\n" +
+	"\x05route\x18\x06 \x01(\v2\x10.quanta.v1.RouteR\x05route\x1a:\n" +
 	"\fHeadersEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01B\x18Z\x16quanta/api/proto/v1;pbb\x06proto3"
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"1\n" +
+	"\x05Route\x12\x12\n" +
+	"\x04sink\x18\x01 \x01(\tR\x04sink\x12\x14\n" +
+	"\x05topic\x18\x02 \x01(\tR\x05topicB\x18Z\x16quanta/api/proto/v1;pbb\x06proto3"
 
 var (
 	file_v1_frame_proto_rawDescOnce sync.Once
@@ -395,13 +458,14 @@ func file_v1_frame_proto_rawDescGZIP() []byte {
 	return file_v1_frame_proto_rawDescData
 }
 
-var file_v1_frame_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_v1_frame_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_v1_frame_proto_goTypes = []any{
 	(*KafkaOffset)(nil),
 	(*SqsHandle)(nil),
 	(*HttpAckID)(nil),
 	(*CheckpointToken)(nil),
 	(*Frame)(nil),
+	(*Route)(nil),
 	nil,
 	(*timestamppb.Timestamp)(nil),
 }
@@ -409,13 +473,14 @@ var file_v1_frame_proto_depIdxs = []int32{
 	0,
 	1,
 	2,
-	5,
 	6,
+	7,
 	3,
-	6,
-	6,
-	6,
-	6,
+	5,
+	7,
+	7,
+	7,
+	7,
 	0,
 }
 
@@ -436,7 +501,7 @@ func file_v1_frame_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_v1_frame_proto_rawDesc), len(file_v1_frame_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
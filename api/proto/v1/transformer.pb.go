@@ -0,0 +1,1162 @@
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Status int32
+
+const (
+	Status_OK    Status = 0
+	Status_DROP  Status = 1
+	Status_RETRY Status = 2
+	Status_ERROR Status = 3
+)
+
+var (
+	Status_name = map[int32]string{
+		0: "OK",
+		1: "DROP",
+		2: "RETRY",
+		3: "ERROR",
+	}
+	Status_value = map[string]int32{
+		"OK":    0,
+		"DROP":  1,
+		"RETRY": 2,
+		"ERROR": 3,
+	}
+)
+
+func (x Status) Enum() *Status {
+	p := new(Status)
+	*p = x
+	return p
+}
+
+func (x Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_v1_transformer_proto_enumTypes[0].Descriptor()
+}
+
+func (Status) Type() protoreflect.EnumType {
+	return &file_v1_transformer_proto_enumTypes[0]
+}
+
+func (x Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+func (Status) EnumDescriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{0}
+}
+
+type ControlMessage_Type int32
+
+const (
+	ControlMessage_START  ControlMessage_Type = 0
+	ControlMessage_STOP   ControlMessage_Type = 1
+	ControlMessage_PING   ControlMessage_Type = 2
+	ControlMessage_PONG   ControlMessage_Type = 3
+	ControlMessage_FLUSH  ControlMessage_Type = 4
+	ControlMessage_GRANT  ControlMessage_Type = 5
+	ControlMessage_PAUSE  ControlMessage_Type = 6
+	ControlMessage_RESUME ControlMessage_Type = 7
+)
+
+var (
+	ControlMessage_Type_name = map[int32]string{
+		0: "START",
+		1: "STOP",
+		2: "PING",
+		3: "PONG",
+		4: "FLUSH",
+		5: "GRANT",
+		6: "PAUSE",
+		7: "RESUME",
+	}
+	ControlMessage_Type_value = map[string]int32{
+		"START":  0,
+		"STOP":   1,
+		"PING":   2,
+		"PONG":   3,
+		"FLUSH":  4,
+		"GRANT":  5,
+		"PAUSE":  6,
+		"RESUME": 7,
+	}
+)
+
+func (x ControlMessage_Type) Enum() *ControlMessage_Type {
+	p := new(ControlMessage_Type)
+	*p = x
+	return p
+}
+
+func (x ControlMessage_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ControlMessage_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_v1_transformer_proto_enumTypes[1].Descriptor()
+}
+
+func (ControlMessage_Type) Type() protoreflect.EnumType {
+	return &file_v1_transformer_proto_enumTypes[1]
+}
+
+func (x ControlMessage_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+func (ControlMessage_Type) EnumDescriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{5, 0}
+}
+
+type TransformRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PipelineId    string                 `protobuf:"bytes,1,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"`
+	PluginId      string                 `protobuf:"bytes,2,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	Metadata      *EventMetadata         `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	BatchMode     bool                   `protobuf:"varint,5,opt,name=batch_mode,json=batchMode,proto3" json:"batch_mode,omitempty"`
+	Key           []byte                 `protobuf:"bytes,6,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransformRequest) Reset() {
+	*x = TransformRequest{}
+	mi := &file_v1_transformer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransformRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransformRequest) ProtoMessage() {}
+
+func (x *TransformRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*TransformRequest) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TransformRequest) GetPipelineId() string {
+	if x != nil {
+		return x.PipelineId
+	}
+	return ""
+}
+
+func (x *TransformRequest) GetPluginId() string {
+	if x != nil {
+		return x.PluginId
+	}
+	return ""
+}
+
+func (x *TransformRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *TransformRequest) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *TransformRequest) GetBatchMode() bool {
+	if x != nil {
+		return x.BatchMode
+	}
+	return false
+}
+
+func (x *TransformRequest) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+type TransformResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*Event               `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Status        Status                 `protobuf:"varint,2,opt,name=status,proto3,enum=quanta.v1.Status" json:"status,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	RetryAfterMs  int32                  `protobuf:"varint,4,opt,name=retry_after_ms,json=retryAfterMs,proto3" json:"retry_after_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransformResponse) Reset() {
+	*x = TransformResponse{}
+	mi := &file_v1_transformer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransformResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransformResponse) ProtoMessage() {}
+
+func (x *TransformResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*TransformResponse) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TransformResponse) GetEvents() []*Event {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *TransformResponse) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_OK
+}
+
+func (x *TransformResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *TransformResponse) GetRetryAfterMs() int32 {
+	if x != nil {
+		return x.RetryAfterMs
+	}
+	return 0
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Metadata      *EventMetadata         `protobuf:"bytes,3,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Key           []byte                 `protobuf:"bytes,4,opt,name=key,proto3,oneof" json:"key,omitempty"`
+	Route         *Route                 `protobuf:"bytes,5,opt,name=route,proto3" json:"route,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_v1_transformer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Event) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Event) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Event) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Event) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *Event) GetRoute() *Route {
+	if x != nil {
+		return x.Route
+	}
+	return nil
+}
+
+type EventMetadata struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TimestampMs     int64                  `protobuf:"varint,1,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`
+	Headers         map[string]string      `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	SourcePartition string                 `protobuf:"bytes,3,opt,name=source_partition,json=sourcePartition,proto3" json:"source_partition,omitempty"`
+	SourceOffset    string                 `protobuf:"bytes,4,opt,name=source_offset,json=sourceOffset,proto3" json:"source_offset,omitempty"`
+	Attributes      map[string]string      `protobuf:"bytes,5,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	BinaryHeaders   map[string][]byte      `protobuf:"bytes,6,rep,name=binary_headers,json=binaryHeaders,proto3" json:"binary_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *EventMetadata) Reset() {
+	*x = EventMetadata{}
+	mi := &file_v1_transformer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventMetadata) ProtoMessage() {}
+
+func (x *EventMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*EventMetadata) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EventMetadata) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
+func (x *EventMetadata) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *EventMetadata) GetSourcePartition() string {
+	if x != nil {
+		return x.SourcePartition
+	}
+	return ""
+}
+
+func (x *EventMetadata) GetSourceOffset() string {
+	if x != nil {
+		return x.SourceOffset
+	}
+	return ""
+}
+
+func (x *EventMetadata) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *EventMetadata) GetBinaryHeaders() map[string][]byte {
+	if x != nil {
+		return x.BinaryHeaders
+	}
+	return nil
+}
+
+type TransformStreamMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+
+	Msg           isTransformStreamMessage_Msg `protobuf_oneof:"msg"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransformStreamMessage) Reset() {
+	*x = TransformStreamMessage{}
+	mi := &file_v1_transformer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransformStreamMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransformStreamMessage) ProtoMessage() {}
+
+func (x *TransformStreamMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*TransformStreamMessage) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TransformStreamMessage) GetMsg() isTransformStreamMessage_Msg {
+	if x != nil {
+		return x.Msg
+	}
+	return nil
+}
+
+func (x *TransformStreamMessage) GetRequest() *TransformRequest {
+	if x != nil {
+		if x, ok := x.Msg.(*TransformStreamMessage_Request); ok {
+			return x.Request
+		}
+	}
+	return nil
+}
+
+func (x *TransformStreamMessage) GetResponse() *TransformResponse {
+	if x != nil {
+		if x, ok := x.Msg.(*TransformStreamMessage_Response); ok {
+			return x.Response
+		}
+	}
+	return nil
+}
+
+func (x *TransformStreamMessage) GetControl() *ControlMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*TransformStreamMessage_Control); ok {
+			return x.Control
+		}
+	}
+	return nil
+}
+
+type isTransformStreamMessage_Msg interface {
+	isTransformStreamMessage_Msg()
+}
+
+type TransformStreamMessage_Request struct {
+	Request *TransformRequest `protobuf:"bytes,1,opt,name=request,proto3,oneof"`
+}
+
+type TransformStreamMessage_Response struct {
+	Response *TransformResponse `protobuf:"bytes,2,opt,name=response,proto3,oneof"`
+}
+
+type TransformStreamMessage_Control struct {
+	Control *ControlMessage `protobuf:"bytes,3,opt,name=control,proto3,oneof"`
+}
+
+func (*TransformStreamMessage_Request) isTransformStreamMessage_Msg() {}
+
+func (*TransformStreamMessage_Response) isTransformStreamMessage_Msg() {}
+
+func (*TransformStreamMessage_Control) isTransformStreamMessage_Msg() {}
+
+type ControlMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          ControlMessage_Type    `protobuf:"varint,1,opt,name=type,proto3,enum=quanta.v1.ControlMessage_Type" json:"type,omitempty"`
+	Credits       int32                  `protobuf:"varint,2,opt,name=credits,proto3" json:"credits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlMessage) Reset() {
+	*x = ControlMessage{}
+	mi := &file_v1_transformer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlMessage) ProtoMessage() {}
+
+func (x *ControlMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*ControlMessage) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ControlMessage) GetType() ControlMessage_Type {
+	if x != nil {
+		return x.Type
+	}
+	return ControlMessage_START
+}
+
+func (x *ControlMessage) GetCredits() int32 {
+	if x != nil {
+		return x.Credits
+	}
+	return 0
+}
+
+type HealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthRequest) Reset() {
+	*x = HealthRequest{}
+	mi := &file_v1_transformer_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthRequest) ProtoMessage() {}
+
+func (x *HealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*HealthRequest) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{6}
+}
+
+type HealthResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Ok      bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Details string                 `protobuf:"bytes,2,opt,name=details,proto3" json:"details,omitempty"`
+
+	InFlight      *int64 `protobuf:"varint,3,opt,name=in_flight,json=inFlight,proto3,oneof" json:"in_flight,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_v1_transformer_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *HealthResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *HealthResponse) GetDetails() string {
+	if x != nil {
+		return x.Details
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetInFlight() int64 {
+	if x != nil && x.InFlight != nil {
+		return *x.InFlight
+	}
+	return 0
+}
+
+type MetadataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EngineVersion *PluginVersion         `protobuf:"bytes,1,opt,name=engine_version,json=engineVersion,proto3" json:"engine_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetadataRequest) Reset() {
+	*x = MetadataRequest{}
+	mi := &file_v1_transformer_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetadataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetadataRequest) ProtoMessage() {}
+
+func (x *MetadataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*MetadataRequest) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MetadataRequest) GetEngineVersion() *PluginVersion {
+	if x != nil {
+		return x.EngineVersion
+	}
+	return nil
+}
+
+type MetadataResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Name            string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version         string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	ProtocolVersion *PluginVersion         `protobuf:"bytes,3,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	Capabilities    map[string]string      `protobuf:"bytes,4,rep,name=capabilities,proto3" json:"capabilities,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *MetadataResponse) Reset() {
+	*x = MetadataResponse{}
+	mi := &file_v1_transformer_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetadataResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetadataResponse) ProtoMessage() {}
+
+func (x *MetadataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*MetadataResponse) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MetadataResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MetadataResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *MetadataResponse) GetProtocolVersion() *PluginVersion {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return nil
+}
+
+func (x *MetadataResponse) GetCapabilities() map[string]string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+type ConfigureRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PipelineId    string                 `protobuf:"bytes,1,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"`
+	PluginId      string                 `protobuf:"bytes,2,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
+	Config        *structpb.Struct       `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureRequest) Reset() {
+	*x = ConfigureRequest{}
+	mi := &file_v1_transformer_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureRequest) ProtoMessage() {}
+
+func (x *ConfigureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*ConfigureRequest) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ConfigureRequest) GetPipelineId() string {
+	if x != nil {
+		return x.PipelineId
+	}
+	return ""
+}
+
+func (x *ConfigureRequest) GetPluginId() string {
+	if x != nil {
+		return x.PluginId
+	}
+	return ""
+}
+
+func (x *ConfigureRequest) GetConfig() *structpb.Struct {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+type ConfigureResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureResponse) Reset() {
+	*x = ConfigureResponse{}
+	mi := &file_v1_transformer_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureResponse) ProtoMessage() {}
+
+func (x *ConfigureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*ConfigureResponse) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ConfigureResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *ConfigureResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+type PluginVersion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Major         int32                  `protobuf:"varint,1,opt,name=major,proto3" json:"major,omitempty"`
+	Minor         int32                  `protobuf:"varint,2,opt,name=minor,proto3" json:"minor,omitempty"`
+	Patch         int32                  `protobuf:"varint,3,opt,name=patch,proto3" json:"patch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PluginVersion) Reset() {
+	*x = PluginVersion{}
+	mi := &file_v1_transformer_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PluginVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PluginVersion) ProtoMessage() {}
+
+func (x *PluginVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_transformer_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (*PluginVersion) Descriptor() ([]byte, []int) {
+	return file_v1_transformer_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PluginVersion) GetMajor() int32 {
+	if x != nil {
+		return x.Major
+	}
+	return 0
+}
+
+func (x *PluginVersion) GetMinor() int32 {
+	if x != nil {
+		return x.Minor
+	}
+	return 0
+}
+
+func (x *PluginVersion) GetPatch() int32 {
+	if x != nil {
+		return x.Patch
+	}
+	return 0
+}
+
+var File_v1_transformer_proto protoreflect.FileDescriptor
+
+const file_v1_transformer_proto_rawDesc = "" +
+	"\n" +
+	"\x14v1/transformer.proto\x12\tquanta.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x0ev1/frame.proto\"\xd1\x01\n" +
+	"\x10TransformRequest\x12\x1f\n" +
+	"\vpipeline_id\x18\x01 \x01(\tR\n" +
+	"pipelineId\x12\x1b\n" +
+	"\tplugin_id\x18\x02 \x01(\tR\bpluginId\x12\x18\n" +
+	"\apayload\x18\x03 \x01(\fR\apayload\x124\n" +
+	"\bmetadata\x18\x04 \x01(\v2\x18.quanta.v1.EventMetadataR\bmetadata\x12\x1d\n" +
+	"\n" +
+	"batch_mode\x18\x05 \x01(\bR\tbatchMode\x12\x10\n" +
+	"\x03key\x18\x06 \x01(\fR\x03key\"\xb3\x01\n" +
+	"\x11TransformResponse\x12(\n" +
+	"\x06events\x18\x01 \x03(\v2\x10.quanta.v1.EventR\x06events\x12)\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x11.quanta.v1.StatusR\x06status\x12#\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\x12$\n" +
+	"\x0eretry_after_ms\x18\x04 \x01(\x05R\fretryAfterMs\"\xaa\x01\n" +
+	"\x05Event\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x124\n" +
+	"\bmetadata\x18\x03 \x01(\v2\x18.quanta.v1.EventMetadataR\bmetadata\x12\x15\n" +
+	"\x03key\x18\x04 \x01(\fH\x00R\x03key\x88\x01\x01\x12&\n" +
+	"\x05route\x18\x05 \x01(\v2\x10.quanta.v1.RouteR\x05routeB\x06\n" +
+	"\x04_key\"\x9e\x04\n" +
+	"\rEventMetadata\x12!\n" +
+	"\ftimestamp_ms\x18\x01 \x01(\x03R\vtimestampMs\x12?\n" +
+	"\aheaders\x18\x02 \x03(\v2%.quanta.v1.EventMetadata.HeadersEntryR\aheaders\x12)\n" +
+	"\x10source_partition\x18\x03 \x01(\tR\x0fsourcePartition\x12#\n" +
+	"\rsource_offset\x18\x04 \x01(\tR\fsourceOffset\x12H\n" +
+	"\n" +
+	"attributes\x18\x05 \x03(\v2(.quanta.v1.EventMetadata.AttributesEntryR\n" +
+	"attributes\x12R\n" +
+	"\x0ebinary_headers\x18\x06 \x03(\v2+.quanta.v1.EventMetadata.BinaryHeadersEntryR\rbinaryHeaders\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a@\n" +
+	"\x12BinaryHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"\xcb\x01\n" +
+	"\x16TransformStreamMessage\x127\n" +
+	"\arequest\x18\x01 \x01(\v2\x1b.quanta.v1.TransformRequestH\x00R\arequest\x12:\n" +
+	"\bresponse\x18\x02 \x01(\v2\x1c.quanta.v1.TransformResponseH\x00R\bresponse\x125\n" +
+	"\acontrol\x18\x03 \x01(\v2\x19.quanta.v1.ControlMessageH\x00R\acontrolB\x05\n" +
+	"\x03msg\"\xbc\x01\n" +
+	"\x0eControlMessage\x122\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1e.quanta.v1.ControlMessage.TypeR\x04type\x12\x18\n" +
+	"\acredits\x18\x02 \x01(\x05R\acredits\"\\\n" +
+	"\x04Type\x12\t\n" +
+	"\x05START\x10\x00\x12\b\n" +
+	"\x04STOP\x10\x01\x12\b\n" +
+	"\x04PING\x10\x02\x12\b\n" +
+	"\x04PONG\x10\x03\x12\t\n" +
+	"\x05FLUSH\x10\x04\x12\t\n" +
+	"\x05GRANT\x10\x05\x12\t\n" +
+	"\x05PAUSE\x10\x06\x12\n" +
+	"\n" +
+	"\x06RESUME\x10\a\"\x0f\n" +
+	"\rHealthRequest\"j\n" +
+	"\x0eHealthResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x18\n" +
+	"\adetails\x18\x02 \x01(\tR\adetails\x12 \n" +
+	"\tin_flight\x18\x03 \x01(\x03H\x00R\binFlight\x88\x01\x01B\f\n" +
+	"\n" +
+	"_in_flight\"R\n" +
+	"\x0fMetadataRequest\x12?\n" +
+	"\x0eengine_version\x18\x01 \x01(\v2\x18.quanta.v1.PluginVersionR\rengineVersion\"\x99\x02\n" +
+	"\x10MetadataResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12C\n" +
+	"\x10protocol_version\x18\x03 \x01(\v2\x18.quanta.v1.PluginVersionR\x0fprotocolVersion\x12Q\n" +
+	"\fcapabilities\x18\x04 \x03(\v2-.quanta.v1.MetadataResponse.CapabilitiesEntryR\fcapabilities\x1a?\n" +
+	"\x11CapabilitiesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x81\x01\n" +
+	"\x10ConfigureRequest\x12\x1f\n" +
+	"\vpipeline_id\x18\x01 \x01(\tR\n" +
+	"pipelineId\x12\x1b\n" +
+	"\tplugin_id\x18\x02 \x01(\tR\bpluginId\x12/\n" +
+	"\x06config\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x06config\"H\n" +
+	"\x11ConfigureResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12#\n" +
+	"\rerror_message\x18\x02 \x01(\tR\ferrorMessage\"Q\n" +
+	"\rPluginVersion\x12\x14\n" +
+	"\x05major\x18\x01 \x01(\x05R\x05major\x12\x14\n" +
+	"\x05minor\x18\x02 \x01(\x05R\x05minor\x12\x14\n" +
+	"\x05patch\x18\x03 \x01(\x05R\x05patch*0\n" +
+	"\x06Status\x12\x06\n" +
+	"\x02OK\x10\x00\x12\b\n" +
+	"\x04DROP\x10\x01\x12\t\n" +
+	"\x05RETRY\x10\x02\x12\t\n" +
+	"\x05ERROR\x10\x032\x83\x03\n" +
+	"\x10TransformService\x12F\n" +
+	"\tTransform\x12\x1b.quanta.v1.TransformRequest\x1a\x1c.quanta.v1.TransformResponse\x12[\n" +
+	"\x0fTransformStream\x12!.quanta.v1.TransformStreamMessage\x1a!.quanta.v1.TransformStreamMessage(\x010\x01\x12=\n" +
+	"\x06Health\x12\x18.quanta.v1.HealthRequest\x1a\x19.quanta.v1.HealthResponse\x12C\n" +
+	"\bMetadata\x12\x1a.quanta.v1.MetadataRequest\x1a\x1b.quanta.v1.MetadataResponse\x12F\n" +
+	"\tConfigure\x12\x1b.quanta.v1.ConfigureRequest\x1a\x1c.quanta.v1.ConfigureResponseB\x18Z\x16quanta/api/proto/v1;pbb\x06proto3"
+
+var (
+	file_v1_transformer_proto_rawDescOnce sync.Once
+	file_v1_transformer_proto_rawDescData []byte
+)
+
+func file_v1_transformer_proto_rawDescGZIP() []byte {
+	file_v1_transformer_proto_rawDescOnce.Do(func() {
+		file_v1_transformer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_v1_transformer_proto_rawDesc), len(file_v1_transformer_proto_rawDesc)))
+	})
+	return file_v1_transformer_proto_rawDescData
+}
+
+var file_v1_transformer_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_v1_transformer_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_v1_transformer_proto_goTypes = []any{
+	(Status)(0),
+	(ControlMessage_Type)(0),
+	(*TransformRequest)(nil),
+	(*TransformResponse)(nil),
+	(*Event)(nil),
+	(*EventMetadata)(nil),
+	(*TransformStreamMessage)(nil),
+	(*ControlMessage)(nil),
+	(*HealthRequest)(nil),
+	(*HealthResponse)(nil),
+	(*MetadataRequest)(nil),
+	(*MetadataResponse)(nil),
+	(*ConfigureRequest)(nil),
+	(*ConfigureResponse)(nil),
+	(*PluginVersion)(nil),
+	nil,
+	nil,
+	nil,
+	nil,
+	(*Route)(nil),
+	(*structpb.Struct)(nil),
+}
+var file_v1_transformer_proto_depIdxs = []int32{
+	5,
+	4,
+	0,
+	5,
+	19,
+	15,
+	16,
+	17,
+	2,
+	3,
+	7,
+	1,
+	14,
+	14,
+	18,
+	20,
+	2,
+	6,
+	8,
+	10,
+	12,
+	3,
+	6,
+	9,
+	11,
+	13,
+	21,
+	16,
+	16,
+	16,
+	0,
+}
+
+func init() { file_v1_transformer_proto_init() }
+func file_v1_transformer_proto_init() {
+	if File_v1_transformer_proto != nil {
+		return
+	}
+	file_v1_frame_proto_init()
+	file_v1_transformer_proto_msgTypes[2].OneofWrappers = []any{}
+	file_v1_transformer_proto_msgTypes[4].OneofWrappers = []any{
+		(*TransformStreamMessage_Request)(nil),
+		(*TransformStreamMessage_Response)(nil),
+		(*TransformStreamMessage_Control)(nil),
+	}
+	file_v1_transformer_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_v1_transformer_proto_rawDesc), len(file_v1_transformer_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   17,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_v1_transformer_proto_goTypes,
+		DependencyIndexes: file_v1_transformer_proto_depIdxs,
+		EnumInfos:         file_v1_transformer_proto_enumTypes,
+		MessageInfos:      file_v1_transformer_proto_msgTypes,
+	}.Build()
+	File_v1_transformer_proto = out.File
+	file_v1_transformer_proto_goTypes = nil
+	file_v1_transformer_proto_depIdxs = nil
+}
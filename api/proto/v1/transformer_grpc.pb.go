@@ -0,0 +1,237 @@
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TransformService_Transform_FullMethodName       = "/quanta.v1.TransformService/Transform"
+	TransformService_TransformStream_FullMethodName = "/quanta.v1.TransformService/TransformStream"
+	TransformService_Health_FullMethodName          = "/quanta.v1.TransformService/Health"
+	TransformService_Metadata_FullMethodName        = "/quanta.v1.TransformService/Metadata"
+	TransformService_Configure_FullMethodName       = "/quanta.v1.TransformService/Configure"
+)
+
+type TransformServiceClient interface {
+	Transform(ctx context.Context, in *TransformRequest, opts ...grpc.CallOption) (*TransformResponse, error)
+	TransformStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TransformStreamMessage, TransformStreamMessage], error)
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	Metadata(ctx context.Context, in *MetadataRequest, opts ...grpc.CallOption) (*MetadataResponse, error)
+	Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*ConfigureResponse, error)
+}
+
+type transformServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTransformServiceClient(cc grpc.ClientConnInterface) TransformServiceClient {
+	return &transformServiceClient{cc}
+}
+
+func (c *transformServiceClient) Transform(ctx context.Context, in *TransformRequest, opts ...grpc.CallOption) (*TransformResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransformResponse)
+	err := c.cc.Invoke(ctx, TransformService_Transform_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transformServiceClient) TransformStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TransformStreamMessage, TransformStreamMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TransformService_ServiceDesc.Streams[0], TransformService_TransformStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TransformStreamMessage, TransformStreamMessage]{ClientStream: stream}
+	return x, nil
+}
+
+type TransformService_TransformStreamClient = grpc.BidiStreamingClient[TransformStreamMessage, TransformStreamMessage]
+
+func (c *transformServiceClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, TransformService_Health_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transformServiceClient) Metadata(ctx context.Context, in *MetadataRequest, opts ...grpc.CallOption) (*MetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MetadataResponse)
+	err := c.cc.Invoke(ctx, TransformService_Metadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transformServiceClient) Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*ConfigureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConfigureResponse)
+	err := c.cc.Invoke(ctx, TransformService_Configure_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type TransformServiceServer interface {
+	Transform(context.Context, *TransformRequest) (*TransformResponse, error)
+	TransformStream(grpc.BidiStreamingServer[TransformStreamMessage, TransformStreamMessage]) error
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	Metadata(context.Context, *MetadataRequest) (*MetadataResponse, error)
+	Configure(context.Context, *ConfigureRequest) (*ConfigureResponse, error)
+	mustEmbedUnimplementedTransformServiceServer()
+}
+
+type UnimplementedTransformServiceServer struct{}
+
+func (UnimplementedTransformServiceServer) Transform(context.Context, *TransformRequest) (*TransformResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transform not implemented")
+}
+func (UnimplementedTransformServiceServer) TransformStream(grpc.BidiStreamingServer[TransformStreamMessage, TransformStreamMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method TransformStream not implemented")
+}
+func (UnimplementedTransformServiceServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedTransformServiceServer) Metadata(context.Context, *MetadataRequest) (*MetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Metadata not implemented")
+}
+func (UnimplementedTransformServiceServer) Configure(context.Context, *ConfigureRequest) (*ConfigureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Configure not implemented")
+}
+func (UnimplementedTransformServiceServer) mustEmbedUnimplementedTransformServiceServer() {}
+func (UnimplementedTransformServiceServer) testEmbeddedByValue()                          {}
+
+type UnsafeTransformServiceServer interface {
+	mustEmbedUnimplementedTransformServiceServer()
+}
+
+func RegisterTransformServiceServer(s grpc.ServiceRegistrar, srv TransformServiceServer) {
+
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TransformService_ServiceDesc, srv)
+}
+
+func _TransformService_Transform_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransformRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransformServiceServer).Transform(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransformService_Transform_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransformServiceServer).Transform(ctx, req.(*TransformRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransformService_TransformStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TransformServiceServer).TransformStream(&grpc.GenericServerStream[TransformStreamMessage, TransformStreamMessage]{ServerStream: stream})
+}
+
+type TransformService_TransformStreamServer = grpc.BidiStreamingServer[TransformStreamMessage, TransformStreamMessage]
+
+func _TransformService_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransformServiceServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransformService_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransformServiceServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransformService_Metadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransformServiceServer).Metadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransformService_Metadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransformServiceServer).Metadata(ctx, req.(*MetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransformService_Configure_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransformServiceServer).Configure(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransformService_Configure_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransformServiceServer).Configure(ctx, req.(*ConfigureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var TransformService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "quanta.v1.TransformService",
+	HandlerType: (*TransformServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Transform",
+			Handler:    _TransformService_Transform_Handler,
+		},
+		{
+			MethodName: "Health",
+			Handler:    _TransformService_Health_Handler,
+		},
+		{
+			MethodName: "Metadata",
+			Handler:    _TransformService_Metadata_Handler,
+		},
+		{
+			MethodName: "Configure",
+			Handler:    _TransformService_Configure_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "TransformStream",
+			Handler:       _TransformService_TransformStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "v1/transformer.proto",
+}
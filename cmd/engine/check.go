@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/config"
+	"quanta/internal/pipeline"
+)
+
+// validateCmd implements `quanta-engine validate [-handshake] FILE...`. It
+// returns the process exit status: 1 when a spec is invalid, 2 on bad usage.
+func validateCmd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	handshake := fs.Bool("handshake", false, "also dial or launch every transformer and run its handshake")
+	timeout := fs.Duration("timeout", 10*time.Second, "handshake budget per spec")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: quanta-engine validate [-handshake] FILE...")
+		return 2
+	}
+	status := 0
+	for _, path := range fs.Args() {
+		if !validateFile(path, *handshake, *timeout, stdout, stderr) {
+			status = 1
+		}
+	}
+	return status
+}
+
+func validateFile(path string, handshake bool, timeout time.Duration, stdout, stderr io.Writer) bool {
+	cfg, confPath, err := config.LoadPipelineSpec(path)
+	if err == nil {
+		err = pipeline.Validate(cfg, confPath)
+	}
+	if err != nil {
+		printErrors(stderr, path, err)
+		return false
+	}
+	if handshake {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		checks, err := pipeline.Check(ctx, cfg, confPath)
+		if err != nil {
+			printErrors(stderr, path, err)
+			return false
+		}
+		ok := true
+		for _, c := range checks {
+			if c.Err != "" || !c.Healthy {
+				fmt.Fprintf(stderr, "%s: stage %s: handshake failed: %s\n", path, c.Name, c.Err)
+				ok = false
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	fmt.Fprintf(stdout, "%s: ok\n", path)
+	return true
+}
+
+// printErrors writes each line of a joined error prefixed with path.
+func printErrors(w io.Writer, path string, err error) {
+	for _, line := range strings.Split(err.Error(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			fmt.Fprintf(w, "%s: %s\n", path, line)
+		}
+	}
+}
+
+// dryRunCmd implements `quanta-engine dry-run -fixtures FRAMES FILE`: the
+// spec is validated, compiled with in-memory sinks and fed the fixtures. It
+// returns 1 when the spec is invalid or any frame fails.
+func dryRunCmd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dry-run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fixtures := fs.String("fixtures", "", "YAML list of input frames (key, value, headers, topic, partition, offset)")
+	timeout := fs.Duration("timeout", 30*time.Second, "budget for the whole run")
+	maxBytes := fs.Int("max-bytes", 200, "truncate printed values to this many bytes")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 || *fixtures == "" {
+		fmt.Fprintln(stderr, "usage: quanta-engine dry-run -fixtures FRAMES FILE")
+		return 2
+	}
+	path := fs.Arg(0)
+	cfg, confPath, err := config.LoadPipelineSpec(path)
+	if err == nil {
+		err = pipeline.Validate(cfg, confPath)
+	}
+	if err != nil {
+		printErrors(stderr, path, err)
+		return 1
+	}
+	frames, err := pipeline.LoadFixtures(*fixtures)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	results, err := pipeline.DryRun(ctx, cfg, frames)
+	if err != nil {
+		printErrors(stderr, path, err)
+		return 1
+	}
+	status := 0
+	for i, res := range results {
+		fmt.Fprintf(stdout, "frame %d %s\n", i, describe(res.Input, *maxBytes))
+		switch {
+		case res.Err != nil:
+			fmt.Fprintf(stdout, "  error: %v\n", res.Err)
+			status = 1
+		case len(res.Outputs) == 0:
+			fmt.Fprintln(stdout, "  dropped")
+		}
+		for _, o := range res.Outputs {
+			fmt.Fprintf(stdout, "  -> %s %s\n", o.Sink, describe(o.Frame, *maxBytes))
+		}
+		if res.Err == nil && !res.Acked {
+			fmt.Fprintln(stdout, "  not acked: the offset would not be committed")
+		}
+	}
+	return status
+}
+
+func describe(f *pb.Frame, maxBytes int) string {
+	var b strings.Builder
+	if len(f.GetKey()) > 0 {
+		fmt.Fprintf(&b, "key=%q ", f.GetKey())
+	}
+	keys := make([]string, 0, len(f.GetHeaders()))
+	for k := range f.GetHeaders() {
+		if k != "traceparent" && k != "tracestate" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%q ", k, f.GetHeaders()[k])
+	}
+	v := f.GetValue()
+	if len(v) > maxBytes {
+		v = v[:maxBytes]
+	}
+	fmt.Fprintf(&b, "value=%q", v)
+	return b.String()
+}
+
+// migrateCmd implements `quanta-engine migrate [-w] FILE...`: v1 specs are
+// converted to v2 and printed, or rewritten in place with -w.
+func migrateCmd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	write := fs.Bool("w", false, "rewrite each file in place instead of printing it")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 || (!*write && fs.NArg() > 1) {
+		fmt.Fprintln(stderr, "usage: quanta-engine migrate FILE | migrate -w FILE...")
+		return 2
+	}
+	status := 0
+	for _, path := range fs.Args() {
+		raw, err := os.ReadFile(path)
+		if err == nil {
+			raw, err = config.MigrateV1(raw, filepath.Dir(path))
+		}
+		if err == nil && *write {
+			err = os.WriteFile(path, raw, 0o644)
+		}
+		switch {
+		case err != nil:
+			printErrors(stderr, path, err)
+			status = 1
+		case *write:
+			fmt.Fprintf(stdout, "%s: migrated to v2\n", path)
+		default:
+			_, _ = stdout.Write(raw)
+		}
+	}
+	return status
+}
+
+// subcommand runs an offline subcommand named by args[0], if there is one.
+func subcommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	switch args[0] {
+	case "validate":
+		return validateCmd(args[1:], os.Stdout, os.Stderr), true
+	case "dry-run":
+		return dryRunCmd(args[1:], os.Stdout, os.Stderr), true
+	case "migrate":
+		return migrateCmd(args[1:], os.Stdout, os.Stderr), true
+	}
+	return 0, false
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os"
 	"os/signal"
 	"quanta/internal/logging"
@@ -12,19 +13,23 @@ import (
 )
 
 func main() {
-	logging.InitFromEnv()
-	pipelinePath := os.Getenv("QUANTA_PIPELINE_YML")
-	if pipelinePath == "" {
-		pipelinePath = "pipeline.yml"
+	kafka.Register("sarama", func() kafka.Adapter { return &kafka.SaramaDriver{} })
+	if code, ok := subcommand(os.Args[1:]); ok {
+		os.Exit(code)
 	}
-	cfg := engine.Config{
-		GRPCPort:    7070,
-		MetricsPort: 9100,
-		PipelineYml: pipelinePath,
+
+	configPath := engine.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := engine.LoadConfig(*configPath, flag.CommandLine)
+	if err != nil {
+		logging.L().Error("config failed", "err", err)
+		os.Exit(2)
 	}
+	logging.Configure(cfg.Log)
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
-	kafka.Register("sarama", func() kafka.Adapter { return &kafka.SaramaDriver{} })
 
 	e, err := engine.Bootstrap(ctx, cfg)
 	if err != nil {
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/logging"
+	"quanta/internal/transform"
+	"quanta/plugintest"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func main() {
+	addr := flag.String("addr", "", "plugin address (host:port or unix:///path)")
+	command := flag.String("exec", "", "plugin binary to launch via the exec handshake")
+	timeout := flag.Duration("timeout", 5*time.Second, "per-call timeout")
+	payload := flag.String("payload", "", "sample payload; @file reads it from a file")
+	large := flag.Int("large-bytes", 1<<20, "size of the large-payload check")
+	conc := flag.Int("concurrency", 16, "concurrent workers for the concurrency check")
+	stageCfg := flag.String("config", "", "stage config as a JSON object; @file reads it from a file")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+	logging.InitFromEnv()
+
+	if (*addr == "") == (*command == "") {
+		fmt.Fprintln(os.Stderr, "plugintest: exactly one of -addr or -exec is required")
+		os.Exit(2)
+	}
+
+	opts := plugintest.Options{Timeout: *timeout, LargeBytes: *large, Concurrency: *conc}
+	if *payload != "" {
+		p, err := readPayload(*payload)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "plugintest:", err)
+			os.Exit(2)
+		}
+		opts.Payload = p
+	}
+	if *stageCfg != "" {
+		raw, err := readPayload(*stageCfg)
+		if err == nil {
+			opts.Config = &structpb.Struct{}
+			err = protojson.Unmarshal(raw, opts.Config)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "plugintest: config:", err)
+			os.Exit(2)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var (
+		svc    pb.TransformServiceClient
+		target string
+		closer func() error
+	)
+	if *command != "" {
+		args := flag.Args()
+		cli, err := transform.NewExecClient(ctx, *command, args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "plugintest:", err)
+			os.Exit(1)
+		}
+		svc, target, closer = cli.Service(), strings.Join(append([]string{*command}, args...), " "), cli.Close
+	} else {
+		cli, err := transform.NewGRPCClient(ctx, *addr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "plugintest:", err)
+			os.Exit(1)
+		}
+		svc, target, closer = cli.Service(), *addr, cli.Close
+	}
+
+	rep := plugintest.Run(ctx, target, svc, opts)
+	_ = closer()
+
+	if *asJSON {
+		_ = rep.WriteJSON(os.Stdout)
+	} else {
+		_ = rep.WriteText(os.Stdout)
+	}
+	if !rep.Passed() {
+		os.Exit(1)
+	}
+}
+
+func readPayload(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "@") {
+		return os.ReadFile(strings.TrimPrefix(s, "@"))
+	}
+	return []byte(s), nil
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/config"
+	"quanta/internal/pipeline"
+	"quanta/internal/transport"
+	"quanta/source/kafka"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var stdout io.Writer = os.Stdout
+
+func ping(ctx context.Context, cli *transport.Client, g *globals, args []string) error {
+	ctx, cancel := g.call(ctx)
+	defer cancel()
+	resp, err := cli.Ping(ctx, &pb.PingRequest{})
+	if err != nil {
+		return err
+	}
+	if node := resp.GetNode(); node != "" {
+		fmt.Fprintf(stdout, "%s (node %s)\n", resp.GetStatus(), node)
+		return nil
+	}
+	fmt.Fprintln(stdout, resp.GetStatus())
+	return nil
+}
+
+func deploy(ctx context.Context, cli *transport.Client, g *globals, args []string) error {
+	fs := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	file := fs.String("f", "", "pipeline spec to deploy")
+	replace := fs.Bool("replace", false, "restart a running pipeline of the same name with this spec")
+	if err := fs.Parse(args); err != nil || *file == "" {
+		return fmt.Errorf("%w: deploy [-replace] -f FILE", errUsage)
+	}
+	raw, err := deployable(*file)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.call(ctx)
+	defer cancel()
+	resp, err := cli.DeployPipeline(ctx, &pb.DeployRequest{Yaml: string(raw), Replace: *replace})
+	if err != nil {
+		return err
+	}
+	if v := resp.GetVersion(); v > 0 {
+		fmt.Fprintf(stdout, "deployed %s version %d\n", resp.GetId(), v)
+		return nil
+	}
+	fmt.Fprintf(stdout, "deployed %s\n", resp.GetId())
+	return nil
+}
+
+// deployable reads the spec at path for the engine, which cannot see this
+// machine's files: a v1 spec's source config file, relative to the spec, is
+// inlined by migrating the spec to v2. ${FILE:...} references are left for
+// the engine to resolve in its secrets directory. A spec that does not
+// decode is sent as it is, for the engine to report why.
+func deployable(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.DecodePipelineSpec(raw)
+	if err != nil || cfg.Source.ConfigFile == "" {
+		return raw, nil
+	}
+	migrated, err := config.MigrateV1(raw, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: inline the source config: %w", path, err)
+	}
+	return migrated, nil
+}
+
+func pause(ctx context.Context, cli *transport.Client, g *globals, args []string) error {
+	id, err := oneID("pause", args)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.call(ctx)
+	defer cancel()
+	if _, err := cli.PausePipeline(ctx, &pb.PauseRequest{Id: id}); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "paused %s\n", id)
+	return nil
+}
+
+func resume(ctx context.Context, cli *transport.Client, g *globals, args []string) error {
+	id, err := oneID("resume", args)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.call(ctx)
+	defer cancel()
+	if _, err := cli.ResumePipeline(ctx, &pb.ResumeRequest{Id: id}); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "resumed %s\n", id)
+	return nil
+}
+
+func list(ctx context.Context, cli *transport.Client, g *globals, args []string) error {
+	ctx, cancel := g.call(ctx)
+	defer cancel()
+	resp, err := cli.ListPipelines(ctx, &pb.ListPipelinesRequest{})
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATE\tLAG\tIN FLIGHT\tUNHEALTHY STAGES\tAGE")
+	for _, p := range resp.GetPipelines() {
+		unhealthy := 0
+		for _, st := range p.GetStages() {
+			if !st.GetHealthy() {
+				unhealthy++
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\n", p.GetId(), p.GetState(), p.GetLag(), p.GetInFlight(), unhealthy, age(p.GetStartedAtMs()))
+	}
+	return tw.Flush()
+}
+
+func status(ctx context.Context, cli *transport.Client, g *globals, args []string) error {
+	id, err := oneID("status", args)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.call(ctx)
+	defer cancel()
+	p, err := cli.GetPipelineStatus(ctx, &pb.PipelineStatusRequest{Id: id})
+	if err != nil {
+		return err
+	}
+	ready := "unknown"
+	if h, err := cli.Health.Check(ctx, &healthpb.HealthCheckRequest{Service: id}); err == nil {
+		ready = h.GetStatus().String()
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Pipeline:\t%s\n", p.GetId())
+	fmt.Fprintf(tw, "State:\t%s\n", p.GetState())
+	fmt.Fprintf(tw, "Ready:\t%s\n", ready)
+	fmt.Fprintf(tw, "Lag:\t%d\n", p.GetLag())
+	fmt.Fprintf(tw, "In flight:\t%d\n", p.GetInFlight())
+	fmt.Fprintf(tw, "Age:\t%s\n", age(p.GetStartedAtMs()))
+	if p.GetLastError() != "" {
+		fmt.Fprintf(tw, "Last error:\t%s\n", p.GetLastError())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(p.GetStages()) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(stdout)
+	tw = tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tHEALTHY\tERROR")
+	for _, st := range p.GetStages() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", st.GetName(), yesNo(st.GetHealthy()), st.GetError())
+	}
+	return tw.Flush()
+}
+
+// headerFlags collects repeated -header k=v flags.
+type headerFlags map[string]string
+
+func (h headerFlags) String() string { return fmt.Sprint(map[string]string(h)) }
+func (h headerFlags) Set(v string) error {
+	k, val, _ := strings.Cut(v, "=")
+	if k == "" {
+		return errors.New("want NAME or NAME=VALUE")
+	}
+	h[k] = val
+	return nil
+}
+
+// tap streams frames until interrupted, -n frames have arrived or the
+// pipeline stops. It is not bounded by -timeout.
+func tap(ctx context.Context, cli *transport.Client, g *globals, args []string) error {
+	fs := flag.NewFlagSet("tap", flag.ContinueOnError)
+	point := fs.String("point", "source", "source, stage:<name|index> or sink:<name>")
+	key := fs.String("key", "", "only frames with this key")
+	headers := headerFlags{}
+	fs.Var(headers, "header", "only frames with this header, NAME or NAME=VALUE (repeatable)")
+	sample := fs.Float64("sample", 0, "share of matching frames to keep, 0 keeps all")
+	buffer := fs.Uint("buffer", 0, "frames the engine queues for this tap before dropping")
+	count := fs.Int("n", 0, "stop after this many frames")
+	asJSON := fs.Bool("json", false, "print each frame as a JSON line")
+	maxBytes := fs.Int("max-bytes", 256, "truncate printed values to this many bytes")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: tap [flags] ID", errUsage)
+	}
+	id, err := oneID("tap", fs.Args())
+	if err != nil {
+		return err
+	}
+	req := &pb.TapRequest{Id: id, Point: *point, SampleRate: *sample, Buffer: uint32(*buffer)}
+	if *key != "" || len(headers) > 0 {
+		req.Filter = &pb.TapFilter{Headers: headers}
+		if *key != "" {
+			req.Filter.Key = []byte(*key)
+		}
+	}
+
+	stream, err := cli.Tap(ctx, req)
+	if err != nil {
+		return err
+	}
+	for n := 0; *count == 0 || n < *count; n++ {
+		msg, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF || grpcstatus.Code(err) == codes.Canceled || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if d := msg.GetDropped(); d > 0 {
+			fmt.Fprintf(os.Stderr, "quantactl: %d frames dropped, reader too slow\n", d)
+		}
+		if *asJSON {
+			b, err := protojson.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(stdout, string(b))
+			continue
+		}
+		printFrame(msg.GetPoint(), msg.GetFrame(), *maxBytes)
+	}
+	return nil
+}
+
+func printFrame(point string, f *pb.Frame, maxBytes int) {
+	pos := "-"
+	if k := f.GetCheckpoint().GetKafka(); k != nil {
+		pos = fmt.Sprintf("%s/%d@%d", k.Topic, k.Partition, k.Offset)
+	}
+	val := f.GetValue()
+	suffix := ""
+	if maxBytes > 0 && len(val) > maxBytes {
+		val, suffix = val[:maxBytes], "..."
+	}
+	fmt.Fprintf(stdout, "%s %s key=%q value=%q%s", point, pos, f.GetKey(), val, suffix)
+	hs := make([]string, 0, len(f.GetHeaders()))
+	for k := range f.GetHeaders() {
+		hs = append(hs, k)
+	}
+	sort.Strings(hs)
+	for _, k := range hs {
+		fmt.Fprintf(stdout, " %s=%q", k, f.GetHeaders()[k])
+	}
+	fmt.Fprintln(stdout)
+}
+
+// validate compiles a spec locally and handshakes with every plugin without
+// touching Kafka or a running engine.
+func history(ctx context.Context, cli *transport.Client, g *globals, args []string) error {
+	id, err := oneID("history", args)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.call(ctx)
+	defer cancel()
+	h, err := cli.GetPipelineHistory(ctx, &pb.HistoryRequest{Id: id})
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tDEPLOYED\tBY\tNOTE")
+	for _, v := range h.GetVersions() {
+		var note []string
+		if v.GetVersion() == h.GetCurrent() {
+			note = append(note, "current")
+			if h.GetPaused() {
+				note = append(note, "paused")
+			}
+		}
+		if v.GetRollbackOf() > 0 {
+			note = append(note, fmt.Sprintf("rollback of %d", v.GetRollbackOf()))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", v.GetVersion(),
+			time.UnixMilli(v.GetDeployedAtMs()).UTC().Format(time.RFC3339), dash(v.GetDeployedBy()), strings.Join(note, ", "))
+	}
+	return tw.Flush()
+}
+
+func rollback(ctx context.Context, cli *transport.Client, g *globals, args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	to := fs.Uint("to", 0, "version to restore (default the one before the current)")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: rollback [-to N] ID", errUsage)
+	}
+	id, err := oneID("rollback [-to N]", fs.Args())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.call(ctx)
+	defer cancel()
+	resp, err := cli.RollbackPipeline(ctx, &pb.RollbackRequest{Id: id, Version: uint32(*to)})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "rolled back %s, now version %d\n", resp.GetId(), resp.GetVersion())
+	return nil
+}
+
+func validate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	file := fs.String("f", "", "pipeline spec to validate")
+	timeout := fs.Duration("timeout", 10*time.Second, "time allowed for plugin handshakes")
+	if err := fs.Parse(args); err != nil || *file == "" {
+		return fmt.Errorf("%w: validate -f FILE", errUsage)
+	}
+	kafka.Register("sarama", func() kafka.Adapter { return &kafka.SaramaDriver{} })
+
+	cfg, confPath, err := config.LoadPipelineSpec(*file)
+	if err != nil {
+		return err
+	}
+	if err := pipeline.Validate(cfg, confPath); err != nil {
+		return fmt.Errorf("%s:\n%w", *file, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	checks, err := pipeline.Check(ctx, cfg, confPath)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tPROTOCOL\tHEALTHY\tERROR")
+	for _, c := range checks {
+		if !c.Healthy {
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\tv%d\t%s\t%s\n", c.Name, c.Protocol, yesNo(c.Healthy), c.Err)
+	}
+	if len(checks) > 0 {
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s: %d of %d stages failed", *file, failed, len(checks))
+	}
+	fmt.Fprintf(stdout, "%s: ok\n", *file)
+	return nil
+}
+
+func oneID(cmd string, args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("%w: %s ID", errUsage, cmd)
+	}
+	return args[0], nil
+}
+
+func age(startedMs int64) string {
+	if startedMs == 0 {
+		return "-"
+	}
+	return time.Since(time.UnixMilli(startedMs)).Truncate(time.Second).String()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
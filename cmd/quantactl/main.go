@@ -0,0 +1,141 @@
+// Command quantactl operates a running Quanta engine over its Control gRPC
+// service and validates pipeline specs offline.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"quanta/internal/transport"
+
+	"google.golang.org/grpc"
+)
+
+const usage = `usage: quantactl [flags] <command> [args]
+
+Commands:
+  ping                  check that the engine answers
+  deploy [-replace] -f FILE
+                        deploy a pipeline spec, replacing a running one
+                        of the same name with -replace
+  pause ID              stop a pipeline fetching
+  resume ID             resume a paused pipeline
+  list                  list pipelines
+  status ID             show a pipeline's stages, lag and health
+  validate -f FILE      compile a spec offline and handshake with its plugins
+  tap [-point P] ID     stream copies of frames from a running pipeline
+  history ID            list a pipeline's deployed versions
+  rollback [-to N] ID   redeploy version N, or the one before the current
+
+Flags:
+`
+
+// errUsage marks errors caused by bad arguments; they exit with status 2.
+var errUsage = errors.New("usage")
+
+type globals struct {
+	addr       string
+	timeout    time.Duration
+	tls        bool
+	ca         string
+	cert       string
+	key        string
+	serverName string
+	skipVerify bool
+	token      string
+}
+
+func main() {
+	var g globals
+	addr := os.Getenv("QUANTACTL_ADDR")
+	if addr == "" {
+		addr = "localhost:7070"
+	}
+	flag.StringVar(&g.addr, "addr", addr, "engine control address (env QUANTACTL_ADDR)")
+	flag.DurationVar(&g.timeout, "timeout", 10*time.Second, "per-call timeout")
+	flag.BoolVar(&g.tls, "tls", false, "connect with TLS")
+	flag.StringVar(&g.ca, "ca", "", "CA bundle to verify the engine (implies -tls)")
+	flag.StringVar(&g.cert, "cert", "", "client certificate for mTLS (implies -tls)")
+	flag.StringVar(&g.key, "key", "", "client key for mTLS")
+	flag.StringVar(&g.serverName, "server-name", "", "override the TLS server name")
+	flag.BoolVar(&g.skipVerify, "insecure-skip-verify", false, "do not verify the engine certificate")
+	flag.StringVar(&g.token, "token", os.Getenv("QUANTACTL_TOKEN"), "bearer token (env QUANTACTL_TOKEN)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	err := run(ctx, &g, flag.Args())
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, "quantactl:", err)
+		flag.Usage()
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "quantactl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, g *globals, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing command", errUsage)
+	}
+	cmd, args := args[0], args[1:]
+	if cmd == "validate" {
+		return validate(ctx, args)
+	}
+
+	cmds := map[string]func(context.Context, *transport.Client, *globals, []string) error{
+		"ping":     ping,
+		"deploy":   deploy,
+		"pause":    pause,
+		"resume":   resume,
+		"list":     list,
+		"status":   status,
+		"tap":      tap,
+		"history":  history,
+		"rollback": rollback,
+	}
+	fn, ok := cmds[cmd]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+	cli, err := g.dial()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	return fn(ctx, cli, g, args)
+}
+
+func (g *globals) dial() (*transport.Client, error) {
+	var tlsCfg *tls.Config
+	if g.tls || g.ca != "" || g.cert != "" || g.skipVerify {
+		var err error
+		if tlsCfg, err = transport.ClientTLS(g.ca, g.cert, g.key, g.serverName, g.skipVerify); err != nil {
+			return nil, err
+		}
+	}
+	var opts []grpc.DialOption
+	if g.token != "" {
+		opts = append(opts, transport.BearerToken(g.token))
+	}
+	return transport.Dial(g.addr, tlsCfg, opts...)
+}
+
+// call bounds a single RPC by the -timeout flag.
+func (g *globals) call(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, g.timeout)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "quanta/api/proto/v1"
+
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeControl records deploy requests and answers every call.
+type fakeControl struct {
+	pb.UnimplementedControlServer
+	deploys []*pb.DeployRequest
+}
+
+func (f *fakeControl) Ping(context.Context, *pb.PingRequest) (*pb.PingReply, error) {
+	return &pb.PingReply{Status: "ok", Node: "n1"}, nil
+}
+
+func (f *fakeControl) DeployPipeline(_ context.Context, req *pb.DeployRequest) (*pb.DeployReply, error) {
+	f.deploys = append(f.deploys, req)
+	return &pb.DeployReply{Id: "orders", Version: uint32(len(f.deploys))}, nil
+}
+
+func startEngine(t *testing.T) (*globals, *fakeControl, *bytes.Buffer) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	fake := &fakeControl{}
+	g := grpc.NewServer()
+	pb.RegisterControlServer(g, fake)
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	var out bytes.Buffer
+	prev := stdout
+	stdout = &out
+	t.Cleanup(func() { stdout = prev })
+	return &globals{addr: lis.Addr().String(), timeout: 5 * time.Second}, fake, &out
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeploy_InlinesSourceConfigAndSendsNoBaseDir(t *testing.T) {
+	g, fake, out := startEngine(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "kafka.yml"), "schema_version: v1\nbrokers: [b:9092]\nsasl_pass: ${FILE:kafka-pass}\n")
+	spec := filepath.Join(dir, "orders.yml")
+	writeFile(t, spec, "name: orders\nsource: { kind: kafka, driver: sarama, config: kafka.yml }\nsinks: [stdout]\n")
+
+	if err := run(context.Background(), g, []string{"deploy", "-f", spec}); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if len(fake.deploys) != 1 {
+		t.Fatalf("want one deploy, got %d", len(fake.deploys))
+	}
+	req := fake.deploys[0]
+	if req.BaseDir != "" {
+		t.Fatalf("the client's directory must not be sent, got %q", req.BaseDir)
+	}
+	var sent struct {
+		SchemaVersion string `yaml:"schema_version"`
+		Source        struct {
+			Config map[string]any `yaml:"config"`
+		} `yaml:"source"`
+	}
+	if err := yaml.Unmarshal([]byte(req.Yaml), &sent); err != nil {
+		t.Fatalf("sent spec: %v", err)
+	}
+	if sent.SchemaVersion != "v2" || sent.Source.Config["brokers"] == nil {
+		t.Fatalf("source config not inlined:\n%s", req.Yaml)
+	}
+	if sent.Source.Config["sasl_pass"] != "${FILE:kafka-pass}" {
+		t.Fatalf("references must be left for the engine:\n%s", req.Yaml)
+	}
+	if got := out.String(); got != "deployed orders version 1\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+}
+
+func TestDeploy_SendsSelfContainedSpecUnchanged(t *testing.T) {
+	g, fake, _ := startEngine(t)
+	spec := filepath.Join(t.TempDir(), "orders.yml")
+	const v2 = "schema_version: v2\nname: orders\nsource: { kind: kafka, driver: sarama, config: { brokers: [b:9092] } }\nsinks: [{ name: out, type: stdout }]\n"
+	writeFile(t, spec, v2)
+
+	if err := run(context.Background(), g, []string{"deploy", "-replace", "-f", spec}); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if req := fake.deploys[0]; req.Yaml != v2 || !req.Replace || req.BaseDir != "" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+}
+
+func TestDeploy_MissingSourceConfigFails(t *testing.T) {
+	g, fake, _ := startEngine(t)
+	spec := filepath.Join(t.TempDir(), "orders.yml")
+	writeFile(t, spec, "name: orders\nsource: { kind: kafka, driver: sarama, config: missing.yml }\nsinks: [stdout]\n")
+
+	err := run(context.Background(), g, []string{"deploy", "-f", spec})
+	if err == nil || !strings.Contains(err.Error(), "inline the source config") {
+		t.Fatalf("want an error about the source config, got %v", err)
+	}
+	if len(fake.deploys) != 0 {
+		t.Fatal("nothing must be sent when the spec cannot be made self-contained")
+	}
+}
+
+func TestRun_PingAndUsageErrors(t *testing.T) {
+	g, _, out := startEngine(t)
+	if err := run(context.Background(), g, []string{"ping"}); err != nil || out.String() != "ok (node n1)\n" {
+		t.Fatalf("ping: %q %v", out.String(), err)
+	}
+	for _, args := range [][]string{nil, {"nope"}, {"deploy"}, {"pause"}, {"pause", "a", "b"}} {
+		if err := run(context.Background(), g, args); !errors.Is(err, errUsage) {
+			t.Fatalf("%v: want a usage error, got %v", args, err)
+		}
+	}
+}
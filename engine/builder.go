@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"quanta/internal/pipeline"
+	"quanta/internal/spec"
+	"quanta/internal/transform"
+	"quanta/sdk"
+	"quanta/sink"
+	"quanta/source"
+	"quanta/source/kafka"
+)
+
+// Pipeline is a built pipeline, ready to be added to an Engine.
+type Pipeline struct {
+	r *pipeline.Runner
+}
+
+// ID is the pipeline's name; pipelines built without one get an id when
+// they are added.
+func (p *Pipeline) ID() string { return p.r.ID() }
+
+// Close releases everything a pipeline holds that was never added to an
+// engine. Pipelines an engine runs are closed when it stops.
+func (p *Pipeline) Close() error { return p.r.Close() }
+
+// StageOptions tune a transformer stage: Timeout bounds one call, a failed
+// call is retried RetryAttempts times RetryBackoff apart, and Config is
+// delivered to the transformer through Configure. Zero values take the
+// engine's defaults, as in a pipeline spec.
+type StageOptions struct {
+	Timeout       time.Duration
+	RetryAttempts int
+	RetryBackoff  time.Duration
+	Config        map[string]any
+}
+
+// RestartPolicy controls how the source is restarted after an error that
+// is not marked fatal: backoff doubles from InitialBackoff up to MaxBackoff,
+// and after MaxRestarts consecutive restarts the pipeline fails. Zero
+// values keep the defaults; MaxRestarts 0 restarts forever.
+type RestartPolicy struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxRestarts    int
+}
+
+// Builder puts a pipeline together from a source, a chain of transformer
+// stages and the sinks every frame leaving the chain is delivered to.
+// Methods record the first error and Build returns it, so calls can be
+// chained.
+type Builder struct {
+	b      *pipeline.Builder
+	hasSrc bool
+	err    error
+}
+
+// NewBuilder starts a pipeline called name.
+func NewBuilder(name string) *Builder {
+	return &Builder{b: pipeline.NewBuilder(name)}
+}
+
+// Source sets the pipeline's source.
+func (b *Builder) Source(s source.Source) *Builder {
+	if b.err == nil && b.setSource() {
+		b.b.SetSource(s)
+	}
+	return b
+}
+
+// Kafka reads from Kafka through the named driver, such as "sarama".
+func (b *Builder) Kafka(driver string, cfg kafka.Config) *Builder {
+	if b.err != nil || !b.setSource() {
+		return b
+	}
+	src, err := kafka.NewAdapter(driver)
+	if err != nil {
+		b.err = fmt.Errorf("source: %w", err)
+		return b
+	}
+	if err := src.Configure(cfg); err != nil {
+		_ = src.Close()
+		b.err = fmt.Errorf("source: %w", err)
+		return b
+	}
+	b.b.SetSource(src)
+	return b
+}
+
+func (b *Builder) setSource() bool {
+	if b.hasSrc {
+		b.err = errors.New("source: already set")
+		return false
+	}
+	b.hasSrc = true
+	return true
+}
+
+// Restart sets the source's restart policy.
+func (b *Builder) Restart(p RestartPolicy) *Builder {
+	o := pipeline.DefaultSupervisorOptions
+	if p.InitialBackoff > 0 {
+		o.InitialBackoff = p.InitialBackoff
+	}
+	if p.MaxBackoff > 0 {
+		o.MaxBackoff = p.MaxBackoff
+	}
+	o.MaxRestarts = p.MaxRestarts
+	b.b.SetSupervisorOptions(o)
+	return b
+}
+
+// GRPC appends a stage calling the transformer plugin served at addr.
+func (b *Builder) GRPC(name, addr string, o StageOptions) *Builder {
+	t := stageSpec(name, "grpc", o)
+	t.Address = addr
+	return b.compile(t)
+}
+
+// Exec appends a stage running a transformer plugin the engine launches
+// itself.
+func (b *Builder) Exec(name, command string, args []string, o StageOptions) *Builder {
+	t := stageSpec(name, "exec", o)
+	t.Command, t.Args = command, args
+	return b.compile(t)
+}
+
+// InProcess appends a stage running the transformer registered as handler
+// with RegisterTransformer.
+func (b *Builder) InProcess(name, handler string, o StageOptions) *Builder {
+	t := stageSpec(name, "inproc", o)
+	t.Handler = handler
+	return b.compile(t)
+}
+
+// Handler appends a stage running h in the engine's process, as an
+// unregistered in-process transformer.
+func (b *Builder) Handler(name string, h sdk.HandlerFunc, o StageOptions, opts ...sdk.Option) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if err := sdk.CheckHandler(h, opts...); err != nil {
+		b.err = fmt.Errorf("transform %s: %w", name, err)
+		return b
+	}
+	var cfg *structpb.Struct
+	if len(o.Config) > 0 {
+		var err error
+		if cfg, err = structpb.NewStruct(o.Config); err != nil {
+			b.err = fmt.Errorf("transform %s: config: %w", name, err)
+			return b
+		}
+	}
+	b.b.Append(pipeline.NewTransformStage(name, transform.NewHandlerClient(h, opts...), pipeline.StageOptions{
+		Timeout:       o.Timeout,
+		RetryAttempts: o.RetryAttempts,
+		RetryBackoff:  o.RetryBackoff,
+		Config:        cfg,
+	}))
+	return b
+}
+
+// Sink adds a sink every frame leaving the stages is delivered to. Sinks
+// implementing sink.AckAware ack frames themselves; the others ack once
+// Push returns. s must already be configured.
+func (b *Builder) Sink(name string, s sink.Adapter) *Builder {
+	if b.err == nil {
+		b.b.AddNamedSink(name, s)
+	}
+	return b
+}
+
+// Build returns the pipeline. On error everything added is closed.
+func (b *Builder) Build() (*Pipeline, error) {
+	if b.err == nil && !b.hasSrc {
+		b.err = errors.New("source: not set")
+	}
+	if b.err != nil {
+		b.b.Discard()
+		return nil, b.err
+	}
+	r, err := b.b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &Pipeline{r: r}, nil
+}
+
+func (b *Builder) compile(t spec.TransformerSpec) *Builder {
+	if b.err != nil {
+		return b
+	}
+	stages, err := pipeline.CompileStages([]spec.TransformerSpec{t})
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.b.Append(stages[0])
+	return b
+}
+
+func stageSpec(name, typ string, o StageOptions) spec.TransformerSpec {
+	t := spec.TransformerSpec{Name: name, Type: typ, Config: o.Config}
+	t.TimeoutMS = int(o.Timeout / time.Millisecond)
+	t.RetryPolicy.Attempts = o.RetryAttempts
+	t.RetryPolicy.BackoffMS = int(o.RetryBackoff / time.Millisecond)
+	return t
+}
+
+// Load compiles the pipeline spec in path.
+func Load(path string) (*Pipeline, error) {
+	r, err := pipeline.Compile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Pipeline{r: r}, nil
+}
+
+// Parse compiles a pipeline spec held in memory; relative paths in it are
+// resolved against baseDir.
+func Parse(raw []byte, baseDir string) (*Pipeline, error) {
+	r, err := pipeline.CompileBytes(raw, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Pipeline{r: r}, nil
+}
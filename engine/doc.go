@@ -0,0 +1,13 @@
+// Package engine embeds Quanta in another Go program. Pipelines are put
+// together with a Builder, from a source, transformer stages and sinks, or
+// loaded from a pipeline spec, which compiles through the same builder; an
+// Engine runs them until it is stopped.
+//
+// Sources, sinks and in-process transformers registered here can be named
+// in pipeline specs like the built-in ones: a source by source.kind, a sink
+// by its type and a transformer by a stage of type "inproc". Register them
+// from init, before any spec that names them is loaded.
+//
+// Metrics are registered with the Prometheus default registry; call
+// RegisterMetrics to expose them from another one.
+package engine
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	iengine "quanta/internal/engine"
+)
+
+// ErrStopped is returned when adding a pipeline to a stopped engine.
+var ErrStopped = errors.New("engine: stopped")
+
+// Engine runs pipelines. Pipelines added before Start are started with it;
+// later ones start as they are added. Stop drains them all.
+type Engine struct {
+	mu      sync.Mutex
+	m       *iengine.Manager
+	cancel  context.CancelFunc
+	pending []*Pipeline
+	stopped bool
+}
+
+// New returns an engine that is not yet running.
+func New() *Engine { return &Engine{} }
+
+// Add hands p to the engine, starting it if the engine is running. A
+// pipeline without a name gets a generated id.
+func (e *Engine) Add(p *Pipeline) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch {
+	case e.stopped:
+		return ErrStopped
+	case e.m == nil:
+		e.pending = append(e.pending, p)
+		return nil
+	}
+	return e.m.Add(p.r)
+}
+
+// Start starts every pipeline added so far. Pipelines keep running until
+// Stop, whatever happens to ctx afterwards. If a pipeline fails to start
+// the ones started are stopped again and the rest closed.
+func (e *Engine) Start(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch {
+	case e.stopped:
+		return ErrStopped
+	case e.m != nil:
+		return errors.New("engine: already started")
+	}
+	rctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	m := iengine.NewManager(rctx)
+	for i, p := range e.pending {
+		if err := m.Add(p.r); err != nil {
+			for _, rest := range e.pending[i:] {
+				_ = rest.r.Close()
+			}
+			e.pending = nil
+			_ = m.Shutdown(ctx)
+			cancel()
+			return err
+		}
+	}
+	e.m, e.cancel, e.pending = m, cancel, nil
+	return nil
+}
+
+// Stop drains every pipeline within ctx, committing what their sinks have
+// acked, and closes them. The engine cannot be started again.
+func (e *Engine) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	m, cancel, pending := e.m, e.cancel, e.pending
+	e.stopped, e.pending = true, nil
+	e.mu.Unlock()
+	for _, p := range pending {
+		_ = p.r.Close()
+	}
+	if m == nil {
+		return nil
+	}
+	defer cancel()
+	return m.Shutdown(ctx)
+}
+
+// Failed delivers an error once every pipeline the engine runs has failed;
+// a caller that should not outlive its pipelines selects on it. It is nil
+// before Start.
+func (e *Engine) Failed() <-chan error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.m == nil {
+		return nil
+	}
+	return e.m.Fatal()
+}
+
+// PipelineStatus describes one running pipeline.
+type PipelineStatus struct {
+	ID    string
+	State string
+	// Lag and InFlight are reported by sources that track them, such as
+	// Kafka.
+	Lag       int64
+	InFlight  int64
+	StartedAt time.Time
+	LastError string
+	Stages    []StageStatus
+}
+
+// StageStatus is the last health answer from one transformer stage.
+type StageStatus struct {
+	Name    string
+	Healthy bool
+	Error   string
+}
+
+// Status reports every pipeline the engine is running, sorted by id. Stage
+// health is asked for within ctx.
+func (e *Engine) Status(ctx context.Context) []PipelineStatus {
+	e.mu.Lock()
+	m := e.m
+	e.mu.Unlock()
+	if m == nil {
+		return nil
+	}
+	var out []PipelineStatus
+	for _, st := range m.List(ctx) {
+		ps := PipelineStatus{
+			ID:        st.Id,
+			State:     st.State,
+			Lag:       st.Lag,
+			InFlight:  st.InFlight,
+			StartedAt: time.UnixMilli(st.StartedAtMs),
+			LastError: st.LastError,
+		}
+		for _, s := range st.Stages {
+			ps.Stages = append(ps.Stages, StageStatus{Name: s.Name, Healthy: s.Healthy, Error: s.Error})
+		}
+		out = append(out, ps)
+	}
+	return out
+}
+
+// Ready reports why the engine is not ready to process frames: it is not
+// started, runs no pipelines, or one of them is not ready.
+func (e *Engine) Ready(ctx context.Context) error {
+	e.mu.Lock()
+	m := e.m
+	e.mu.Unlock()
+	if m == nil {
+		return errors.New("engine: not started")
+	}
+	return m.Ready(ctx)
+}
@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	pb "quanta/api/proto/v1"
+	"quanta/sdk"
+	"quanta/sink"
+	"quanta/source"
+)
+
+// listSource emits its values once, then waits to be stopped.
+type listSource struct {
+	values []string
+	prefix string
+
+	mu    sync.Mutex
+	acked []int64
+}
+
+func (s *listSource) Configure(c map[string]any) error {
+	s.prefix, _ = c["prefix"].(string)
+	return nil
+}
+
+func (s *listSource) Run(ctx context.Context, emit source.EmitFunc) error {
+	for i, v := range s.values {
+		f := &pb.Frame{
+			Value:      []byte(s.prefix + v),
+			Checkpoint: &pb.CheckpointToken{Kind: &pb.CheckpointToken_Kafka{Kafka: &pb.KafkaOffset{Topic: "list", Offset: int64(i)}}},
+		}
+		if err := emit(f); err != nil {
+			return err
+		}
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *listSource) OnAck(a *pb.ConnectorAck) {
+	s.mu.Lock()
+	s.acked = append(s.acked, a.Checkpoint.GetKafka().GetOffset())
+	s.mu.Unlock()
+}
+
+func (s *listSource) Close() error { return nil }
+
+func (s *listSource) ackedOffsets() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := append([]int64(nil), s.acked...)
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// listSink records the values pushed to it.
+type listSink struct {
+	mu     sync.Mutex
+	values []string
+}
+
+func (s *listSink) Configure(any) error { return nil }
+func (s *listSink) Close() error        { return nil }
+func (s *listSink) Push(f *pb.Frame) error {
+	s.mu.Lock()
+	s.values = append(s.values, string(f.Value))
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *listSink) got() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.values, ",")
+}
+
+func upper(_ context.Context, ev sdk.Event) ([]sdk.Event, sdk.Status, error) {
+	ev.Value = bytes.ToUpper(ev.Value)
+	return []sdk.Event{ev}, sdk.StatusOK, nil
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEngine_RunsBuiltPipeline(t *testing.T) {
+	src := &listSource{values: []string{"a", "b", "c"}}
+	out := &listSink{}
+	p, err := NewBuilder("embedded").
+		Source(src).
+		Handler("upper", upper, StageOptions{Timeout: time.Second}).
+		Sink("out", out).
+		Build()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	e := New()
+	if err := e.Add(p); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := e.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	waitFor(t, "acks", func() bool { return len(src.ackedOffsets()) == 3 })
+	if got := out.got(); got != "A,B,C" {
+		t.Fatalf("sink got %q, want A,B,C", got)
+	}
+
+	st := e.Status(context.Background())
+	if len(st) != 1 || st[0].ID != "embedded" || st[0].State != "running" {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	if len(st[0].Stages) != 1 || st[0].Stages[0].Name != "upper" || !st[0].Stages[0].Healthy {
+		t.Fatalf("unexpected stage status %+v", st[0].Stages)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := e.Stop(ctx); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := e.Add(p); err != ErrStopped {
+		t.Fatalf("add after stop: got %v, want ErrStopped", err)
+	}
+}
+
+func TestEngine_SpecUsesRegisteredComponents(t *testing.T) {
+	src := &listSource{values: []string{"x", "y"}}
+	out := &listSink{}
+	RegisterSource("test-list", func() source.Source { return src })
+	RegisterSink("test-list", func() sink.Adapter { return out })
+	RegisterTransformer("test-upper", upper)
+
+	p, err := Parse([]byte(`
+schema_version: v2
+name: from-spec
+source:
+  kind: test-list
+  config:
+    prefix: "k-"
+transformers:
+  - name: shout
+    type: inproc
+    handler: test-upper
+sinks:
+  - name: out
+    type: test-list
+`), t.TempDir())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	e := New()
+	if err := e.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer e.Stop(context.Background())
+	if err := e.Add(p); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	waitFor(t, "acks", func() bool { return len(src.ackedOffsets()) == 2 })
+	if got := out.got(); got != "K-X,K-Y" {
+		t.Fatalf("sink got %q, want K-X,K-Y", got)
+	}
+}
+
+func TestBuilder_ReportsFirstError(t *testing.T) {
+	_, err := NewBuilder("bad").
+		Source(&listSource{}).
+		InProcess("missing", "not-registered", StageOptions{}).
+		Sink("out", &listSink{}).
+		Build()
+	if err == nil || !strings.Contains(err.Error(), `unknown in-process transformer "not-registered"`) {
+		t.Fatalf("got %v, want unknown in-process transformer", err)
+	}
+	if _, err := NewBuilder("nosource").Build(); err == nil {
+		t.Fatal("build without a source should fail")
+	}
+	_, err = NewBuilder("nohandler").
+		Source(&listSource{}).
+		Handler("nil", nil, StageOptions{}).
+		Sink("out", &listSink{}).
+		Build()
+	if !errors.Is(err, sdk.ErrNoHandler) {
+		t.Fatalf("got %v, want sdk.ErrNoHandler", err)
+	}
+}
+
+func TestRegisterTransformer_PanicsWithoutHandler(t *testing.T) {
+	defer func() {
+		if p := recover(); p == nil {
+			t.Fatal("registering a nil handler must panic")
+		}
+	}()
+	RegisterTransformer("nil-handler", nil)
+}
+
+func TestRegisterMetrics_OwnRegistry(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if err := RegisterMetrics(reg); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := RegisterMetrics(reg); err != nil {
+		t.Fatalf("registering twice: %v", err)
+	}
+	if err := RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		t.Fatalf("default registerer: %v", err)
+	}
+}
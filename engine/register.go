@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"quanta/internal/pipeline"
+	"quanta/internal/telemetry"
+	"quanta/internal/transform"
+	"quanta/sdk"
+	"quanta/sink"
+	"quanta/source"
+)
+
+// RegisterSource makes a source kind available as source.kind in pipeline
+// specs. Sources implementing source.Configurable receive the spec's
+// source.config block. "kafka" is reserved for the built-in source.
+func RegisterSource(kind string, f func() source.Source) {
+	source.Register(kind, f)
+}
+
+// RegisterSink makes a sink type available as a sink's type in pipeline
+// specs; Configure receives the sink's config block as a map. Registering a
+// built-in type replaces it.
+func RegisterSink(typ string, f func() sink.Adapter) {
+	sink.Register(typ, f)
+}
+
+// RegisterTransformer makes h available to stages of type "inproc" named
+// name, or whose handler is name. Each stage gets its own SDK server, so
+// stage config and metadata options work as they do in a plugin binary.
+// It panics if h is nil and opts set no batch handler.
+func RegisterTransformer(name string, h sdk.HandlerFunc, opts ...sdk.Option) {
+	if err := sdk.CheckHandler(h, opts...); err != nil {
+		panic(fmt.Sprintf("engine: transformer %q: %v", name, err))
+	}
+	transform.RegisterInProcess(name, func() transform.Client {
+		return transform.NewHandlerClient(h, opts...)
+	})
+}
+
+// UnregisterTransformer removes a transformer registered with
+// RegisterTransformer. Running pipelines keep their stages.
+func UnregisterTransformer(name string) {
+	transform.UnregisterInProcess(name)
+}
+
+// RegisterMetrics registers the engine's metrics with reg. Collectors reg
+// already has are skipped, so passing the default registerer is harmless.
+func RegisterMetrics(reg prometheus.Registerer) error {
+	cs := append(telemetry.Collectors(), pipeline.Collectors()...)
+	for _, c := range cs {
+		if err := reg.Register(c); err != nil {
+			var are prometheus.AlreadyRegisteredError
+			if !errors.As(err, &are) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"quanta/internal/logging"
+	"quanta/internal/secrets"
+
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// Auditor records mutating control plane calls as JSON lines.
+type Auditor struct {
+	log *slog.Logger
+}
+
+// NewAuditor writes audit records to w, with resolved config secrets
+// redacted.
+func NewAuditor(w io.Writer) *Auditor {
+	return &Auditor{log: slog.New(logging.Redacting(slog.NewJSONHandler(w, nil)))}
+}
+
+// Event is one audited call. Diff is the change to the pipeline spec, if
+// the call touched one.
+type Event struct {
+	Method   string
+	Pipeline string
+	Diff     string
+	Err      error
+}
+
+// Record writes ev with the caller from ctx. A nil Auditor records nothing.
+func (a *Auditor) Record(ctx context.Context, ev Event) {
+	if a == nil {
+		return
+	}
+	id := FromContext(ctx)
+	attrs := []any{
+		"identity", id.Name,
+		"auth", id.Method,
+		"role", id.Role.String(),
+		"method", ev.Method,
+		"pipeline", ev.Pipeline,
+		"outcome", status.Code(ev.Err).String(),
+	}
+	if ev.Err != nil {
+		attrs = append(attrs, "error", ev.Err.Error())
+	}
+	if ev.Diff != "" {
+		attrs = append(attrs, "diff", ev.Diff)
+	}
+	a.log.InfoContext(ctx, "audit", attrs...)
+}
+
+// SpecDiff is Diff over two pipeline specs with the values of
+// credential-like keys masked, since a v2 spec may hold sink or source
+// credentials inline. References such as ${FILE:...} are kept, as they
+// name a secret rather than hold it.
+func SpecDiff(old, new string) string {
+	return Diff(RedactSpec(old), RedactSpec(new))
+}
+
+// RedactSpec masks the values of credential-like keys anywhere in a YAML
+// document. A document that does not parse is masked whole.
+func RedactSpec(raw string) string {
+	if strings.TrimSpace(raw) == "" {
+		return raw
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &doc); err != nil {
+		return secrets.Redacted + "\n"
+	}
+	redactNode(&doc, false)
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return secrets.Redacted + "\n"
+	}
+	_ = enc.Close()
+	return b.String()
+}
+
+func redactNode(n *yaml.Node, sensitive bool) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if sensitive && !strings.HasPrefix(n.Value, "${") {
+			n.Value, n.Style, n.Tag = secrets.Redacted, 0, "!!str"
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			redactNode(n.Content[i+1], sensitive || secrets.SensitiveKey(n.Content[i].Value))
+		}
+	default:
+		for _, c := range n.Content {
+			redactNode(c, sensitive)
+		}
+	}
+}
+
+// Diff returns the lines removed from old ("-") and added in new ("+"),
+// in order, with unchanged lines left out.
+func Diff(old, new string) string {
+	a, b := splitLines(old), splitLines(new)
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/secrets"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func tokenHash(tok string) string {
+	sum := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(`schema_version: v1
+authenticated: viewer
+users:
+  - {name: ci, role: admin, token_sha256: ` + tokenHash("s3cret") + `}
+  - {name: alice.example.com, role: operator}
+`))
+	if err != nil {
+		t.Fatalf("ParsePolicy: %v", err)
+	}
+	if p.Anonymous != RoleNone || p.Authenticated != RoleViewer || p.Users[0].Role != RoleAdmin {
+		t.Fatalf("unexpected policy: %+v", p)
+	}
+	if u, err := p.tokenUser("s3cret"); err != nil || u.Name != "ci" {
+		t.Fatalf("tokenUser: %+v %v", u, err)
+	}
+	if _, err := p.tokenUser("guess"); !errors.Is(err, errBadToken) {
+		t.Fatalf("want errBadToken, got %v", err)
+	}
+	if name, role := p.certRole([]string{"svc", "alice.example.com"}); name != "alice.example.com" || role != RoleOperator {
+		t.Fatalf("bound certificate: %s %s", name, role)
+	}
+	if name, role := p.certRole([]string{"bob"}); name != "bob" || role != RoleViewer {
+		t.Fatalf("unbound certificate: %s %s", name, role)
+	}
+
+	for name, bad := range map[string]string{
+		"role":    "users: [{name: x, role: root}]",
+		"no role": "users: [{name: x}]",
+		"hash":    "users: [{name: x, role: admin, token_sha256: abc}]",
+		"field":   "users: [{name: x, role: admin, password: y}]",
+		"schema":  "schema_version: v2",
+	} {
+		if _, err := ParsePolicy([]byte(bad)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+type whoami struct{ pb.UnimplementedControlServer }
+
+func (whoami) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingReply, error) {
+	return &pb.PingReply{Status: FromContext(ctx).String()}, nil
+}
+
+func (whoami) DeployPipeline(context.Context, *pb.DeployRequest) (*pb.DeployReply, error) {
+	return &pb.DeployReply{Id: "p"}, nil
+}
+
+func TestAuthorizer_TokensAndRoles(t *testing.T) {
+	p, err := ParsePolicy([]byte(`users:
+  - {name: dash, role: viewer, token_sha256: ` + tokenHash("view") + `}
+  - {name: ci, role: admin, token_sha256: ` + tokenHash("admin") + `}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer(NewAuthorizer(p).ServerOptions()...)
+	pb.RegisterControlServer(g, whoami{})
+	healthpb.RegisterHealthServer(g, health.NewServer())
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+	cli := pb.NewControlClient(cc)
+
+	as := func(tok string) context.Context {
+		if tok == "" {
+			return context.Background()
+		}
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+tok)
+	}
+	if _, err := healthpb.NewHealthClient(cc).Check(as(""), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("health must not need credentials: %v", err)
+	}
+	if _, err := cli.Ping(as(""), &pb.PingRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("anonymous ping: want Unauthenticated, got %v", err)
+	}
+	if _, err := cli.Ping(as("nope"), &pb.PingRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("bad token: want Unauthenticated, got %v", err)
+	}
+	resp, err := cli.Ping(as("view"), &pb.PingRequest{})
+	if err != nil || resp.Status != "dash (token, viewer)" {
+		t.Fatalf("viewer ping: %v %v", resp, err)
+	}
+	if _, err := cli.DeployPipeline(as("view"), &pb.DeployRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("viewer deploy: want PermissionDenied, got %v", err)
+	}
+	if _, err := cli.DeployPipeline(as("admin"), &pb.DeployRequest{}); err != nil {
+		t.Fatalf("admin deploy: %v", err)
+	}
+	if _, err := cli.DeletePipeline(as("view"), &pb.DeleteRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("viewer delete: want PermissionDenied, got %v", err)
+	}
+}
+
+func TestAuditor_RecordsIdentityAndDiff(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithIdentity(context.Background(), Identity{Name: "ci", Method: "token", Role: RoleAdmin})
+	NewAuditor(&buf).Record(ctx, Event{
+		Method:   "DeployPipeline",
+		Pipeline: "orders",
+		Diff:     Diff("a\nb\nc\n", "a\nB\nc\nd\n"),
+	})
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("audit record is not JSON: %v: %s", err, buf.String())
+	}
+	if rec["identity"] != "ci" || rec["role"] != "admin" || rec["pipeline"] != "orders" || rec["outcome"] != "OK" {
+		t.Fatalf("unexpected record: %v", rec)
+	}
+	if rec["diff"] != "-b\n+B\n+d\n" {
+		t.Fatalf("unexpected diff %q", rec["diff"])
+	}
+	if d := Diff("x\n", "x\n"); d != "" {
+		t.Fatalf("identical specs must not diff, got %q", d)
+	}
+	if d := Diff("", "a\nb"); !strings.HasPrefix(d, "+a\n+b") {
+		t.Fatalf("new spec must be all additions, got %q", d)
+	}
+}
+
+func TestAuthorizer_WithoutPolicyFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewAuthorizer(nil).authorize(ctx, "/quanta.v1.Control/Ping"); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("want Unauthenticated without a policy, got %v", err)
+	}
+	if _, err := NewAuthorizer(nil).authorize(ctx, "/grpc.health.v1.Health/Check"); err != nil {
+		t.Fatalf("health must stay public: %v", err)
+	}
+	got, err := NewInsecureAuthorizer().authorize(ctx, "/quanta.v1.Control/DeployPipeline")
+	if err != nil || FromContext(got).Role != RoleAdmin {
+		t.Fatalf("insecure authorizer: %v, %v", FromContext(got), err)
+	}
+}
+
+func TestSpecDiff_MasksInlineCredentials(t *testing.T) {
+	old := "name: orders\nsinks:\n  - { name: out, type: kafka, config: { brokers: [b:9092], sasl_pass: hunter2 } }\n"
+	new := "name: orders\nsinks:\n  - { name: out, type: kafka, config: { brokers: [b:9092], sasl_pass: \"${FILE:pass}\" } }\n" +
+		"transformers:\n  - name: enrich\n    config:\n      auth:\n        bearer: tok-123\n      region: eu\n"
+	d := SpecDiff(old, new)
+	if strings.Contains(d, "hunter2") || strings.Contains(d, "tok-123") {
+		t.Fatalf("diff leaks a credential: %q", d)
+	}
+	for _, want := range []string{"${FILE:pass}", "region: eu", "bearer: '" + secrets.Redacted + "'"} {
+		if !strings.Contains(d, want) {
+			t.Errorf("diff lacks %q: %q", want, d)
+		}
+	}
+	if got := RedactSpec("a: [unterminated"); strings.Contains(got, "unterminated") {
+		t.Fatalf("an unparsable spec must be masked whole, got %q", got)
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Identity is the authenticated caller of an RPC.
+type Identity struct {
+	// Name is the policy user, the certificate common name or "anonymous".
+	Name string
+	// Method is how the caller authenticated: "token", "mtls" or "none".
+	Method string
+	Role   Role
+}
+
+func (id Identity) String() string { return id.Name + " (" + id.Method + ", " + id.Role.String() + ")" }
+
+type identityKey struct{}
+
+// WithIdentity returns ctx carrying id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the caller recorded by the interceptors. Without one
+// the caller is anonymous.
+func FromContext(ctx context.Context) Identity {
+	if id, ok := ctx.Value(identityKey{}).(Identity); ok {
+		return id
+	}
+	return Identity{Name: "anonymous", Method: "none"}
+}
+
+// MethodRoles is the minimum role for each Control RPC. Methods missing
+// here need RoleAdmin, except those under PublicServices.
+var MethodRoles = map[string]Role{
+	"/quanta.v1.Control/Ping":               RoleViewer,
+	"/quanta.v1.Control/ListPipelines":      RoleViewer,
+	"/quanta.v1.Control/GetPipelineStatus":  RoleViewer,
+	"/quanta.v1.Control/GetPipelineHistory": RoleViewer,
+	"/quanta.v1.Control/PausePipeline":      RoleOperator,
+	"/quanta.v1.Control/ResumePipeline":     RoleOperator,
+	"/quanta.v1.Control/Tap":                RoleOperator,
+	"/quanta.v1.Control/DeployPipeline":     RoleAdmin,
+	"/quanta.v1.Control/DeletePipeline":     RoleAdmin,
+	"/quanta.v1.Control/RollbackPipeline":   RoleAdmin,
+}
+
+// PublicServices are served to everyone so probes need no credentials.
+var PublicServices = []string{"/grpc.health.v1.Health/", "/quanta.v1.Health/"}
+
+// Authorizer resolves callers against a policy. Without one it fails
+// closed: every call outside PublicServices is rejected.
+type Authorizer struct {
+	policy   *Policy
+	insecure bool
+}
+
+func NewAuthorizer(p *Policy) *Authorizer { return &Authorizer{policy: p} }
+
+// NewInsecureAuthorizer authorizes every caller as an anonymous admin, for
+// engines explicitly configured to serve the control plane without a
+// policy.
+func NewInsecureAuthorizer() *Authorizer { return &Authorizer{insecure: true} }
+
+// ServerOptions installs the authorizer's interceptors on a gRPC server.
+func (a *Authorizer) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(a.unary),
+		grpc.ChainStreamInterceptor(a.stream),
+	}
+}
+
+func (a *Authorizer) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return h(ctx, req)
+}
+
+func (a *Authorizer) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+	ctx, err := a.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return h(srv, &identityStream{ServerStream: ss, ctx: ctx})
+}
+
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context { return s.ctx }
+
+func (a *Authorizer) authorize(ctx context.Context, method string) (context.Context, error) {
+	for _, prefix := range PublicServices {
+		if strings.HasPrefix(method, prefix) {
+			return ctx, nil
+		}
+	}
+	id, err := a.identify(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	need, ok := MethodRoles[method]
+	if !ok {
+		need = RoleAdmin
+	}
+	if id.Role < need {
+		if id.Method == "none" {
+			return nil, status.Errorf(codes.Unauthenticated, "%s requires credentials", method)
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "%s requires role %s, %s has %s", method, need, id.Name, id.Role)
+	}
+	return WithIdentity(ctx, id), nil
+}
+
+// identify prefers a bearer token over the client certificate.
+func (a *Authorizer) identify(ctx context.Context) (Identity, error) {
+	if a.policy == nil {
+		if a.insecure {
+			return Identity{Name: "anonymous", Method: "none", Role: RoleAdmin}, nil
+		}
+		return Identity{}, errors.New("the engine has no auth policy; control calls are refused")
+	}
+	if tok, ok := bearerToken(ctx); ok {
+		u, err := a.policy.tokenUser(tok)
+		if err != nil {
+			return Identity{}, err
+		}
+		return Identity{Name: u.Name, Method: "token", Role: u.Role}, nil
+	}
+	if names := certNames(ctx); len(names) > 0 {
+		name, role := a.policy.certRole(names)
+		return Identity{Name: name, Method: "mtls", Role: role}, nil
+	}
+	return Identity{Name: "anonymous", Method: "none", Role: a.policy.Anonymous}, nil
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if scheme, tok, ok := strings.Cut(v, " "); ok && strings.EqualFold(scheme, "bearer") {
+			return strings.TrimSpace(tok), true
+		}
+	}
+	return "", false
+}
+
+// certNames lists the common name and DNS SANs of a verified client
+// certificate.
+func certNames(ctx context.Context) []string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	leaf := info.State.VerifiedChains[0][0]
+	var names []string
+	if leaf.Subject.CommonName != "" {
+		names = append(names, leaf.Subject.CommonName)
+	}
+	return append(names, leaf.DNSNames...)
+}
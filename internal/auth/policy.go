@@ -0,0 +1,189 @@
+// Package auth authenticates control plane callers by client certificate or
+// bearer token, authorizes each RPC against a role, and records mutating
+// calls in an audit log.
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"quanta/internal/secrets"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Role is a caller's permission level; each role includes the ones below it.
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleOperator
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// ParseRole accepts viewer, operator, admin, or an empty string for none.
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "":
+		return RoleNone, nil
+	case "viewer":
+		return RoleViewer, nil
+	case "operator":
+		return RoleOperator, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return RoleNone, fmt.Errorf("unknown role %q (want viewer, operator or admin)", s)
+}
+
+func (r *Role) UnmarshalYAML(n *yaml.Node) error {
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+	v, err := ParseRole(s)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// Policy assigns roles to callers.
+type Policy struct {
+	SchemaVersion string `yaml:"schema_version"`
+	// Anonymous is the role of callers without credentials; none rejects
+	// them.
+	Anonymous Role `yaml:"anonymous"`
+	// Authenticated is the role of callers with a verified client
+	// certificate that no user entry names.
+	Authenticated Role   `yaml:"authenticated"`
+	Users         []User `yaml:"users"`
+}
+
+// User binds a role to a certificate identity (common name or DNS SAN) or,
+// when TokenSHA256 is set, to a bearer token.
+type User struct {
+	Name string `yaml:"name"`
+	Role Role   `yaml:"role"`
+	// TokenSHA256 is the hex SHA-256 of the user's bearer token; the token
+	// itself never appears in the policy.
+	TokenSHA256 string `yaml:"token_sha256"`
+}
+
+// HasTokens reports whether any user authenticates with a bearer token.
+func (p *Policy) HasTokens() bool {
+	for _, u := range p.Users {
+		if u.TokenSHA256 != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPolicy reads a policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parsePolicy(raw, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParsePolicy decodes a policy; unknown fields are rejected.
+func ParsePolicy(raw []byte) (*Policy, error) {
+	return parsePolicy(raw, "")
+}
+
+// parsePolicy decodes a policy and resolves ${ENV:NAME} and ${FILE:path}
+// references, taking relative FILE paths from baseDir.
+func parsePolicy(raw []byte, baseDir string) (*Policy, error) {
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("auth policy: %w", err)
+	}
+	if err := secrets.ExpandAll(&p, baseDir); err != nil {
+		return nil, fmt.Errorf("auth policy: %w", err)
+	}
+	if p.SchemaVersion != "" && p.SchemaVersion != "v1" {
+		return nil, fmt.Errorf("auth policy schema_version %q not supported (want v1)", p.SchemaVersion)
+	}
+	for i, u := range p.Users {
+		if u.Name == "" {
+			return nil, fmt.Errorf("auth policy: user %d has no name", i)
+		}
+		if u.Role == RoleNone {
+			return nil, fmt.Errorf("auth policy: user %s has no role", u.Name)
+		}
+		if u.TokenSHA256 != "" {
+			if b, err := hex.DecodeString(u.TokenSHA256); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("auth policy: user %s: token_sha256 must be 64 hex characters", u.Name)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// errBadToken is returned for a bearer token no user holds.
+var errBadToken = errors.New("invalid bearer token")
+
+// tokenUser finds the user holding token, comparing digests in constant
+// time.
+func (p *Policy) tokenUser(token string) (User, error) {
+	sum := sha256.Sum256([]byte(token))
+	for _, u := range p.Users {
+		if u.TokenSHA256 == "" {
+			continue
+		}
+		want, _ := hex.DecodeString(u.TokenSHA256)
+		if subtle.ConstantTimeCompare(sum[:], want) == 1 {
+			return u, nil
+		}
+	}
+	return User{}, errBadToken
+}
+
+// certRole returns the role bound to any of a certificate's names, or
+// Authenticated when none is bound.
+func (p *Policy) certRole(names []string) (string, Role) {
+	for _, u := range p.Users {
+		if u.TokenSHA256 != "" {
+			continue
+		}
+		for _, n := range names {
+			if n == u.Name {
+				return u.Name, u.Role
+			}
+		}
+	}
+	if len(names) > 0 {
+		return names[0], p.Authenticated
+	}
+	return "", RoleNone
+}
@@ -1,9 +1,19 @@
 package config
 
 import (
+	"quanta/internal/spec"
 	kcfg "quanta/source/kafka"
 )
 
 func LoadKafkaConfig(path string) (kcfg.Config, error) {
 	return kcfg.LoadConfig(path)
 }
+
+// LoadSourceConfig returns the Kafka config of a parsed spec: a v1 spec's
+// file at confPath, or a v2 spec's inline source config.
+func LoadSourceConfig(cfg spec.File, confPath string) (kcfg.Config, error) {
+	if cfg.Source.ConfigFile != "" {
+		return kcfg.LoadConfig(confPath)
+	}
+	return kcfg.ConfigFromMap(cfg.Source.Config)
+}
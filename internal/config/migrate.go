@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"quanta/internal/spec"
+)
+
+// MigrateV1 converts a v1 pipeline spec to v2 YAML: the source config file
+// is inlined under source.config, sinks become {name, type, config} entries
+// and debug settings move into the stdout sink's config. References such as
+// ${FILE:...} are carried over unresolved; comments are not.
+func MigrateV1(raw []byte, baseDir string) ([]byte, error) {
+	var head struct {
+		SchemaVersion string `yaml:"schema_version"`
+	}
+	if err := yaml.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+	if head.SchemaVersion != "" && head.SchemaVersion != SchemaV1 {
+		return nil, fmt.Errorf("pipeline schema_version %q is not v1", head.SchemaVersion)
+	}
+	var v1 spec.V1File
+	if err := decodeStrict(raw, &v1); err != nil {
+		return nil, err
+	}
+	cfg, err := v1.File()
+	if err != nil {
+		return nil, err
+	}
+	cfg.SchemaVersion = SchemaV2
+	if path := cfg.Source.ConfigFile; path != "" {
+		if strings.Contains(path, "${") {
+			return nil, fmt.Errorf("source.config %q is a reference; inline the config by hand", path)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		kraw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("source.config: %w", err)
+		}
+		var m map[string]any
+		if err := yaml.Unmarshal(kraw, &m); err != nil {
+			return nil, fmt.Errorf("source.config %s: %w", path, err)
+		}
+		if sv, _ := m["schema_version"].(string); sv != "" && sv != "v1" {
+			return nil, fmt.Errorf("source.config %s: kafka schema_version %q not supported (want v1)", path, sv)
+		}
+		delete(m, "schema_version")
+		if len(m) > 0 {
+			cfg.Source.Config = m
+		}
+		cfg.Source.ConfigFile = ""
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
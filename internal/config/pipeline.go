@@ -1,35 +1,108 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 
+	"quanta/internal/secrets"
 	"quanta/internal/spec"
 )
 
-const SupportedSchema = "v1"
+const (
+	SchemaV1 = "v1"
+	SchemaV2 = "v2"
+	// SupportedSchema is assumed for specs without a schema_version.
+	SupportedSchema = SchemaV1
+)
 
 func LoadPipelineSpec(path string) (spec.File, string, error) {
-	var cfg spec.File
 	raw, err := os.ReadFile(path)
 	if err != nil {
-		return cfg, "", err
+		return spec.File{}, "", err
 	}
-	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+	return ParsePipelineSpec(raw, filepath.Dir(path))
+}
+
+// ParsePipelineSpec decodes a v1 or v2 pipeline spec from bytes; relative
+// paths in it are resolved against baseDir. Unknown fields are rejected with
+// their line, so a typo such as retry_polcy fails instead of falling back to
+// defaults. v1 specs are converted to the v2 model; the returned path is a
+// v1 spec's resolved source config file, empty for v2 specs, which inline
+// it. ${ENV:NAME} and ${FILE:path} references in string values are resolved
+// after decoding; relative FILE paths are taken from baseDir.
+func ParsePipelineSpec(raw []byte, baseDir string) (spec.File, string, error) {
+	cfg, err := DecodePipelineSpec(raw)
+	if err != nil {
 		return cfg, "", err
 	}
-	if cfg.SchemaVersion == "" {
-		cfg.SchemaVersion = SupportedSchema
-	}
-	if cfg.SchemaVersion != SupportedSchema {
-		return cfg, "", fmt.Errorf("pipeline schema_version %q not supported (want %q)", cfg.SchemaVersion, SupportedSchema)
+	if err := secrets.ExpandAll(&cfg, baseDir); err != nil {
+		return cfg, "", err
 	}
-	confPath := cfg.Source.Config
+	confPath := cfg.Source.ConfigFile
 	if confPath != "" && !filepath.IsAbs(confPath) {
-		confPath = filepath.Join(filepath.Dir(path), confPath)
+		confPath = filepath.Join(baseDir, confPath)
 	}
 	return cfg, confPath, nil
 }
+
+// ParseDeployedPipelineSpec decodes a spec received over the control plane
+// rather than read from the engine host's disk. Such a spec must be
+// self-contained: a source config file is rejected, as are ${ENV:...}
+// references, and ${FILE:...} references may only read files inside
+// secretsDir, relative paths taken from it. With no secretsDir they are
+// rejected too.
+func ParseDeployedPipelineSpec(raw []byte, secretsDir string) (spec.File, error) {
+	cfg, err := DecodePipelineSpec(raw)
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.Source.ConfigFile != "" {
+		return cfg, fmt.Errorf("source config file %q cannot be read on the engine; deploy a v2 spec with the source config inline", cfg.Source.ConfigFile)
+	}
+	if err := secrets.ExpandAllIn(&cfg, secrets.Scope{BaseDir: secretsDir, Confined: true}); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// DecodePipelineSpec strictly decodes raw as the schema it declares, without
+// resolving references or paths.
+func DecodePipelineSpec(raw []byte) (spec.File, error) {
+	var head struct {
+		SchemaVersion string `yaml:"schema_version"`
+	}
+	if err := yaml.Unmarshal(raw, &head); err != nil {
+		return spec.File{}, err
+	}
+	switch head.SchemaVersion {
+	case "", SchemaV1:
+		var v1 spec.V1File
+		if err := decodeStrict(raw, &v1); err != nil {
+			return spec.File{}, err
+		}
+		v1.SchemaVersion = SchemaV1
+		return v1.File()
+	case SchemaV2:
+		var cfg spec.File
+		if err := decodeStrict(raw, &cfg); err != nil {
+			return cfg, err
+		}
+		return cfg, nil
+	}
+	return spec.File{}, fmt.Errorf("pipeline schema_version %q not supported (want %q or %q)", head.SchemaVersion, SchemaV1, SchemaV2)
+}
+
+func decodeStrict(raw []byte, into any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(into); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"quanta/internal/spec"
 )
 
 func TestLoadPipelineSpec_ResolvesRelativeSourceConfigAndSchema(t *testing.T) {
@@ -51,3 +55,154 @@ sinks: [stdout]
 		t.Fatal("expected error for invalid schema_version")
 	}
 }
+
+func TestParsePipelineSpec_RejectsUnknownFieldsWithLine(t *testing.T) {
+	_, _, err := ParsePipelineSpec([]byte(`schema_version: v1
+source: { kind: kafka, driver: sarama, config: cf.yml }
+transformers:
+  - name: enrich
+    type: grpc
+    retry_polcy: { attempts: 3 }
+sinks: [stdout]
+`), ".")
+	if err == nil || !strings.Contains(err.Error(), "line 6") || !strings.Contains(err.Error(), "retry_polcy") {
+		t.Fatalf("want an error naming retry_polcy on line 6, got %v", err)
+	}
+}
+
+func TestLoadPipelineSpec_ResolvesEnvAndFileReferences(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("QUANTA_TEST_ENRICH_ADDR", "enrich:50051")
+	t.Setenv("QUANTA_TEST_SOURCE_CONFIG", "kafka_source.yml")
+	pipe := []byte(`source:
+  kind: kafka
+  driver: sarama
+  config: ${ENV:QUANTA_TEST_SOURCE_CONFIG}
+transformers:
+  - { name: enrich, type: grpc, address: "${ENV:QUANTA_TEST_ENRICH_ADDR}", config: { key: "${FILE:secrets/api_key}" } }
+sinks: [stdout]
+`)
+	files := map[string]string{
+		"pipeline.yml":      string(pipe),
+		"kafka_source.yml":  "sasl_user: quanta\nsasl_pass: ${FILE:secrets/sasl_pass}\n",
+		"secrets/sasl_pass": "hunter2-from-secret\n",
+		"secrets/api_key":   "api-key-from-secret\n",
+	}
+	for name, body := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, confPath, err := LoadPipelineSpec(filepath.Join(dir, "pipeline.yml"))
+	if err != nil {
+		t.Fatalf("LoadPipelineSpec: %v", err)
+	}
+	if got := cfg.Transformers[0]; got.Address != "enrich:50051" || got.Config["key"] != "api-key-from-secret" {
+		t.Fatalf("transformer not resolved: %+v", got)
+	}
+	kc, err := LoadKafkaConfig(confPath)
+	if err != nil {
+		t.Fatalf("LoadKafkaConfig: %v", err)
+	}
+	if kc.SASLPass != "hunter2-from-secret" {
+		t.Fatalf("sasl_pass = %q", kc.SASLPass)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "secrets/api_key")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadPipelineSpec(filepath.Join(dir, "pipeline.yml")); err == nil || !strings.Contains(err.Error(), "${FILE:secrets/api_key}") {
+		t.Fatalf("want a missing secret error, got %v", err)
+	}
+}
+
+func TestParsePipelineSpec_V2InlinesSourceAndTypedSinks(t *testing.T) {
+	cfg, confPath, err := ParsePipelineSpec([]byte(`schema_version: v2
+name: orders
+source:
+  kind: kafka
+  driver: sarama
+  config:
+    brokers: [localhost:9092]
+    topics: [orders]
+    backpressure: { capacity: 10, check_interval: 50ms }
+sinks:
+  - { name: console, type: stdout, config: { ack_batch_size: 5 } }
+`), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confPath != "" || len(cfg.Sinks) != 1 || cfg.Sinks[0].Name != "console" || cfg.Sinks[0].Config["ack_batch_size"] != 5 {
+		t.Fatalf("unexpected spec: %+v (config path %q)", cfg, confPath)
+	}
+	kc, err := LoadSourceConfig(cfg, confPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kc.Topics[0] != "orders" || kc.BackPressure.Capacity != 10 || kc.BackPressure.CheckInt.Milliseconds() != 50 || kc.StartFrom != "newest" {
+		t.Fatalf("inline source config not applied: %+v", kc)
+	}
+
+	cfg.Source.Config["group"] = "typo"
+	if _, err := LoadSourceConfig(cfg, ""); err == nil || !strings.Contains(err.Error(), "group") {
+		t.Fatalf("want unknown key error, got %v", err)
+	}
+	if _, _, err := ParsePipelineSpec([]byte("schema_version: v2\nsinks: [stdout]\n"), "."); err == nil {
+		t.Fatal("v2 sinks must be objects")
+	}
+	if _, _, err := ParsePipelineSpec([]byte("schema_version: v3\n"), "."); err == nil || !strings.Contains(err.Error(), `"v3" not supported`) {
+		t.Fatalf("want unsupported schema error, got %v", err)
+	}
+}
+
+func TestMigrateV1_ProducesEquivalentV2(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "kafka.yml"), []byte("schema_version: v1\nbrokers: [b:9092]\nsasl_pass: ${FILE:/run/secrets/pass}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v1 := []byte(`name: p
+source: { kind: kafka, driver: sarama, config: kafka.yml }
+graph:
+  nodes:
+    - { name: a, type: grpc, address: "localhost:1" }
+    - { name: out, type: sink, sink: stdout }
+    - { name: stdout, type: sink }
+  edges:
+    - { from: source, to: a }
+    - { from: a, to: out }
+    - { from: a, to: stdout }
+debug: { print_counter: true, per_frame_delay_ms: 3 }
+`)
+	before, _, err := ParsePipelineSpec(v1, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := MigrateV1(v1, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "sasl_pass: ${FILE:/run/secrets/pass}") {
+		t.Fatalf("references must be carried over unresolved:\n%s", out)
+	}
+	var after spec.File
+	if err := decodeStrict(out, &after); err != nil {
+		t.Fatalf("migrated spec does not decode as v2: %v\n%s", err, out)
+	}
+	if after.SchemaVersion != SchemaV2 || after.Source.Config["brokers"].([]any)[0] != "b:9092" {
+		t.Fatalf("source not inlined:\n%s", out)
+	}
+	if !reflect.DeepEqual(before.Graph, after.Graph) || !reflect.DeepEqual(before.Sinks, after.Sinks) {
+		t.Fatalf("migrated graph or sinks differ:\nv1: %+v %+v\nv2: %+v %+v", before.Graph, before.Sinks, after.Graph, after.Sinks)
+	}
+	if s := after.Sinks; len(s) != 1 || s[0].Config["delay_ms"] != 3 || s[0].Config["print_counter"] != true {
+		t.Fatalf("debug not moved into the stdout sink: %+v", s)
+	}
+	if _, err := MigrateV1(out, dir); err == nil {
+		t.Fatal("migrating a v2 spec must fail")
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"quanta/internal/spec"
+	skafka "quanta/sink/kafka"
+	"quanta/sink/stdout"
+	kcfg "quanta/source/kafka"
+)
+
+// TestPipelineSchema_MatchesSpecTypes keeps the published JSON Schema in
+// step with the structs v2 specs decode into.
+func TestPipelineSchema_MatchesSpecTypes(t *testing.T) {
+	raw, err := os.ReadFile("../../schemas/pipeline.v2.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatal(err)
+	}
+	source := schema.Defs["source"].Properties
+	kafkaKeys := map[string]bool{}
+	for k, v := range schema.Defs["kafkaSource"].Properties {
+		var nested struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		}
+		if err := json.Unmarshal(v, &nested); err != nil {
+			t.Fatal(err)
+		}
+		if len(nested.Properties) == 0 {
+			kafkaKeys[k] = true
+		}
+		for n := range nested.Properties {
+			kafkaKeys[k+"."+n] = true
+		}
+	}
+
+	for _, c := range []struct {
+		name   string
+		schema map[string]bool
+		want   map[string]bool
+	}{
+		{"pipeline", keys(schema.Properties), fields(reflect.TypeOf(spec.File{}), "yaml")},
+		{"source", keys(source), fields(reflect.TypeOf(spec.SourceSpec{}), "yaml")},
+		{"source.restart", keys(properties(t, source["restart"])), fields(reflect.TypeOf(spec.RestartSpec{}), "yaml")},
+		{"source.config", kafkaKeys, kafkaConfigKeys(reflect.TypeOf(kcfg.Config{}), "")},
+		{"transformer", keys(schema.Defs["transformer"].Properties), fields(reflect.TypeOf(spec.TransformerSpec{}), "yaml")},
+		{"route", keys(schema.Defs["route"].Properties), fields(reflect.TypeOf(spec.RouteSpec{}), "yaml")},
+		{"predicate", keys(schema.Defs["predicate"].Properties), fields(reflect.TypeOf(spec.Predicate{}), "yaml")},
+		{"graph", keys(schema.Defs["graph"].Properties), fields(reflect.TypeOf(spec.GraphSpec{}), "yaml")},
+		{"node", keys(schema.Defs["node"].Properties), fields(reflect.TypeOf(spec.NodeSpec{}), "yaml")},
+		{"sink", keys(schema.Defs["sink"].Properties), fields(reflect.TypeOf(spec.SinkSpec{}), "yaml")},
+		{"stdout sink", keys(schema.Defs["stdoutSink"].Properties), fields(reflect.TypeOf(stdout.Config{}), "yaml")},
+		{"kafka sink", keys(schema.Defs["kafkaSink"].Properties), fields(reflect.TypeOf(skafka.Config{}), "yaml")},
+	} {
+		if !reflect.DeepEqual(c.schema, c.want) {
+			t.Errorf("%s: schema has %v, spec has %v", c.name, sorted(c.schema), sorted(c.want))
+		}
+	}
+}
+
+func properties(t *testing.T, raw json.RawMessage) map[string]json.RawMessage {
+	var s struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s.Properties
+}
+
+func keys(m map[string]json.RawMessage) map[string]bool {
+	out := map[string]bool{}
+	for k := range m {
+		out[k] = true
+	}
+	return out
+}
+
+// fields lists the keys a struct decodes from, following inline fields and
+// skipping "-".
+func fields(t reflect.Type, tag string) map[string]bool {
+	out := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get(tag), ",")
+		switch {
+		case strings.Contains(opts, "inline"):
+			for k := range fields(f.Type, tag) {
+				out[k] = true
+			}
+		case name != "" && name != "-":
+			out[name] = true
+		}
+	}
+	return out
+}
+
+func kafkaConfigKeys(t reflect.Type, prefix string) map[string]bool {
+	out := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := prefix + f.Tag.Get("koanf")
+		if f.Type.Kind() == reflect.Struct {
+			for k := range kafkaConfigKeys(f.Type, key+".") {
+				out[k] = true
+			}
+			continue
+		}
+		out[key] = true
+	}
+	return out
+}
+
+func sorted(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
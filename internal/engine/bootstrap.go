@@ -2,34 +2,195 @@ package engine
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"quanta/internal/pipeline"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"quanta/internal/auth"
+	"quanta/internal/logging"
+	"quanta/internal/registry"
 	"quanta/internal/telemetry"
 	"quanta/internal/transport"
 )
 
-func Bootstrap(ctx context.Context, cfg Config) (*Engine, error) {
+func Bootstrap(ctx context.Context, cfg Config) (_ *Engine, err error) {
+	shutdownTracing, err := telemetry.SetupTracing(ctx, cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	// Whatever has been started is torn down again, last first, if
+	// Bootstrap fails part way.
+	var undo []func()
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}()
+	undo = append(undo, func() {
+		tctx, tcancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer tcancel()
+		_ = shutdownTracing(tctx)
+	})
 
-	srv, err := transport.StartServer(cfg.GRPCPort)
+	// Pipelines outlive ctx: Engine.Run drains them once it is cancelled.
+	mgr := NewManager(context.WithoutCancel(ctx))
+	undo = append(undo, func() { _ = mgr.Close() })
+	mgr.node = cfg.NodeID
+	mgr.secretsDir = cfg.SecretsDir
+	if cfg.DrainTimeout > 0 {
+		mgr.drainTimeout = cfg.DrainTimeout
+	}
+	if cfg.RegistryDir != "" {
+		if mgr.store, err = registry.Open(cfg.RegistryDir); err != nil {
+			return nil, err
+		}
+	}
+	var tlsCfg *tls.Config
+	if cfg.TLS.CertFile != "" {
+		if tlsCfg, err = transport.ServerTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile); err != nil {
+			return nil, err
+		}
+	}
+	authz, audit, auditFile, err := setupAuth(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if auditFile != nil {
+		undo = append(undo, func() { _ = auditFile.Close() })
+	}
+	srv, err := transport.StartServer(cfg.GRPCAddr, tlsCfg, NewControlServer(mgr, audit), NewHealthServer(mgr), authz.ServerOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("transport: %w", err)
 	}
+	undo = append(undo, srv.Stop)
 
-	var runner *pipeline.Runner
-	if cfg.PipelineYml != "" {
-		runner, err = pipeline.Compile(cfg.PipelineYml)
-		if err != nil {
+	// Registry pipelines come back before the files are loaded, and both
+	// before Run serves the control plane.
+	if n, err := mgr.Restore(); err != nil {
+		logging.L().Error("pipeline registry not restored", "err", err)
+	} else if n > 0 {
+		logging.L().Info("pipelines restored from registry", "count", n, "dir", cfg.RegistryDir)
+	}
+	files, err := pipelineFiles(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline: %w", err)
+	}
+	if err := startPipelines(mgr, files); err != nil {
+		if len(mgr.ids()) == 0 {
 			return nil, fmt.Errorf("pipeline: %w", err)
 		}
-		if err := runner.Start(ctx); err != nil {
-			return nil, err
+		logging.L().Error("some pipelines failed to start", "err", err)
+	}
+
+	if cfg.WatchPipelines {
+		wctx, stopWatch := context.WithCancel(ctx)
+		undo = append(undo, stopWatch)
+		if err := watchPipelines(wctx, mgr, files, cfg.PipelineDir); err != nil {
+			logging.L().Warn("pipeline hot reload disabled", "err", err)
 		}
 	}
 
-	telemetry.Expose(cfg.MetricsPort)
+	shutdownAdmin, err := telemetry.Expose(cfg.MetricsAddr, cfg.Admin, func(context.Context) error { return mgr.Live() }, mgr.Ready)
+	if err != nil {
+		return nil, fmt.Errorf("admin: %w", err)
+	}
+	undo = append(undo, func() {
+		actx, acancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer acancel()
+		_ = shutdownAdmin(actx)
+	})
 
 	return &Engine{
-		transport: srv,
-		runner:    runner,
+		transport:    srv,
+		manager:      mgr,
+		drainTimeout: mgr.drainTimeout,
+		tracing:      shutdownTracing,
+		admin:        shutdownAdmin,
+		auditFile:    auditFile,
 	}, nil
 }
+
+// setupAuth loads the control plane policy and opens the audit log. The
+// returned file is nil when auditing to stderr.
+func setupAuth(cfg Config) (*auth.Authorizer, *auth.Auditor, *os.File, error) {
+	var authz *auth.Authorizer
+	switch {
+	case cfg.Auth.PolicyFile != "":
+		p, err := auth.LoadPolicy(cfg.Auth.PolicyFile)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if p.HasTokens() && cfg.TLS.CertFile == "" {
+			return nil, nil, nil, errors.New("auth: the policy has bearer tokens, which would be sent in plaintext; configure tls")
+		}
+		authz = auth.NewAuthorizer(p)
+	case cfg.Auth.Insecure:
+		logging.L().Warn("control plane has no auth policy and auth.insecure is set; every caller is an admin", "addr", cfg.GRPCAddr)
+		authz = auth.NewInsecureAuthorizer()
+	default:
+		logging.L().Warn("control plane has no auth policy; control calls are refused", "addr", cfg.GRPCAddr)
+		authz = auth.NewAuthorizer(nil)
+	}
+	if cfg.Auth.AuditFile == "" {
+		return authz, auth.NewAuditor(os.Stderr), nil, nil
+	}
+	f, err := os.OpenFile(cfg.Auth.AuditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("audit: %w", err)
+	}
+	return authz, auth.NewAuditor(f), f, nil
+}
+
+// pipelineFiles lists the spec files named by cfg, in a stable order and
+// without duplicates.
+func pipelineFiles(cfg Config) ([]string, error) {
+	var files []string
+	if cfg.PipelineYml != "" {
+		files = append(files, cfg.PipelineYml)
+	}
+	files = append(files, cfg.PipelineFiles...)
+	if cfg.PipelineDir != "" {
+		entries, err := os.ReadDir(cfg.PipelineDir)
+		if err != nil {
+			return nil, err
+		}
+		var found []string
+		for _, e := range entries {
+			ext := strings.ToLower(filepath.Ext(e.Name()))
+			if !e.IsDir() && (ext == ".yml" || ext == ".yaml") {
+				found = append(found, filepath.Join(cfg.PipelineDir, e.Name()))
+			}
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+
+	seen := map[string]bool{}
+	out := files[:0]
+	for _, f := range files {
+		if abs, err := filepath.Abs(f); err == nil && !seen[abs] {
+			seen[abs] = true
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// startPipelines deploys every file; one bad spec does not stop the rest.
+func startPipelines(mgr *Manager, files []string) error {
+	var errs []error
+	for _, f := range files {
+		if _, err := mgr.DeployFile(f); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
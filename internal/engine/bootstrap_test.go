@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"quanta/internal/telemetry"
+	"quanta/source/kafka"
+)
+
+func TestStartPipelines_DirectoryIsolatesBadSpecs(t *testing.T) {
+	kafka.Register("fake", func() kafka.Adapter { return &fakeSource{} })
+	dir := t.TempDir()
+	files := map[string]string{
+		"orders.yml":  strings.Replace(testSpec, "name: orders", "name: orders-v2", 1),
+		"clicks.yaml": strings.Replace(testSpec, "name: orders\n", "", 1),
+		"broken.yml":  "source: {kind: sqs}\n",
+		"notes.txt":   "ignored",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	paths, err := pipelineFiles(Config{PipelineDir: dir, PipelineFiles: []string{filepath.Join(dir, "orders.yml")}})
+	if err != nil {
+		t.Fatalf("pipelineFiles: %v", err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("want 3 spec files without duplicates, got %v", paths)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgr := NewManager(ctx)
+	defer mgr.Close()
+
+	err = startPipelines(mgr, paths)
+	if err == nil || !strings.Contains(err.Error(), "broken.yml") {
+		t.Fatalf("want error naming the broken spec, got %v", err)
+	}
+	if got := strings.Join(mgr.ids(), ","); got != "clicks,orders-v2" {
+		t.Fatalf("unexpected running pipelines %q", got)
+	}
+}
+
+func TestSetupAuth_RefusesTokensWithoutTLS(t *testing.T) {
+	policy := filepath.Join(t.TempDir(), "policy.yml")
+	body := "users:\n  - { name: ci, role: admin, token_sha256: " + strings.Repeat("ab", 32) + " }\n"
+	if err := os.WriteFile(policy, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := setupAuth(Config{Auth: AuthConfig{PolicyFile: policy}}); err == nil || !strings.Contains(err.Error(), "tls") {
+		t.Fatalf("want tokens without tls refused, got %v", err)
+	}
+}
+
+func TestBootstrap_FailureTearsDownWhatStarted(t *testing.T) {
+	src := &fakeSource{}
+	kafka.Register("fake-bootstrap", func() kafka.Adapter { return src })
+	spec := filepath.Join(t.TempDir(), "orders.yml")
+	if err := os.WriteFile(spec, []byte(strings.Replace(testSpec, "driver: fake", "driver: fake-bootstrap", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	grpcAddr := freeAddr(t)
+
+	_, err = Bootstrap(context.Background(), Config{
+		GRPCAddr:    grpcAddr,
+		MetricsAddr: taken.Addr().String(),
+		Admin:       telemetry.AdminEndpoints{Metrics: true},
+		PipelineYml: spec,
+	})
+	if err == nil || !strings.Contains(err.Error(), "admin") {
+		t.Fatalf("want the admin listener to fail, got %v", err)
+	}
+	if !src.closed.Load() {
+		t.Fatal("pipelines started before the failure must be closed")
+	}
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		t.Fatalf("control server still holds its port: %v", err)
+	}
+	_ = lis.Close()
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+func TestEngine_RunReleasesTheAdminListener(t *testing.T) {
+	adminAddr := freeAddr(t)
+	cfg := Config{
+		GRPCAddr:    freeAddr(t),
+		MetricsAddr: adminAddr,
+		Admin:       telemetry.AdminEndpoints{Metrics: true, Probes: true},
+	}
+	// An embedded engine must be able to start again on the same ports.
+	for i := 0; i < 2; i++ {
+		e, err := Bootstrap(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("bootstrap %d: %v", i, err)
+		}
+		resp, err := http.Get("http://" + adminAddr + "/healthz")
+		if err != nil {
+			t.Fatalf("healthz: %v", err)
+		}
+		_ = resp.Body.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := e.Run(ctx); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		if _, err := http.Get("http://" + adminAddr + "/healthz"); err == nil {
+			t.Fatal("the admin listener must be closed once the engine stops")
+		}
+	}
+}
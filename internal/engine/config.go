@@ -1,7 +1,348 @@
 package engine
 
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"quanta/internal/logging"
+	"quanta/internal/secrets"
+	"quanta/internal/telemetry"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+)
+
 type Config struct {
-	GRPCPort    int
-	MetricsPort int
+	// NodeID identifies this engine in logs, traces and Ping replies.
+	NodeID string
+	// GRPCAddr serves the control plane and gRPC health; MetricsAddr serves
+	// the admin HTTP endpoints selected by Admin.
+	GRPCAddr    string
+	MetricsAddr string
+	Admin       telemetry.AdminEndpoints
+	// TLS secures the control plane; the zero value serves plaintext.
+	TLS TLSConfig
+	// Auth authorizes control plane callers and audits their changes.
+	Auth AuthConfig
+	Log  logging.Options
+
 	PipelineYml string
+	// PipelineFiles and PipelineDir add more pipelines; every *.yml and
+	// *.yaml file in PipelineDir is loaded.
+	PipelineFiles []string
+	PipelineDir   string
+	// WatchPipelines reloads pipeline files when they change on disk.
+	WatchPipelines bool
+	// RegistryDir keeps pipelines deployed over the control plane, with
+	// their version history, across restarts; empty keeps them in memory.
+	RegistryDir string
+	// SecretsDir is the only directory ${FILE:...} references in specs
+	// deployed over the control plane may read; empty rejects them.
+	// ${ENV:...} references are always rejected in deployed specs.
+	SecretsDir string
+	// DrainTimeout bounds the graceful shutdown of each pipeline; zero
+	// means DefaultDrainTimeout.
+	DrainTimeout time.Duration
+	// Tracing selects the OpenTelemetry exporter for frame traces.
+	Tracing telemetry.TracingConfig
+}
+
+// TLSConfig points at the control plane's certificate; ClientCAFile turns on
+// mutual TLS.
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+// AuthConfig points at the control plane role policy (see auth.Policy) and
+// the audit log. Without a policy control calls are refused unless Insecure
+// makes every caller an admin; without an audit file records go to stderr.
+type AuthConfig struct {
+	PolicyFile string
+	AuditFile  string
+	Insecure   bool
+}
+
+// DefaultConfigFile is read when no config path is given; unlike an explicit
+// path it may be missing.
+const DefaultConfigFile = "engine.yml"
+
+// engineFile mirrors engine.yml.
+type engineFile struct {
+	SchemaVersion string `koanf:"schema_version"`
+	Node          struct {
+		ID string `koanf:"id"`
+	} `koanf:"node"`
+	Listen struct {
+		GRPC    string `koanf:"grpc"`
+		Metrics string `koanf:"metrics"`
+	} `koanf:"listen"`
+	TLS struct {
+		CertFile     string `koanf:"cert_file"`
+		KeyFile      string `koanf:"key_file"`
+		ClientCAFile string `koanf:"client_ca_file"`
+	} `koanf:"tls"`
+	Auth struct {
+		PolicyFile string `koanf:"policy_file"`
+		AuditFile  string `koanf:"audit_file"`
+		Insecure   bool   `koanf:"insecure"`
+	} `koanf:"auth"`
+	Log struct {
+		Level  string `koanf:"level"`
+		Format string `koanf:"format"`
+	} `koanf:"log"`
+	Pipelines struct {
+		Files []string `koanf:"files"`
+		Dir   string   `koanf:"dir"`
+		Watch bool     `koanf:"watch"`
+	} `koanf:"pipelines"`
+	Registry struct {
+		Dir string `koanf:"dir"`
+	} `koanf:"registry"`
+	Secrets struct {
+		Dir string `koanf:"dir"`
+	} `koanf:"secrets"`
+	DrainTimeout time.Duration `koanf:"drain_timeout"`
+	Admin        struct {
+		Metrics bool `koanf:"metrics"`
+		Probes  bool `koanf:"probes"`
+		Pprof   bool `koanf:"pprof"`
+	} `koanf:"admin"`
+	Tracing struct {
+		Exporter    string  `koanf:"exporter"`
+		Endpoint    string  `koanf:"endpoint"`
+		Insecure    bool    `koanf:"insecure"`
+		SampleRatio float64 `koanf:"sample_ratio"`
+		ServiceName string  `koanf:"service_name"`
+	} `koanf:"tracing"`
+}
+
+var configDefaults = map[string]any{
+	"listen.grpc":     ":7070",
+	"listen.metrics":  ":9100",
+	"log.level":       "info",
+	"log.format":      "text",
+	"pipelines.watch": true,
+	"drain_timeout":   DefaultDrainTimeout.String(),
+	"admin.metrics":   true,
+	"admin.probes":    true,
+}
+
+// flagKeys maps each engine flag to the config key it overrides.
+var flagKeys = map[string]string{
+	"node-id":        "node.id",
+	"grpc-addr":      "listen.grpc",
+	"metrics-addr":   "listen.metrics",
+	"tls-cert":       "tls.cert_file",
+	"tls-key":        "tls.key_file",
+	"tls-client-ca":  "tls.client_ca_file",
+	"auth-policy":    "auth.policy_file",
+	"audit-file":     "auth.audit_file",
+	"auth-insecure":  "auth.insecure",
+	"log-level":      "log.level",
+	"log-format":     "log.format",
+	"pipeline":       "pipelines.files",
+	"pipeline-dir":   "pipelines.dir",
+	"watch":          "pipelines.watch",
+	"registry-dir":   "registry.dir",
+	"secrets-dir":    "secrets.dir",
+	"drain-timeout":  "drain_timeout",
+	"admin-metrics":  "admin.metrics",
+	"admin-probes":   "admin.probes",
+	"admin-pprof":    "admin.pprof",
+	"trace-exporter": "tracing.exporter",
+	"trace-endpoint": "tracing.endpoint",
+}
+
+// legacyEnv maps the variables the engine read before engine.yml existed
+// to their config keys.
+var legacyEnv = map[string]string{
+	"QUANTA_PIPELINE_YML":         "pipelines.files",
+	"QUANTA_PIPELINE_DIR":         "pipelines.dir",
+	"QUANTA_PIPELINE_WATCH":       "pipelines.watch",
+	"QUANTA_LOG_LEVEL":            "log.level",
+	"QUANTA_TRACING_EXPORTER":     "tracing.exporter",
+	"QUANTA_TRACING_ENDPOINT":     "tracing.endpoint",
+	"QUANTA_TRACING_INSECURE":     "tracing.insecure",
+	"QUANTA_TRACING_SAMPLE_RATIO": "tracing.sample_ratio",
+}
+
+// listKeys hold comma-separated lists when set from env or flags.
+var listKeys = map[string]bool{"pipelines.files": true}
+
+// RegisterFlags defines the engine flags on fs and returns the -config
+// value. Flags only override the config when given explicitly.
+func RegisterFlags(fs *flag.FlagSet) *string {
+	path := fs.String("config", "", "engine config file (env QUANTA_ENGINE_CONFIG, default "+DefaultConfigFile+")")
+	fs.String("node-id", "", "node identity (default hostname)")
+	fs.String("grpc-addr", ":7070", "control plane listen address")
+	fs.String("metrics-addr", ":9100", "admin HTTP listen address")
+	fs.String("tls-cert", "", "control plane TLS certificate")
+	fs.String("tls-key", "", "control plane TLS key")
+	fs.String("tls-client-ca", "", "require client certificates signed by this CA")
+	fs.String("auth-policy", "", "control plane role policy file")
+	fs.String("audit-file", "", "append control plane audit records here (default stderr)")
+	fs.Bool("auth-insecure", false, "without an auth policy, serve every control plane caller as an admin")
+	fs.String("log-level", "info", "debug, info, warn or error")
+	fs.String("log-format", "text", "text or json")
+	fs.String("pipeline", "", "comma-separated pipeline spec files")
+	fs.String("pipeline-dir", "", "run every *.yml/*.yaml in this directory")
+	fs.Bool("watch", true, "reload pipeline files when they change")
+	fs.String("registry-dir", "", "persist deployed pipelines and their versions here")
+	fs.String("secrets-dir", "", "directory ${FILE:...} references in deployed specs may read")
+	fs.Duration("drain-timeout", DefaultDrainTimeout, "graceful shutdown budget per pipeline")
+	fs.Bool("admin-metrics", true, "serve /metrics")
+	fs.Bool("admin-probes", true, "serve /healthz and /readyz")
+	fs.Bool("admin-pprof", false, "serve /debug/pprof/")
+	fs.String("trace-exporter", "", "otlp, stdout or empty")
+	fs.String("trace-endpoint", "", "OTLP collector address")
+	return path
+}
+
+// LoadConfig builds the engine config from, in increasing precedence:
+// built-in defaults, the YAML file at path (DefaultConfigFile when empty),
+// the legacy QUANTA_* variables, QUANTA_ENGINE__* variables (QUANTA_ENGINE__
+// LISTEN__GRPC sets listen.grpc) and the flags explicitly set on flags, which
+// may be nil. ${ENV:NAME} and ${FILE:path} references in any of them are
+// resolved once everything is merged.
+func LoadConfig(path string, flags *flag.FlagSet) (Config, error) {
+	k := koanf.New(".")
+	for key, v := range configDefaults {
+		_ = k.Set(key, v)
+	}
+
+	if path == "" {
+		path = os.Getenv("QUANTA_ENGINE_CONFIG")
+	}
+	optional := path == ""
+	if optional {
+		path = DefaultConfigFile
+	}
+	if err := k.Load(file.Provider(path), yaml.Parser()); err != nil &&
+		!(optional && errors.Is(err, fs.ErrNotExist)) {
+		return Config{}, fmt.Errorf("engine config %s: %w", path, err)
+	}
+	if sv := k.String("schema_version"); sv != "" && sv != "v1" {
+		return Config{}, fmt.Errorf("engine schema_version %q not supported (want v1)", sv)
+	}
+
+	for name, key := range legacyEnv {
+		if v := os.Getenv(name); v != "" {
+			_ = k.Set(key, configValue(key, v))
+		}
+	}
+	if b, err := strconv.ParseBool(os.Getenv("QUANTA_LOG_JSON")); err == nil && b {
+		_ = k.Set("log.format", "json")
+	}
+	const prefix = "QUANTA_ENGINE__"
+	err := k.Load(env.ProviderWithValue(prefix, ".", func(name, v string) (string, any) {
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(name, prefix)), "__", ".")
+		return key, configValue(key, v)
+	}), nil)
+	if err != nil {
+		return Config{}, err
+	}
+	if flags != nil {
+		flags.Visit(func(f *flag.Flag) {
+			if key, ok := flagKeys[f.Name]; ok {
+				_ = k.Set(key, configValue(key, f.Value.String()))
+			}
+		})
+	}
+
+	if err := secrets.ExpandKoanf(k, filepath.Dir(path)); err != nil {
+		return Config{}, fmt.Errorf("engine config: %w", err)
+	}
+
+	var ef engineFile
+	if err := k.Unmarshal("", &ef); err != nil {
+		return Config{}, fmt.Errorf("engine config: %w", err)
+	}
+	return ef.config()
+}
+
+func configValue(key, v string) any {
+	if !listKeys[key] {
+		return v
+	}
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (ef engineFile) config() (Config, error) {
+	cfg := Config{
+		NodeID:      ef.Node.ID,
+		GRPCAddr:    ef.Listen.GRPC,
+		MetricsAddr: ef.Listen.Metrics,
+		Admin: telemetry.AdminEndpoints{
+			Metrics: ef.Admin.Metrics,
+			Probes:  ef.Admin.Probes,
+			Pprof:   ef.Admin.Pprof,
+		},
+		TLS: TLSConfig{
+			CertFile:     ef.TLS.CertFile,
+			KeyFile:      ef.TLS.KeyFile,
+			ClientCAFile: ef.TLS.ClientCAFile,
+		},
+		Auth: AuthConfig{
+			PolicyFile: ef.Auth.PolicyFile,
+			AuditFile:  ef.Auth.AuditFile,
+			Insecure:   ef.Auth.Insecure,
+		},
+		PipelineFiles:  ef.Pipelines.Files,
+		PipelineDir:    ef.Pipelines.Dir,
+		WatchPipelines: ef.Pipelines.Watch,
+		RegistryDir:    ef.Registry.Dir,
+		SecretsDir:     ef.Secrets.Dir,
+		DrainTimeout:   ef.DrainTimeout,
+		Tracing: telemetry.TracingConfig{
+			Exporter:    ef.Tracing.Exporter,
+			Endpoint:    ef.Tracing.Endpoint,
+			Insecure:    ef.Tracing.Insecure,
+			SampleRatio: ef.Tracing.SampleRatio,
+			ServiceName: ef.Tracing.ServiceName,
+		},
+	}
+	if cfg.NodeID == "" {
+		cfg.NodeID, _ = os.Hostname()
+	}
+	cfg.Tracing.InstanceID = cfg.NodeID
+
+	switch ef.Log.Format {
+	case "text", "":
+	case "json":
+		cfg.Log.JSON = true
+	default:
+		return Config{}, fmt.Errorf("engine config: log.format %q: want text or json", ef.Log.Format)
+	}
+	cfg.Log.Level, cfg.Log.Node = ef.Log.Level, cfg.NodeID
+
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return Config{}, errors.New("engine config: tls.cert_file and tls.key_file must be set together")
+	}
+	if cfg.TLS.ClientCAFile != "" && cfg.TLS.CertFile == "" {
+		return Config{}, errors.New("engine config: tls.client_ca_file requires tls.cert_file")
+	}
+	if cfg.Auth.Insecure && cfg.Auth.PolicyFile != "" {
+		return Config{}, errors.New("engine config: auth.insecure cannot be combined with auth.policy_file")
+	}
+	if len(cfg.PipelineFiles) == 0 && cfg.PipelineDir == "" {
+		cfg.PipelineFiles = []string{"pipeline.yml"}
+	}
+	return cfg, nil
 }
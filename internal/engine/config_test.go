@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg, err := LoadConfig("", nil)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.GRPCAddr != ":7070" || cfg.MetricsAddr != ":9100" || cfg.DrainTimeout != DefaultDrainTimeout {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+	if !cfg.WatchPipelines || !cfg.Admin.Metrics || !cfg.Admin.Probes || cfg.Admin.Pprof {
+		t.Fatalf("unexpected default switches: %+v", cfg)
+	}
+	if strings.Join(cfg.PipelineFiles, ",") != "pipeline.yml" || cfg.NodeID == "" || cfg.Log.Node != cfg.NodeID {
+		t.Fatalf("unexpected pipelines or node: %+v", cfg)
+	}
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "engine.yml")
+	yml := `schema_version: v1
+node: {id: file-node}
+listen: {grpc: ":1111", metrics: ":2222"}
+log: {level: debug, format: json}
+pipelines: {dir: /etc/quanta/pipelines, watch: false}
+drain_timeout: 5s
+admin: {pprof: true}
+tracing: {exporter: stdout}
+`
+	if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("QUANTA_PIPELINE_YML", "a.yml, b.yml")
+	t.Setenv("QUANTA_TRACING_EXPORTER", "otlp")
+	t.Setenv("QUANTA_ENGINE__LISTEN__METRICS", ":3333")
+	t.Setenv("QUANTA_ENGINE__TRACING__EXPORTER", "none")
+	t.Setenv("QUANTA_ENGINE__NODE__ID", "env-node")
+
+	fs := flag.NewFlagSet("engine", flag.ContinueOnError)
+	RegisterFlags(fs)
+	if err := fs.Parse([]string{"-node-id", "flag-node", "-admin-metrics=false", "-grpc-addr", "127.0.0.1:0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path, fs)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"node from flag", cfg.NodeID, "flag-node"},
+		{"grpc from flag", cfg.GRPCAddr, "127.0.0.1:0"},
+		{"metrics from engine env", cfg.MetricsAddr, ":3333"},
+		{"tracing engine env over legacy env", cfg.Tracing.Exporter, "none"},
+		{"files from legacy env", strings.Join(cfg.PipelineFiles, ","), "a.yml,b.yml"},
+		{"dir from file", cfg.PipelineDir, "/etc/quanta/pipelines"},
+		{"watch from file", cfg.WatchPipelines, false},
+		{"drain from file", cfg.DrainTimeout, 5 * time.Second},
+		{"log json from file", cfg.Log.JSON, true},
+		{"pprof from file", cfg.Admin.Pprof, true},
+		{"metrics off by flag", cfg.Admin.Metrics, false},
+		{"probes default", cfg.Admin.Probes, true},
+		{"instance id", cfg.Tracing.InstanceID, "flag-node"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadConfig(filepath.Join(dir, "missing.yml"), nil); err == nil {
+		t.Fatal("an explicit config file must exist")
+	}
+	for name, yml := range map[string]string{
+		"schema": "schema_version: v9\n",
+		"format": "log: {format: xml}\n",
+		"tls":    "tls: {cert_file: c.pem}\n",
+		"auth":   "auth: {insecure: true, policy_file: policy.yml}\n",
+	} {
+		path := filepath.Join(dir, name+".yml")
+		if err := os.WriteFile(path, []byte(yml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path, nil); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
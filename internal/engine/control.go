@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"context"
+	"errors"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/auth"
+	"quanta/internal/config"
+	"quanta/internal/pipeline"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// controlServer exposes the Manager over the Control gRPC service and
+// records every mutating call with audit.
+type controlServer struct {
+	pb.UnimplementedControlServer
+	m     *Manager
+	audit *auth.Auditor
+}
+
+// NewControlServer serves m; a nil audit records nothing.
+func NewControlServer(m *Manager, audit *auth.Auditor) pb.ControlServer {
+	return &controlServer{m: m, audit: audit}
+}
+
+func (s *controlServer) Ping(context.Context, *pb.PingRequest) (*pb.PingReply, error) {
+	return &pb.PingReply{Status: "ok", Node: s.m.node}, nil
+}
+
+func (s *controlServer) DeployPipeline(ctx context.Context, req *pb.DeployRequest) (_ *pb.DeployReply, err error) {
+	ev := auth.Event{Method: "DeployPipeline"}
+	defer func() { ev.Err = err; s.audit.Record(ctx, ev) }()
+	if req.GetYaml() == "" {
+		return nil, status.Error(codes.InvalidArgument, "yaml is required")
+	}
+	// The diff is against whatever already runs under the spec's name, so
+	// a rejected redeploy still shows what it would have changed.
+	var old []byte
+	if cfg, perr := config.DecodePipelineSpec([]byte(req.GetYaml())); perr == nil && cfg.Name != "" {
+		ev.Pipeline = cfg.Name
+		old, _ = s.m.Spec(cfg.Name)
+	}
+	ev.Diff = auth.SpecDiff(string(old), req.GetYaml())
+
+	// base_dir is ignored: a deployed spec must not reach into the engine
+	// host's files beyond the secrets directory.
+	id, v, err := s.m.Apply([]byte(req.GetYaml()), DeployOptions{
+		Replace: req.GetReplace(),
+		By:      auth.FromContext(ctx).Name,
+	})
+	if errors.Is(err, ErrPipelineExists) {
+		return nil, toStatus(err)
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ev.Pipeline = id
+	return &pb.DeployReply{Id: id, Version: uint32(v)}, nil
+}
+
+func (s *controlServer) RollbackPipeline(ctx context.Context, req *pb.RollbackRequest) (_ *pb.RollbackReply, err error) {
+	old, _ := s.m.Spec(req.GetId())
+	defer func() {
+		cur, _ := s.m.Spec(req.GetId())
+		s.audit.Record(ctx, auth.Event{Method: "RollbackPipeline", Pipeline: req.GetId(), Diff: auth.SpecDiff(string(old), string(cur)), Err: err})
+	}()
+	v, err := s.m.Rollback(req.GetId(), int(req.GetVersion()), auth.FromContext(ctx).Name)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.RollbackReply{Id: req.GetId(), Version: uint32(v)}, nil
+}
+
+func (s *controlServer) GetPipelineHistory(_ context.Context, req *pb.HistoryRequest) (*pb.PipelineHistory, error) {
+	rec, err := s.m.History(req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	out := &pb.PipelineHistory{Id: rec.ID, Current: uint32(rec.Current), Paused: rec.Paused}
+	for _, v := range rec.Versions {
+		out.Versions = append(out.Versions, &pb.PipelineVersion{
+			Version:      uint32(v.Version),
+			DeployedAtMs: v.DeployedAt.UnixMilli(),
+			DeployedBy:   v.DeployedBy,
+			RollbackOf:   uint32(v.RollbackOf),
+		})
+	}
+	return out, nil
+}
+
+func (s *controlServer) PausePipeline(ctx context.Context, req *pb.PauseRequest) (_ *pb.PauseReply, err error) {
+	defer func() { s.audit.Record(ctx, auth.Event{Method: "PausePipeline", Pipeline: req.GetId(), Err: err}) }()
+	if err := s.m.Pause(req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.PauseReply{Ok: true}, nil
+}
+
+func (s *controlServer) ResumePipeline(ctx context.Context, req *pb.ResumeRequest) (_ *pb.ResumeReply, err error) {
+	defer func() { s.audit.Record(ctx, auth.Event{Method: "ResumePipeline", Pipeline: req.GetId(), Err: err}) }()
+	if err := s.m.Resume(req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ResumeReply{Ok: true}, nil
+}
+
+func (s *controlServer) DeletePipeline(ctx context.Context, req *pb.DeleteRequest) (_ *pb.DeleteReply, err error) {
+	old, _ := s.m.Spec(req.GetId())
+	defer func() {
+		s.audit.Record(ctx, auth.Event{Method: "DeletePipeline", Pipeline: req.GetId(), Diff: auth.SpecDiff(string(old), ""), Err: err})
+	}()
+	if err := s.m.Delete(req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DeleteReply{Ok: true}, nil
+}
+
+func (s *controlServer) ListPipelines(ctx context.Context, _ *pb.ListPipelinesRequest) (*pb.ListPipelinesReply, error) {
+	return &pb.ListPipelinesReply{Pipelines: s.m.List(ctx)}, nil
+}
+
+func (s *controlServer) GetPipelineStatus(ctx context.Context, req *pb.PipelineStatusRequest) (*pb.PipelineStatus, error) {
+	st, err := s.m.Status(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return st, nil
+}
+
+// Tap streams copies of the frames passing a point of a pipeline until the
+// caller goes away or the pipeline stops. Each message reports how many
+// frames were dropped because the caller fell behind.
+func (s *controlServer) Tap(req *pb.TapRequest, stream pb.Control_TapServer) error {
+	if r := req.GetSampleRate(); r < 0 || r > 1 {
+		return status.Error(codes.InvalidArgument, "sample_rate must be between 0 and 1")
+	}
+	t, err := s.m.Tap(req.GetId(), req.GetPoint(), pipeline.TapOptions{
+		Key:        req.GetFilter().GetKey(),
+		Headers:    req.GetFilter().GetHeaders(),
+		SampleRate: req.GetSampleRate(),
+		Buffer:     int(req.GetBuffer()),
+	})
+	if errors.Is(err, ErrPipelineNotFound) {
+		return toStatus(err)
+	}
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer t.Close()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case f, ok := <-t.Frames():
+			if !ok {
+				return status.Errorf(codes.Unavailable, "pipeline %s stopped", req.GetId())
+			}
+			if err := stream.Send(&pb.TapFrame{Point: t.Point(), Frame: f, Dropped: t.Dropped()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, ErrPipelineNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrPipelineExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+}
@@ -0,0 +1,279 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/auth"
+	"quanta/internal/pipeline"
+	"quanta/internal/telemetry"
+	"quanta/source/kafka"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeSource struct {
+	paused   atomic.Bool
+	closed   atomic.Bool
+	inFlight atomic.Int64
+	notReady atomic.Bool
+}
+
+func (f *fakeSource) Configure(kafka.Config) error { return nil }
+func (f *fakeSource) Run(ctx context.Context, _ kafka.EmitFunc) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+func (f *fakeSource) Close() error { f.closed.Store(true); return nil }
+func (f *fakeSource) Pause()       { f.paused.Store(true) }
+func (f *fakeSource) Resume()      { f.paused.Store(false) }
+func (f *fakeSource) Ready() error {
+	if f.notReady.Load() {
+		return errors.New("no partitions assigned")
+	}
+	return nil
+}
+func (f *fakeSource) Stats() kafka.Stats {
+	return kafka.Stats{Lag: 7, InFlight: f.inFlight.Load()}
+}
+
+const testSpec = `schema_version: v1
+name: orders
+source:
+  kind: kafka
+  driver: fake
+sinks: [stdout]
+`
+
+func startControl(t *testing.T) (pb.ControlClient, *fakeSource) {
+	t.Helper()
+	src := &fakeSource{}
+	kafka.Register("fake", func() kafka.Adapter { return src })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr := NewManager(ctx)
+	t.Cleanup(func() { _ = mgr.Close(); cancel() })
+
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	pb.RegisterControlServer(g, NewControlServer(mgr, nil))
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+	return pb.NewControlClient(cc), src
+}
+
+func TestControl_PipelineLifecycle(t *testing.T) {
+	cli, src := startControl(t)
+	ctx := context.Background()
+
+	dep, err := cli.DeployPipeline(ctx, &pb.DeployRequest{Yaml: testSpec, BaseDir: t.TempDir()})
+	if err != nil || dep.Id != "orders" {
+		t.Fatalf("deploy: %+v %v", dep, err)
+	}
+	if _, err := cli.DeployPipeline(ctx, &pb.DeployRequest{Yaml: testSpec}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("want AlreadyExists on redeploy, got %v", err)
+	}
+	if _, err := cli.DeployPipeline(ctx, &pb.DeployRequest{Yaml: "source: {kind: sqs}"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("want InvalidArgument for a bad spec, got %v", err)
+	}
+
+	src.inFlight.Store(2)
+	if _, err := cli.PausePipeline(ctx, &pb.PauseRequest{Id: "orders"}); err != nil || !src.paused.Load() {
+		t.Fatalf("pause: %v (paused=%v)", err, src.paused.Load())
+	}
+	st, err := cli.GetPipelineStatus(ctx, &pb.PipelineStatusRequest{Id: "orders"})
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if st.State != StatePaused || st.Lag != 7 || st.InFlight != 2 || st.StartedAtMs == 0 {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if _, err := cli.ResumePipeline(ctx, &pb.ResumeRequest{Id: "orders"}); err != nil || src.paused.Load() {
+		t.Fatalf("resume: %v (paused=%v)", err, src.paused.Load())
+	}
+
+	src.inFlight.Store(0)
+
+	list, err := cli.ListPipelines(ctx, &pb.ListPipelinesRequest{})
+	if err != nil || len(list.Pipelines) != 1 || list.Pipelines[0].State != StateRunning {
+		t.Fatalf("list: %+v %v", list, err)
+	}
+
+	if _, err := cli.DeletePipeline(ctx, &pb.DeleteRequest{Id: "orders"}); err != nil || !src.closed.Load() {
+		t.Fatalf("delete: %v (closed=%v)", err, src.closed.Load())
+	}
+	if _, err := cli.GetPipelineStatus(ctx, &pb.PipelineStatusRequest{Id: "orders"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("want NotFound after delete, got %v", err)
+	}
+	if telemetry.PipelineState.DeleteLabelValues("orders", pipeline.StateStopped) {
+		t.Fatal("a deleted pipeline's series must be removed")
+	}
+}
+
+type dyingSource struct{ fakeSource }
+
+func (d *dyingSource) Run(context.Context, kafka.EmitFunc) error {
+	return kafka.Fatal(errors.New("broker gone"))
+}
+
+func TestManager_FatalWhenEveryPipelineFails(t *testing.T) {
+	kafka.Register("dying", func() kafka.Adapter { return &dyingSource{} })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(ctx)
+	defer m.Close()
+
+	spec := strings.Replace(testSpec, "driver: fake", "driver: dying", 1)
+	if _, err := m.Deploy([]byte(spec)); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	select {
+	case err := <-m.Fatal():
+		if !strings.Contains(err.Error(), "broker gone") {
+			t.Fatalf("unexpected fatal error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("dead consumer was not reported")
+	}
+	st, err := m.Status(ctx, "orders")
+	if err != nil || st.State != StateFailed || st.LastError == "" {
+		t.Fatalf("want failed status with last error, got %+v %v", st, err)
+	}
+}
+
+type tickingSource struct{ fakeSource }
+
+func (s *tickingSource) Run(ctx context.Context, emit kafka.EmitFunc) error {
+	tk := time.NewTicker(5 * time.Millisecond)
+	defer tk.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tk.C:
+			if err := emit(&pb.Frame{Key: []byte("k"), Value: []byte("v")}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func TestControl_TapStreamsFramesUntilPipelineStops(t *testing.T) {
+	cli, _ := startControl(t)
+	kafka.Register("ticking", func() kafka.Adapter { return &tickingSource{} })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	spec := strings.Replace(testSpec, "driver: fake", "driver: ticking", 1)
+	if _, err := cli.DeployPipeline(ctx, &pb.DeployRequest{Yaml: spec, BaseDir: t.TempDir()}); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+
+	recvErr := func(req *pb.TapRequest) error {
+		stream, err := cli.Tap(ctx, req)
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+	if err := recvErr(&pb.TapRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("want NotFound for an unknown pipeline, got %v", err)
+	}
+	if err := recvErr(&pb.TapRequest{Id: "orders", Point: "sink:nope"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("want InvalidArgument for an unknown point, got %v", err)
+	}
+
+	stream, err := cli.Tap(ctx, &pb.TapRequest{Id: "orders", Point: "sink:stdout", Filter: &pb.TapFilter{Key: []byte("k")}})
+	if err != nil {
+		t.Fatalf("tap: %v", err)
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if msg.Point != "sink:stdout" || string(msg.Frame.GetValue()) != "v" {
+		t.Fatalf("unexpected tap frame: %+v", msg)
+	}
+
+	if _, err := cli.DeletePipeline(ctx, &pb.DeleteRequest{Id: "orders"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("want Unavailable once the pipeline stops, got %v", err)
+	}
+}
+
+func TestControl_AuditsMutationsWithSpecDiff(t *testing.T) {
+	kafka.Register("fake", func() kafka.Adapter { return &fakeSource{} })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgr := NewManager(ctx)
+	defer mgr.Close()
+	var buf bytes.Buffer
+	srv := NewControlServer(mgr, auth.NewAuditor(&buf))
+	caller := auth.WithIdentity(ctx, auth.Identity{Name: "ci", Method: "token", Role: auth.RoleAdmin})
+
+	if _, err := srv.DeployPipeline(caller, &pb.DeployRequest{Yaml: testSpec, BaseDir: t.TempDir()}); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	changed := strings.Replace(testSpec, "sinks: [stdout]", "sinks: [stdout, stdout]", 1)
+	if _, err := srv.DeployPipeline(caller, &pb.DeployRequest{Yaml: changed}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("want AlreadyExists, got %v", err)
+	}
+	if _, err := srv.DeletePipeline(caller, &pb.DeleteRequest{Id: "orders"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	var recs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("bad audit line %q: %v", line, err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("want 3 audit records, got %d", len(recs))
+	}
+	for _, rec := range recs {
+		if rec["identity"] != "ci" || rec["pipeline"] != "orders" {
+			t.Fatalf("record without identity or pipeline: %v", rec)
+		}
+	}
+	if d, _ := recs[0]["diff"].(string); !strings.Contains(d, "+name: orders") {
+		t.Fatalf("first deploy must add the whole spec, got %q", d)
+	}
+	if d := recs[1]["diff"]; d != "-sinks: [stdout]\n+sinks: [stdout, stdout]\n" || recs[1]["outcome"] != "AlreadyExists" {
+		t.Fatalf("rejected redeploy: %v", recs[1])
+	}
+	if d, _ := recs[2]["diff"].(string); !strings.Contains(d, "-name: orders") || recs[2]["method"] != "DeletePipeline" {
+		t.Fatalf("delete must remove the spec: %v", recs[2])
+	}
+}
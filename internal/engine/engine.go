@@ -2,24 +2,64 @@ package engine
 
 import (
 	"context"
-	"quanta/internal/pipeline"
+	"errors"
+	"os"
+	"quanta/internal/logging"
 	"quanta/internal/transport"
+	"time"
 )
 
 type Engine struct {
-	transport *transport.Server
-	runner    *pipeline.Runner
+	transport    *transport.Server
+	manager      *Manager
+	drainTimeout time.Duration
+	tracing      func(context.Context) error
+	admin        func(context.Context) error
+	auditFile    *os.File
 }
 
+// Run serves the control plane until ctx is cancelled, the gRPC server fails
+// or every pipeline has failed, then shuts down in order: stop accepting
+// control calls, drain each pipeline and close the admin listener. The
+// returned error is the reason the engine stopped, if it was not ctx.
 func (e *Engine) Run(ctx context.Context) error {
 
-	go func() {
-		<-ctx.Done()
-		e.transport.Stop()
-		if e.runner != nil {
-			_ = e.runner.Close()
-		}
-	}()
+	served := make(chan error, 1)
+	go func() { served <- e.transport.Serve() }()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-e.manager.Fatal():
+		logging.L().Error("all pipelines failed; shutting down", "err", err)
+	case err = <-served:
+	}
 
-	return e.transport.Serve()
+	e.transport.Stop()
+	dctx, cancel := context.WithTimeout(context.Background(), e.drainTimeout)
+	defer cancel()
+	if derr := e.manager.Shutdown(dctx); derr != nil {
+		logging.L().Warn("shutdown drain incomplete", "err", derr)
+		if err == nil && !errors.Is(derr, context.DeadlineExceeded) {
+			err = derr
+		}
+	}
+	if e.admin != nil {
+		actx, acancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer acancel()
+		if aerr := e.admin(actx); aerr != nil {
+			logging.L().Warn("admin listener shutdown failed", "err", aerr)
+		}
+	}
+	if e.tracing != nil {
+		tctx, tcancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer tcancel()
+		if terr := e.tracing(tctx); terr != nil {
+			logging.L().Warn("trace exporter shutdown failed", "err", terr)
+		}
+	}
+	if e.auditFile != nil {
+		_ = e.auditFile.Close()
+	}
+	return err
 }
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// healthWatchInterval is how often Watch re-evaluates readiness.
+const healthWatchInterval = time.Second
+
+// healthServer implements the standard gRPC health protocol on top of the
+// Manager. The empty service name reports whether every pipeline is ready;
+// a pipeline id reports that pipeline alone.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	m *Manager
+}
+
+func NewHealthServer(m *Manager) healthpb.HealthServer { return &healthServer{m: m} }
+
+func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	st, err := s.status(ctx, req.GetService())
+	if err != nil {
+		return nil, err
+	}
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+func (s *healthServer) List(ctx context.Context, _ *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	out := &healthpb.HealthListResponse{Statuses: map[string]*healthpb.HealthCheckResponse{}}
+	for _, svc := range append([]string{""}, s.m.ids()...) {
+		if st, err := s.status(ctx, svc); err == nil {
+			out.Statuses[svc] = &healthpb.HealthCheckResponse{Status: st}
+		}
+	}
+	return out, nil
+}
+
+// Watch polls readiness and streams every change. Unknown services are
+// reported as SERVICE_UNKNOWN rather than ending the stream, as the
+// protocol requires.
+func (s *healthServer) Watch(req *healthpb.HealthCheckRequest, stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse]) error {
+	ctx := stream.Context()
+	t := time.NewTicker(healthWatchInterval)
+	defer t.Stop()
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		st, err := s.status(ctx, req.GetService())
+		if err != nil {
+			st = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if st != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: st}); err != nil {
+				return err
+			}
+			last = st
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (s *healthServer) status(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	var err error
+	if service == "" {
+		err = s.m.Ready(ctx)
+	} else {
+		err = s.m.PipelineReady(ctx, service)
+	}
+	switch {
+	case errors.Is(err, ErrPipelineNotFound) && service != "":
+		return 0, status.Errorf(codes.NotFound, "unknown service %q", service)
+	case err != nil:
+		return healthpb.HealthCheckResponse_NOT_SERVING, nil
+	}
+	return healthpb.HealthCheckResponse_SERVING, nil
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"quanta/source/kafka"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealth_ReflectsPipelineReadiness(t *testing.T) {
+	src := &fakeSource{}
+	kafka.Register("fake", func() kafka.Adapter { return src })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(ctx)
+	defer m.Close()
+	hs := NewHealthServer(m)
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		res, err := hs.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("check %q: %v", service, err)
+		}
+		return res.GetStatus()
+	}
+
+	if got := check(""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("engine without pipelines must not be ready, got %v", got)
+	}
+	if _, err := m.Deploy([]byte(testSpec)); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if got := check(""); got != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("want SERVING, got %v (%v)", got, m.Ready(ctx))
+	}
+	if got := check("orders"); got != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("want orders SERVING, got %v", got)
+	}
+
+	src.notReady.Store(true)
+	if got := check("orders"); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("source without partitions must not be ready, got %v", got)
+	}
+	if err := m.Live(); err != nil {
+		t.Fatalf("unready pipeline must still be live: %v", err)
+	}
+
+	_, err := hs.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("want NotFound for unknown service, got %v", err)
+	}
+}
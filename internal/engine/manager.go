@@ -0,0 +1,474 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/config"
+	"quanta/internal/logging"
+	"quanta/internal/pipeline"
+	"quanta/internal/registry"
+	"quanta/internal/spec"
+)
+
+const (
+	StateRunning    = pipeline.StateRunning
+	StatePaused     = "paused"
+	StateRestarting = pipeline.StateRestarting
+	StateFailed     = pipeline.StateFailed
+)
+
+// DefaultDrainTimeout bounds how long a pipeline may take to drain when it
+// is deleted, restarted or the engine shuts down.
+const DefaultDrainTimeout = 30 * time.Second
+
+var (
+	ErrPipelineNotFound = errors.New("pipeline not found")
+	ErrPipelineExists   = errors.New("pipeline already exists")
+)
+
+// Manager owns the engine's running pipelines, keyed by pipeline name.
+type Manager struct {
+	ctx context.Context
+
+	mu        sync.Mutex
+	pipelines map[string]*managedPipeline
+	files     map[string]string
+	seq       int
+
+	reloadMu sync.Mutex
+
+	drainTimeout time.Duration
+	fatal        chan error
+	// node is the engine's node id, reported by Ping.
+	node string
+	// secretsDir is the only directory ${FILE:...} references in deployed
+	// specs may read; empty rejects them.
+	secretsDir string
+	// store persists pipelines deployed over the control plane; nil keeps
+	// them in memory only.
+	store *registry.Store
+}
+
+type managedPipeline struct {
+	runner  *pipeline.Runner
+	state   string
+	started time.Time
+	// raw is the spec as deployed, for audit diffs.
+	raw []byte
+	// version is the spec's registry version; 0 for pipelines the registry
+	// does not hold.
+	version int
+
+	// path, spec and confPath are set for pipelines loaded from a file so
+	// Reload can diff against what is running.
+	path     string
+	spec     spec.File
+	confPath string
+}
+
+// NewManager returns a manager whose pipelines run until ctx is done or they
+// are deleted.
+func NewManager(ctx context.Context) *Manager {
+	return &Manager{
+		ctx:          ctx,
+		pipelines:    map[string]*managedPipeline{},
+		files:        map[string]string{},
+		drainTimeout: DefaultDrainTimeout,
+		fatal:        make(chan error, 1),
+	}
+}
+
+// Deploy compiles a pipeline spec and starts it. Specs without a name get a
+// generated id.
+func (m *Manager) Deploy(raw []byte) (string, error) {
+	id, _, err := m.Apply(raw, DeployOptions{})
+	return id, err
+}
+
+// DeployFile compiles and starts the pipeline in path. Specs without a name
+// take the file name, minus its extension, as their id.
+func (m *Manager) DeployFile(path string) (string, error) {
+	path = absPath(path)
+	cfg, confPath, err := config.LoadPipelineSpec(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	r, err := pipeline.CompileSpec(cfg, confPath)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if r.ID() == "" {
+		r.SetPipelineID(fileID(path))
+	}
+	raw, _ := os.ReadFile(path)
+	if err := m.add(&managedPipeline{runner: r, path: path, spec: cfg, confPath: confPath, raw: raw}); err != nil {
+		_ = r.Close()
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return r.ID(), nil
+}
+
+func fileID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// Add starts an already compiled runner under the manager.
+func (m *Manager) Add(r *pipeline.Runner) error {
+	return m.add(&managedPipeline{runner: r})
+}
+
+func (m *Manager) add(p *managedPipeline) error {
+	_, err := m.start(p, nil)
+	return err
+}
+
+// start runs p under the manager. When p replaces old, old keeps its entry
+// until p has started, so a replacement that cannot start leaves old
+// running; once p runs it takes over old's entry and paused state, and
+// replaced reports that old is left for the caller to drain. It is false
+// when old was deleted meanwhile.
+func (m *Manager) start(p, old *managedPipeline) (replaced bool, err error) {
+	r := p.runner
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for r.ID() == "" {
+		m.seq++
+		if id := fmt.Sprintf("pipeline-%d", m.seq); m.pipelines[id] == nil {
+			r.SetPipelineID(id)
+		}
+	}
+	id := r.ID()
+	if q, ok := m.pipelines[id]; ok && (old == nil || q != old) {
+		return false, fmt.Errorf("%w: %s", ErrPipelineExists, id)
+	}
+	if err := r.Start(m.ctx); err != nil {
+		return false, err
+	}
+	p.state, p.started = StateRunning, time.Now()
+	if old != nil {
+		oldID := old.runner.ID()
+		if replaced = m.pipelines[oldID] == old; replaced {
+			delete(m.pipelines, oldID)
+		}
+		if old.path != "" && m.files[old.path] == oldID {
+			delete(m.files, old.path)
+		}
+		if replaced && old.state == StatePaused {
+			if err := r.Pause(); err != nil {
+				logging.L().Warn("restarted pipeline not paused", "pipeline", id, "err", err)
+			} else {
+				p.state = StatePaused
+			}
+		}
+	}
+	m.pipelines[id] = p
+	if p.path != "" {
+		m.files[p.path] = id
+	}
+	go m.watch(p)
+	logging.L().Info("pipeline started", "pipeline", id)
+	return replaced, nil
+}
+
+// Spec returns the spec a pipeline was deployed or last reloaded with.
+func (m *Manager) Spec(id string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pipelines[id]
+	if !ok {
+		return nil, false
+	}
+	return p.raw, true
+}
+
+func (m *Manager) Pause(id string) error {
+	if err := m.setState(id, StatePaused, (*pipeline.Runner).Pause); err != nil {
+		return err
+	}
+	m.persistPaused(id, true)
+	return nil
+}
+
+func (m *Manager) Resume(id string) error {
+	if err := m.setState(id, StateRunning, (*pipeline.Runner).Resume); err != nil {
+		return err
+	}
+	m.persistPaused(id, false)
+	return nil
+}
+
+func (m *Manager) setState(id, state string, fn func(*pipeline.Runner) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pipelines[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	if p.state == state {
+		return nil
+	}
+	if p.state == StateFailed {
+		return fmt.Errorf("pipeline %s has failed: %v", id, p.runner.Err())
+	}
+	if err := fn(p.runner); err != nil {
+		return err
+	}
+	p.state = state
+	logging.L().Info("pipeline state changed", "pipeline", id, "state", state)
+	return nil
+}
+
+func (m *Manager) Delete(id string) error {
+	p, ok := m.detach(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	logging.L().Info("pipeline deleted", "pipeline", id)
+	if p.version > 0 {
+		if err := m.store.Delete(id); err != nil {
+			logging.L().Error("pipeline registry delete failed", "pipeline", id, "err", err)
+		}
+	}
+	forget := pipeline.StaleMetrics(p.runner, nil)
+	err := m.stop(p)
+	forget()
+	return err
+}
+
+func (m *Manager) stop(p *managedPipeline) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.drainTimeout)
+	defer cancel()
+	return p.runner.Drain(ctx)
+}
+
+// handOver quiesces old, so that it holds no uncommitted frames when its
+// replacement joins the consumer group, and then starts next in its place.
+// If next cannot start old resumes.
+func (m *Manager) handOver(next, old *managedPipeline) (replaced bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.drainTimeout)
+	if err := old.runner.Quiesce(ctx); err != nil {
+		logging.L().Warn("pipeline drain incomplete before restart", "pipeline", old.runner.ID(), "err", err)
+	}
+	cancel()
+	if replaced, err = m.start(next, old); err != nil {
+		m.resume(old)
+	}
+	return replaced, err
+}
+
+// resume restarts fetching in a quiesced pipeline unless it was paused.
+func (m *Manager) resume(p *managedPipeline) {
+	m.mu.Lock()
+	paused := p.state == StatePaused
+	m.mu.Unlock()
+	if !paused {
+		_ = p.runner.Resume()
+	}
+}
+
+// watch marks a pipeline failed once its supervisor gives up on the source.
+// Once no pipeline is left running the error is reported on Fatal.
+func (m *Manager) watch(p *managedPipeline) {
+	<-p.runner.Done()
+	err := p.runner.Err()
+	if err == nil {
+		return
+	}
+	id := p.runner.ID()
+	m.mu.Lock()
+	if m.pipelines[id] != p {
+		m.mu.Unlock()
+		return
+	}
+	p.state = StateFailed
+	alive := 0
+	for _, q := range m.pipelines {
+		if q.state != StateFailed {
+			alive++
+		}
+	}
+	m.mu.Unlock()
+	logging.L().Error("pipeline failed", "pipeline", id, "err", err)
+	if alive == 0 {
+		select {
+		case m.fatal <- fmt.Errorf("pipeline %s: %w", id, err):
+		default:
+		}
+	}
+}
+
+// Fatal delivers an error once every pipeline has failed.
+func (m *Manager) Fatal() <-chan error { return m.fatal }
+
+func (m *Manager) detach(id string) (*managedPipeline, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pipelines[id]
+	if !ok {
+		return nil, false
+	}
+	delete(m.pipelines, id)
+	if p.path != "" && m.files[p.path] == id {
+		delete(m.files, p.path)
+	}
+	return p, true
+}
+
+func (m *Manager) Status(ctx context.Context, id string) (*pb.PipelineStatus, error) {
+	m.mu.Lock()
+	p, ok := m.pipelines[id]
+	var state string
+	if ok {
+		state = p.state
+	}
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	if rs := p.runner.State(); state != StateFailed && rs == StateRestarting {
+		state = rs
+	}
+
+	st := p.runner.SourceStats()
+	out := &pb.PipelineStatus{
+		Id:          id,
+		State:       state,
+		Lag:         st.Lag,
+		InFlight:    st.InFlight,
+		StartedAtMs: p.started.UnixMilli(),
+	}
+	if err := p.runner.LastError(); err != nil {
+		out.LastError = err.Error()
+	}
+	for _, h := range p.runner.StageHealth(ctx) {
+		out.Stages = append(out.Stages, &pb.StageStatus{Name: h.Name, Healthy: h.Healthy, Error: h.Err})
+	}
+	return out, nil
+}
+
+// Tap attaches a frame tap to a running pipeline; see Runner.Tap. The tap is
+// closed when the pipeline is deleted or restarted by a reload.
+func (m *Manager) Tap(id, point string, o pipeline.TapOptions) (*pipeline.Tap, error) {
+	m.mu.Lock()
+	p, ok := m.pipelines[id]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	return p.runner.Tap(point, o)
+}
+
+// Ready reports whether every pipeline is ready to process frames; see
+// Runner.Ready. An engine without pipelines is not ready.
+func (m *Manager) Ready(ctx context.Context) error {
+	ids := m.ids()
+	if len(ids) == 0 {
+		return errors.New("no pipelines deployed")
+	}
+	var errs []error
+	for _, id := range ids {
+		if err := m.PipelineReady(ctx, id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) PipelineReady(ctx context.Context, id string) error {
+	m.mu.Lock()
+	p, ok := m.pipelines[id]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	if err := p.runner.Ready(ctx); err != nil {
+		return fmt.Errorf("pipeline %s: %w", id, err)
+	}
+	return nil
+}
+
+// Live fails once every deployed pipeline has failed; the engine is about
+// to exit at that point and should not be considered alive.
+func (m *Manager) Live() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.pipelines) == 0 {
+		return nil
+	}
+	for _, p := range m.pipelines {
+		if p.state != StateFailed {
+			return nil
+		}
+	}
+	return errors.New("every pipeline has failed")
+}
+
+func (m *Manager) List(ctx context.Context) []*pb.PipelineStatus {
+	out := make([]*pb.PipelineStatus, 0)
+	for _, id := range m.ids() {
+		if st, err := m.Status(ctx, id); err == nil {
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+func (m *Manager) ids() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, 0, len(m.pipelines))
+	for id := range m.pipelines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Shutdown drains every pipeline in parallel within ctx.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, id := range m.ids() {
+		p, ok := m.detach(id)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.runner.Drain(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("pipeline %s: %w", id, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Close drains every pipeline with the manager's drain timeout.
+func (m *Manager) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.drainTimeout)
+	defer cancel()
+	return m.Shutdown(ctx)
+}
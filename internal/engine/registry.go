@@ -0,0 +1,229 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"quanta/internal/logging"
+	"quanta/internal/pipeline"
+	"quanta/internal/registry"
+)
+
+// ErrNoRegistry is returned for version history and rollback on an engine
+// without a pipeline registry.
+var ErrNoRegistry = errors.New("engine has no pipeline registry")
+
+// DeployOptions tune Apply.
+type DeployOptions struct {
+	// Replace restarts a running pipeline of the same name with the new spec
+	// instead of failing with ErrPipelineExists. A paused pipeline stays
+	// paused.
+	Replace bool
+	// By names the caller in the registry's version history.
+	By string
+}
+
+// Apply compiles a pipeline spec and starts it, recording it as a new
+// version in the registry when the manager has one. The returned version is
+// 0 without a registry. The spec is treated as deployed over the control
+// plane: see config.ParseDeployedPipelineSpec for what it may reference.
+func (m *Manager) Apply(raw []byte, o DeployOptions) (string, int, error) {
+	r, err := pipeline.CompileDeployed(raw, m.secretsDir)
+	if err != nil {
+		return "", 0, err
+	}
+	return m.apply(r, raw, o, 0)
+}
+
+func (m *Manager) apply(r *pipeline.Runner, raw []byte, o DeployOptions, rollbackOf int) (string, int, error) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	next := &managedPipeline{runner: r, raw: raw}
+	m.mu.Lock()
+	old := m.pipelines[r.ID()]
+	m.mu.Unlock()
+	switch {
+	case old != nil && o.Replace && old.path != "":
+		_ = r.Close()
+		return "", 0, fmt.Errorf("%w: %s is loaded from %s", ErrPipelineExists, r.ID(), old.path)
+	case old != nil && o.Replace:
+		// The old version stays open, quiesced, until the new one is
+		// recorded, so a failed registry write can hand its entry back.
+		replaced, err := m.handOver(next, old)
+		if err != nil {
+			_ = r.Close()
+			return "", 0, fmt.Errorf("restart %s: %w; the running version is kept", old.runner.ID(), err)
+		}
+		if !replaced {
+			old = nil
+		}
+	default:
+		if err := m.add(next); err != nil {
+			_ = r.Close()
+			return "", 0, err
+		}
+		old = nil
+	}
+
+	id := r.ID()
+	version := 0
+	if m.store != nil {
+		v, err := m.store.Put(id, raw, registry.Version{DeployedBy: o.By, RollbackOf: rollbackOf})
+		if err != nil {
+			m.revert(next, old)
+			return "", 0, fmt.Errorf("record %s: %w; the deploy was undone", id, err)
+		}
+		m.mu.Lock()
+		next.version = v.Version
+		paused := next.state == StatePaused
+		m.mu.Unlock()
+		m.persistPaused(id, paused)
+		version = v.Version
+	}
+	if old != nil {
+		forget := pipeline.StaleMetrics(old.runner, r)
+		if err := m.stop(old); err != nil {
+			logging.L().Warn("pipeline drain incomplete after restart", "pipeline", id, "err", err)
+		}
+		forget()
+	}
+	return id, version, nil
+}
+
+// revert undoes handOver(next, old): next is drained and old, which was
+// only quiesced, takes its entry back and resumes. old may be nil.
+func (m *Manager) revert(next, old *managedPipeline) {
+	id := next.runner.ID()
+	m.mu.Lock()
+	if m.pipelines[id] == next {
+		delete(m.pipelines, id)
+	}
+	if old != nil {
+		m.pipelines[old.runner.ID()] = old
+	}
+	m.mu.Unlock()
+	if old != nil {
+		m.resume(old)
+	}
+	var kept *pipeline.Runner
+	if old != nil {
+		kept = old.runner
+	}
+	forget := pipeline.StaleMetrics(next.runner, kept)
+	if err := m.stop(next); err != nil {
+		logging.L().Warn("pipeline drain incomplete after undoing a deploy", "pipeline", id, "err", err)
+	}
+	forget()
+}
+
+// persistPaused records a registered pipeline's paused state.
+func (m *Manager) persistPaused(id string, paused bool) {
+	m.mu.Lock()
+	p, ok := m.pipelines[id]
+	registered := ok && p.version > 0
+	m.mu.Unlock()
+	if !registered {
+		return
+	}
+	if err := m.store.SetPaused(id, paused); err != nil {
+		logging.L().Error("pipeline registry write failed", "pipeline", id, "err", err)
+	}
+}
+
+// Restore starts every pipeline in the registry at its current version,
+// paused if it was paused when the engine stopped. A pipeline that no longer
+// compiles or starts is logged and skipped; its history is kept.
+func (m *Manager) Restore() (int, error) {
+	if m.store == nil {
+		return 0, nil
+	}
+	recs, err := m.store.List()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, rec := range recs {
+		if err := m.restore(rec); err != nil {
+			logging.L().Error("pipeline restore failed", "pipeline", rec.ID, "version", rec.Current, "err", err)
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (m *Manager) restore(rec registry.Record) error {
+	r, raw, err := m.compileVersion(rec, rec.Current)
+	if err != nil {
+		return err
+	}
+	if err := m.add(&managedPipeline{runner: r, raw: raw, version: rec.Current}); err != nil {
+		_ = r.Close()
+		return err
+	}
+	logging.L().Info("pipeline restored", "pipeline", rec.ID, "version", rec.Current, "paused", rec.Paused)
+	if rec.Paused {
+		return m.Pause(rec.ID)
+	}
+	return nil
+}
+
+// compileVersion compiles version v of a registry record under the
+// record's id.
+func (m *Manager) compileVersion(rec registry.Record, v int) (*pipeline.Runner, []byte, error) {
+	if _, ok := rec.Version(v); !ok {
+		return nil, nil, fmt.Errorf("%w: %s has no version %d", ErrPipelineNotFound, rec.ID, v)
+	}
+	raw, err := m.store.Spec(rec.ID, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := pipeline.CompileDeployed(raw, m.secretsDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("version %d: %w", v, err)
+	}
+	r.SetPipelineID(rec.ID)
+	return r, raw, nil
+}
+
+// Rollback redeploys an earlier version of a pipeline's spec, or the one
+// before the current version when version is 0. The restored spec becomes a
+// new version whose RollbackOf names the one it came from.
+func (m *Manager) Rollback(id string, version int, by string) (int, error) {
+	rec, err := m.History(id)
+	if err != nil {
+		return 0, err
+	}
+	if version == 0 {
+		for i, v := range rec.Versions {
+			if v.Version == rec.Current && i > 0 {
+				version = rec.Versions[i-1].Version
+			}
+		}
+		if version == 0 {
+			return 0, fmt.Errorf("pipeline %s has no version before %d", id, rec.Current)
+		}
+	}
+	if version == rec.Current {
+		return 0, fmt.Errorf("pipeline %s already runs version %d", id, version)
+	}
+	r, raw, err := m.compileVersion(rec, version)
+	if err != nil {
+		return 0, err
+	}
+	_, v, err := m.apply(r, raw, DeployOptions{Replace: true, By: by}, version)
+	return v, err
+}
+
+// History returns a pipeline's registry record.
+func (m *Manager) History(id string) (registry.Record, error) {
+	if m.store == nil {
+		return registry.Record{}, ErrNoRegistry
+	}
+	rec, err := m.store.Get(id)
+	if errors.Is(err, registry.ErrNotFound) {
+		return registry.Record{}, fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	return rec, err
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"quanta/internal/registry"
+	"quanta/source/kafka"
+)
+
+func newRegistryManager(t *testing.T, dir string) *Manager {
+	t.Helper()
+	st, err := registry.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := NewManager(ctx)
+	m.store = st
+	t.Cleanup(func() { _ = m.Close(); cancel() })
+	return m
+}
+
+func TestManager_RegistryRestoresAndRollsBack(t *testing.T) {
+	kafka.Register("fake", func() kafka.Adapter { return &fakeSource{} })
+	dir := t.TempDir()
+	v2 := testSpec + "transformers:\n  - { name: upper, type: grpc, address: \"localhost:1\", timeout_ms: 100 }\n"
+
+	m := newRegistryManager(t, dir)
+	if _, v, err := m.Apply([]byte(testSpec), DeployOptions{By: "ci"}); err != nil || v != 1 {
+		t.Fatalf("deploy: version %d, %v", v, err)
+	}
+	if _, _, err := m.Apply([]byte(v2), DeployOptions{}); !errors.Is(err, ErrPipelineExists) {
+		t.Fatalf("redeploy without replace: want ErrPipelineExists, got %v", err)
+	}
+	if _, v, err := m.Apply([]byte(v2), DeployOptions{Replace: true, By: "ci"}); err != nil || v != 2 {
+		t.Fatalf("replace: version %d, %v", v, err)
+	}
+	if err := m.Pause("orders"); err != nil {
+		t.Fatal(err)
+	}
+	// Shutdown stops pipelines without forgetting them.
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m = newRegistryManager(t, dir)
+	if n, err := m.Restore(); err != nil || n != 1 {
+		t.Fatalf("restore: %d %v", n, err)
+	}
+	if raw, _ := m.Spec("orders"); string(raw) != v2 {
+		t.Fatalf("restored spec:\n%s", raw)
+	}
+	if st, err := m.Status(context.Background(), "orders"); err != nil || st.State != StatePaused {
+		t.Fatalf("restored status: %+v %v", st, err)
+	}
+
+	v, err := m.Rollback("orders", 0, "ops")
+	if err != nil || v != 3 {
+		t.Fatalf("rollback: version %d, %v", v, err)
+	}
+	if raw, _ := m.Spec("orders"); string(raw) != testSpec {
+		t.Fatalf("rolled back spec:\n%s", raw)
+	}
+	if st, _ := m.Status(context.Background(), "orders"); st.State != StatePaused {
+		t.Fatalf("rollback must keep the pipeline paused, got %s", st.State)
+	}
+	if _, err := m.Rollback("orders", 3, "ops"); err == nil {
+		t.Fatal("rollback to the current version: want error")
+	}
+	rec, err := m.History("orders")
+	if err != nil || rec.Current != 3 || len(rec.Versions) != 3 || !rec.Paused {
+		t.Fatalf("history: %+v %v", rec, err)
+	}
+	if last := rec.Versions[2]; last.RollbackOf != 1 || last.DeployedBy != "ops" {
+		t.Fatalf("rollback version: %+v", last)
+	}
+
+	if err := m.Delete("orders"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.History("orders"); !errors.Is(err, ErrPipelineNotFound) {
+		t.Fatalf("history after delete: want ErrPipelineNotFound, got %v", err)
+	}
+	if _, err := NewManager(context.Background()).History("orders"); !errors.Is(err, ErrNoRegistry) {
+		t.Fatalf("history without registry: want ErrNoRegistry, got %v", err)
+	}
+}
+
+func TestManager_ApplyConfinesReferencesToSecretsDir(t *testing.T) {
+	kafka.Register("fake", func() kafka.Adapter { return &fakeSource{} })
+	dir := t.TempDir()
+	secretsDir := filepath.Join(dir, "secrets")
+	if err := os.Mkdir(secretsDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for path, body := range map[string]string{
+		filepath.Join(secretsDir, "name"): "orders-from-secret\n",
+		filepath.Join(dir, "outside"):     "outside-secret\n",
+	} {
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("QUANTA_TEST_PIPELINE_NAME", "orders-from-env")
+	named := func(name string) []byte {
+		return []byte(fmt.Sprintf("schema_version: v1\nname: %q\nsource: { kind: kafka, driver: fake }\nsinks: [stdout]\n", name))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(ctx)
+	defer m.Close()
+	if _, _, err := m.Apply(named("${FILE:name}"), DeployOptions{}); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("want FILE rejected without a secrets dir, got %v", err)
+	}
+
+	m.secretsDir = secretsDir
+	for spec, want := range map[string]string{
+		string(named("${ENV:QUANTA_TEST_PIPELINE_NAME}")):                             "not allowed",
+		string(named("${FILE:../outside}")):                                           "escapes",
+		string(named("${FILE:" + filepath.Join(dir, "outside") + "}")):                "escapes",
+		"source: { kind: kafka, driver: fake, config: kafka.yml }\nsinks: [stdout]\n": "cannot be read on the engine",
+	} {
+		if _, _, err := m.Apply([]byte(spec), DeployOptions{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Apply(%q) = %v, want an error containing %q", spec, err, want)
+		}
+	}
+	if id, _, err := m.Apply(named("${FILE:name}"), DeployOptions{}); err != nil || id != "orders-from-secret" {
+		t.Fatalf("Apply with a secrets dir reference = %q, %v", id, err)
+	}
+}
+
+func TestManager_ApplyUndoesDeployWhenRegistryWriteFails(t *testing.T) {
+	kafka.Register("fake", func() kafka.Adapter { return &fakeSource{} })
+	dir := t.TempDir()
+	m := newRegistryManager(t, dir)
+	if _, _, err := m.Apply([]byte(testSpec), DeployOptions{}); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	before := runnerOf(m, "orders")
+
+	// A directory where the next spec version goes makes its write fail.
+	if err := os.MkdirAll(filepath.Join(dir, "orders", "v000002.yml", "x"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	v2 := strings.Replace(testSpec, "sinks: [stdout]", "sinks: [stdout, stdout]", 1)
+	if _, _, err := m.Apply([]byte(v2), DeployOptions{Replace: true}); err == nil || !strings.Contains(err.Error(), "undone") {
+		t.Fatalf("want the redeploy undone, got %v", err)
+	}
+	if runnerOf(m, "orders") != before {
+		t.Fatal("the running version must be kept when its replacement is not recorded")
+	}
+	if st, err := m.Status(context.Background(), "orders"); err != nil || st.GetState() != StateRunning {
+		t.Fatalf("orders status: %v, %v", st, err)
+	}
+	if rec, err := m.History("orders"); err != nil || rec.Current != 1 {
+		t.Fatalf("history: %+v, %v", rec, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "clicks"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clicks := strings.Replace(testSpec, "name: orders", "name: clicks", 1)
+	if _, _, err := m.Apply([]byte(clicks), DeployOptions{}); err == nil {
+		t.Fatal("want the deploy refused when it cannot be recorded")
+	}
+	if runnerOf(m, "clicks") != nil {
+		t.Fatal("an unrecorded deploy must not keep running")
+	}
+}
@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"quanta/internal/config"
+	"quanta/internal/logging"
+	"quanta/internal/pipeline"
+	"quanta/internal/spec"
+	"quanta/internal/telemetry"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	reloadUnchanged = "unchanged"
+	reloadStages    = "stages"
+	reloadRestart   = "restart"
+	reloadRejected  = "rejected"
+)
+
+// reloadDebounce coalesces the bursts of events editors produce for a
+// single save.
+const reloadDebounce = 250 * time.Millisecond
+
+// specChange classifies the difference between the running spec and a new
+// one: transformer-only edits can be swapped in place, anything touching the
+// source, sinks or graph needs a restart.
+func specChange(old, cur spec.File, oldConf, curConf string) string {
+	if oldConf != curConf {
+		return reloadRestart
+	}
+	o, c := old, cur
+	o.Transformers, c.Transformers = nil, nil
+	if !reflect.DeepEqual(o, c) {
+		return reloadRestart
+	}
+	if !reflect.DeepEqual(old.Transformers, cur.Transformers) {
+		return reloadStages
+	}
+	return reloadUnchanged
+}
+
+// Reload re-reads the spec at path and applies it to the pipeline loaded
+// from that file, deploying it if the file is new. A spec that fails to
+// parse or compile is rejected and the running pipeline is left alone.
+func (m *Manager) Reload(path string) error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	path = absPath(path)
+	m.mu.Lock()
+	id, known := m.files[path]
+	p := m.pipelines[id]
+	m.mu.Unlock()
+	if !known {
+		id = fileID(path)
+	}
+
+	result, err := m.reload(path, p)
+	if err != nil {
+		telemetry.PipelineReloads.WithLabelValues(id, reloadRejected).Inc()
+		logging.L().Error("pipeline reload rejected", "pipeline", id, "file", path, "err", err)
+		return err
+	}
+	// A renamed pipeline's old series are gone; count it under its new id.
+	m.mu.Lock()
+	if cur, ok := m.files[path]; ok {
+		id = cur
+	}
+	m.mu.Unlock()
+	telemetry.PipelineReloads.WithLabelValues(id, result).Inc()
+	if result != reloadUnchanged {
+		logging.L().Info("pipeline reloaded", "pipeline", id, "file", path, "result", result)
+	}
+	return nil
+}
+
+func (m *Manager) reload(path string, p *managedPipeline) (string, error) {
+	cfg, confPath, err := config.LoadPipelineSpec(path)
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		_, err := m.DeployFile(path)
+		return reloadRestart, err
+	}
+
+	newID := cfg.Name
+	if newID == "" {
+		newID = fileID(path)
+	}
+	change := specChange(p.spec, cfg, p.confPath, confPath)
+	if newID != p.runner.ID() {
+		change = reloadRestart
+	}
+
+	switch change {
+	case reloadUnchanged:
+		return change, nil
+	case reloadStages:
+		stages, err := pipeline.CompileStages(cfg.Transformers)
+		if err != nil {
+			return "", err
+		}
+		p.runner.ReplaceStages(stages)
+		raw, _ := os.ReadFile(path)
+		m.mu.Lock()
+		p.spec, p.raw = cfg, raw
+		m.mu.Unlock()
+		return change, nil
+	}
+
+	r, err := pipeline.CompileSpec(cfg, confPath)
+	if err != nil {
+		return "", err
+	}
+	r.SetPipelineID(newID)
+	raw, _ := os.ReadFile(path)
+	return change, m.restart(p, &managedPipeline{runner: r, path: path, spec: cfg, confPath: confPath, raw: raw})
+}
+
+// restart quiesces the old pipeline, starts its replacement carrying over
+// a paused state, and only then closes the old one. If the replacement
+// cannot start the old pipeline resumes and the error says so.
+func (m *Manager) restart(old, next *managedPipeline) error {
+	oldID := old.runner.ID()
+	replaced, err := m.handOver(next, old)
+	if err != nil {
+		_ = next.runner.Close()
+		return fmt.Errorf("restart %s: %w; the running version is kept", oldID, err)
+	}
+	if !replaced {
+		return nil
+	}
+	forget := pipeline.StaleMetrics(old.runner, next.runner)
+	if err := m.stop(old); err != nil {
+		logging.L().Warn("pipeline drain incomplete after restart", "pipeline", oldID, "err", err)
+	}
+	forget()
+	return nil
+}
+
+// RemoveFile deletes the pipeline that was loaded from path, if any.
+func (m *Manager) RemoveFile(path string) error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.mu.Lock()
+	id, ok := m.files[absPath(path)]
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return m.Delete(id)
+}
+
+func isSpecFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// watchPipelines reloads the given files, and any spec file in dir, when
+// they change on disk until ctx is done. Parent directories are watched
+// rather than the files so editors that save by rename are picked up.
+func watchPipelines(ctx context.Context, m *Manager, files []string, dir string) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	tracked := map[string]bool{}
+	dirs := map[string]bool{}
+	for _, f := range files {
+		f = absPath(f)
+		tracked[f] = true
+		dirs[filepath.Dir(f)] = true
+	}
+	if dir != "" {
+		dir = absPath(dir)
+		dirs[dir] = true
+	}
+	for d := range dirs {
+		if err := w.Add(d); err != nil {
+			_ = w.Close()
+			return fmt.Errorf("watch %s: %w", d, err)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		pending = map[string]*time.Timer{}
+	)
+	apply := func(path string) {
+		mu.Lock()
+		delete(pending, path)
+		mu.Unlock()
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			if err := m.RemoveFile(path); err != nil {
+				logging.L().Error("pipeline removal failed", "file", path, "err", err)
+			}
+			return
+		}
+		_ = m.Reload(path)
+	}
+
+	go func() {
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				mu.Lock()
+				for _, t := range pending {
+					t.Stop()
+				}
+				mu.Unlock()
+				return
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				logging.L().Warn("pipeline watcher error", "err", err)
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				path := filepath.Clean(ev.Name)
+				if !tracked[path] && (dir == "" || filepath.Dir(path) != dir || !isSpecFile(path)) {
+					continue
+				}
+				if ev.Op == fsnotify.Chmod {
+					continue
+				}
+				mu.Lock()
+				if t, ok := pending[path]; ok {
+					t.Reset(reloadDebounce)
+				} else {
+					pending[path] = time.AfterFunc(reloadDebounce, func() { apply(path) })
+				}
+				mu.Unlock()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"quanta/internal/telemetry"
+	"quanta/source/kafka"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const reloadSpec = `schema_version: v1
+name: clicks
+source:
+  kind: kafka
+  driver: fake
+transformers:
+  - { name: upper, type: grpc, address: "localhost:1", timeout_ms: 100 }
+sinks: [stdout]
+`
+
+func writeSpec(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write spec: %v", err)
+	}
+}
+
+func newReloadManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	kafka.Register("fake", func() kafka.Adapter { return &fakeSource{} })
+	ctx, cancel := context.WithCancel(context.Background())
+	m := NewManager(ctx)
+	t.Cleanup(func() { _ = m.Close(); cancel() })
+	path := filepath.Join(t.TempDir(), "clicks.yml")
+	writeSpec(t, path, reloadSpec)
+	if _, err := m.DeployFile(path); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	return m, path
+}
+
+func runnerOf(m *Manager, id string) any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.pipelines[id]; ok {
+		return p.runner
+	}
+	return nil
+}
+
+func TestManager_ReloadAppliesSpecChanges(t *testing.T) {
+	m, path := newReloadManager(t)
+	before := runnerOf(m, "clicks")
+	count := func(result string) float64 {
+		return testutil.ToFloat64(telemetry.PipelineReloads.WithLabelValues("clicks", result))
+	}
+	stages, restarts, rejected := count(reloadStages), count(reloadRestart), count(reloadRejected)
+
+	writeSpec(t, path, strings.Replace(reloadSpec, "timeout_ms: 100", "timeout_ms: 500", 1))
+	if err := m.Reload(path); err != nil {
+		t.Fatalf("reload stages: %v", err)
+	}
+	if runnerOf(m, "clicks") != before || count(reloadStages) != stages+1 {
+		t.Fatal("stage-only change should swap stages in place")
+	}
+
+	writeSpec(t, path, "schema_version: v1\nsource: [not, a, map\n")
+	if err := m.Reload(path); err == nil {
+		t.Fatal("invalid spec should be rejected")
+	}
+	if runnerOf(m, "clicks") != before || count(reloadRejected) != rejected+1 {
+		t.Fatal("rejected reload must leave the running pipeline alone")
+	}
+
+	writeSpec(t, path, reloadSpec+"debug: { print_counter: true }\n")
+	if err := m.Pause("clicks"); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if err := m.Reload(path); err != nil {
+		t.Fatalf("reload restart: %v", err)
+	}
+	after := runnerOf(m, "clicks")
+	if after == nil || after == before || count(reloadRestart) != restarts+1 {
+		t.Fatal("sink-side change should restart the pipeline")
+	}
+	if st, _ := m.Status(context.Background(), "clicks"); st.GetState() != StatePaused {
+		t.Fatalf("restart should keep the paused state, got %q", st.GetState())
+	}
+
+	if err := m.RemoveFile(path); err != nil || runnerOf(m, "clicks") != nil {
+		t.Fatalf("removing the file should delete the pipeline: %v", err)
+	}
+}
+
+// handOverSource calls onRun when it starts consuming.
+type handOverSource struct {
+	fakeSource
+	onRun func()
+}
+
+func (h *handOverSource) Run(ctx context.Context, emit kafka.EmitFunc) error {
+	h.onRun()
+	return h.fakeSource.Run(ctx, emit)
+}
+
+func TestManager_RestartQuiescesTheOldSourceFirst(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		sources []*handOverSource
+		started = make(chan bool, 4)
+	)
+	kafka.Register("handover", func() kafka.Adapter {
+		mu.Lock()
+		defer mu.Unlock()
+		h := &handOverSource{}
+		first := h
+		if len(sources) > 0 {
+			first = sources[0]
+		}
+		// Whether the first source was quiet when this one began.
+		h.onRun = func() { started <- first == h || first.paused.Load() || first.closed.Load() }
+		sources = append(sources, h)
+		return h
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	m := NewManager(ctx)
+	t.Cleanup(func() { _ = m.Close(); cancel() })
+	spec := strings.Replace(reloadSpec, "driver: fake", "driver: handover", 1)
+	path := filepath.Join(t.TempDir(), "clicks.yml")
+	writeSpec(t, path, spec)
+	if _, err := m.DeployFile(path); err != nil {
+		t.Fatalf("deploy clicks: %v", err)
+	}
+	if _, err := m.Deploy([]byte(strings.Replace(testSpec, "driver: fake", "driver: handover", 1))); err != nil {
+		t.Fatalf("deploy orders: %v", err)
+	}
+	wait := func() bool {
+		t.Helper()
+		select {
+		case quiet := <-started:
+			return quiet
+		case <-time.After(3 * time.Second):
+			t.Fatal("source did not start")
+			return false
+		}
+	}
+	wait()
+	wait()
+
+	// A replacement that cannot start hands the old source back unpaused.
+	writeSpec(t, path, strings.Replace(spec, "name: clicks", "name: orders", 1))
+	if err := m.Reload(path); err == nil {
+		t.Fatal("want the restart refused")
+	}
+	if sources[0].paused.Load() || sources[0].closed.Load() {
+		t.Fatal("the old pipeline must resume when its replacement cannot start")
+	}
+
+	writeSpec(t, path, spec+"debug: { print_counter: true }\n")
+	if err := m.Reload(path); err != nil {
+		t.Fatalf("reload restart: %v", err)
+	}
+	if !wait() {
+		t.Fatal("the replacement started while the old source was still fetching")
+	}
+	if !sources[0].closed.Load() {
+		t.Fatal("the old source must be closed after the restart")
+	}
+}
+
+func TestWatchPipelines_ReloadsOnWrite(t *testing.T) {
+	m, path := newReloadManager(t)
+	before := runnerOf(m, "clicks")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := watchPipelines(ctx, m, []string{path}, ""); err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+
+	writeSpec(t, path, reloadSpec+"debug: { print_counter: true }\n")
+	deadline := time.Now().Add(3 * time.Second)
+	for runnerOf(m, "clicks") == before {
+		if time.Now().After(deadline) {
+			t.Fatal("pipeline was not reloaded after the file changed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestManager_RestartKeepsOldPipelineWhenReplacementFails(t *testing.T) {
+	m, path := newReloadManager(t)
+	before := runnerOf(m, "clicks")
+	if _, err := m.Deploy([]byte(testSpec)); err != nil {
+		t.Fatalf("deploy orders: %v", err)
+	}
+
+	// Renaming clicks to a running pipeline's name cannot start.
+	writeSpec(t, path, strings.Replace(reloadSpec, "name: clicks", "name: orders", 1))
+	err := m.Reload(path)
+	if err == nil || !strings.Contains(err.Error(), "running version is kept") {
+		t.Fatalf("want the restart refused, got %v", err)
+	}
+	if runnerOf(m, "clicks") != before {
+		t.Fatal("the old pipeline must keep running when its replacement cannot start")
+	}
+	if st, err := m.Status(context.Background(), "clicks"); err != nil || st.GetState() != StateRunning {
+		t.Fatalf("clicks status: %v, %v", st, err)
+	}
+}
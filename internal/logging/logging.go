@@ -11,6 +11,8 @@ import (
 type Options struct {
 	Level string
 	JSON  bool
+	// Node, when set, is attached to every record as "node".
+	Node string
 }
 
 var def atomic.Value
@@ -18,7 +20,7 @@ var def atomic.Value
 func init() {
 	cfg := &slog.HandlerOptions{Level: slog.LevelInfo}
 	h := slog.NewTextHandler(os.Stderr, cfg)
-	def.Store(slog.New(h))
+	def.Store(slog.New(Redacting(h)))
 }
 
 func Configure(opts Options) {
@@ -30,7 +32,11 @@ func Configure(opts Options) {
 	} else {
 		h = slog.NewTextHandler(os.Stderr, cfg)
 	}
-	def.Store(slog.New(h))
+	l := slog.New(Redacting(h))
+	if opts.Node != "" {
+		l = l.With("node", opts.Node)
+	}
+	def.Store(l)
 }
 
 func parseLevel(s string) slog.Level {
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"quanta/internal/secrets"
+)
+
+// Redacting wraps h so values resolved from ${ENV:...} and ${FILE:...}
+// config references never reach it: they are replaced in the message and
+// in every attribute, including errors and other values rendered as text.
+func Redacting(h slog.Handler) slog.Handler {
+	return redactHandler{h}
+}
+
+type redactHandler struct {
+	next slog.Handler
+}
+
+func (h redactHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !secrets.Active() {
+		return h.next.Handle(ctx, r)
+	}
+	out := slog.NewRecord(r.Time, r.Level, secrets.Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	red := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		red[i] = redactAttr(a)
+	}
+	return redactHandler{h.next.WithAttrs(red)}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, secrets.Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		red := make([]any, len(group))
+		for i, g := range group {
+			red[i] = redactAttr(g)
+		}
+		return slog.Group(a.Key, red...)
+	case slog.KindAny:
+		if v.Any() == nil {
+			return a
+		}
+		// Errors and structs render as text anyway; redact that text.
+		s := v.String()
+		if red := secrets.Redact(s); red != s {
+			return slog.String(a.Key, red)
+		}
+	}
+	return a
+}
@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"quanta/internal/secrets"
+)
+
+func TestRedacting_HidesResolvedSecrets(t *testing.T) {
+	t.Setenv("QUANTA_TEST_SASL_PASS", "pa55word-xyz")
+	cfg := map[string]any{"sasl_pass": "${ENV:QUANTA_TEST_SASL_PASS}"}
+	if err := secrets.ExpandAll(&cfg, ""); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	l := slog.New(Redacting(slog.NewJSONHandler(&buf, nil))).With("dsn", "user:pa55word-xyz@db")
+	l.Info("dial with pa55word-xyz",
+		"err", errors.New("auth failed for pa55word-xyz"),
+		slog.Group("kafka", "pass", "pa55word-xyz"),
+		"partition", 1)
+
+	out := buf.String()
+	if strings.Contains(out, "pa55word-xyz") {
+		t.Fatalf("secret leaked: %s", out)
+	}
+	if n := strings.Count(out, secrets.Redacted); n != 4 {
+		t.Fatalf("want 4 redactions, got %d: %s", n, out)
+	}
+	if !strings.Contains(out, `"partition":1`) {
+		t.Fatalf("non-secret attr changed: %s", out)
+	}
+}
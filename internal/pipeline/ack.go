@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"sync"
+
+	pb "quanta/api/proto/v1"
+)
+
+// ackTracker holds a source checkpoint back until every frame derived from it
+// has been acked by an ack-aware sink. Frames derived from the same source
+// record share the checkpoint pointer, which is the tracking key; pushFrame
+// holds one reference of its own while the frame is in flight so drops and
+// non-ack-aware sinks need no bookkeeping.
+type ackTracker struct {
+	mu      sync.Mutex
+	pending map[*pb.CheckpointToken]*ackRefs
+}
+
+// ackRefs counts a checkpoint's references. A failed checkpoint is never
+// acked upstream: the source redelivers the record instead.
+type ackRefs struct {
+	n      int
+	failed bool
+}
+
+func (t *ackTracker) hold(tok *pb.CheckpointToken) {
+	if tok == nil {
+		return
+	}
+	t.mu.Lock()
+	if t.pending == nil {
+		t.pending = map[*pb.CheckpointToken]*ackRefs{}
+	}
+	refs, ok := t.pending[tok]
+	if !ok {
+		refs = &ackRefs{}
+		t.pending[tok] = refs
+	}
+	refs.n++
+	t.mu.Unlock()
+}
+
+// release drops one reference and reports whether the checkpoint may be
+// acked upstream: its last reference went away and it did not fail. A
+// checkpoint that is not held, such as a second ack for the same frame, is
+// never acked. Frames without a checkpoint are not tracked and always are.
+func (t *ackTracker) release(tok *pb.CheckpointToken) bool {
+	if tok == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.drop(tok, false)
+}
+
+// forget drops one reference like release and marks the checkpoint failed,
+// so the references other branches still hold never ack it.
+func (t *ackTracker) forget(tok *pb.CheckpointToken) {
+	if tok == nil {
+		return
+	}
+	t.mu.Lock()
+	t.drop(tok, true)
+	t.mu.Unlock()
+}
+
+func (t *ackTracker) drop(tok *pb.CheckpointToken, failed bool) bool {
+	refs, ok := t.pending[tok]
+	if !ok {
+		return false
+	}
+	refs.failed = refs.failed || failed
+	if refs.n--; refs.n > 0 {
+		return false
+	}
+	delete(t.pending, tok)
+	return !refs.failed
+}
+
+func (t *ackTracker) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
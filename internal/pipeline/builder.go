@@ -0,0 +1,100 @@
+package pipeline
+
+import (
+	"errors"
+
+	"quanta/internal/logging"
+	"quanta/sink"
+	"quanta/source"
+)
+
+// Builder assembles a runner from a source, stages or a graph, and sinks,
+// and wires acknowledgements between them. The YAML compiler is one
+// front-end to it; the public engine package's pipeline builder is another.
+type Builder struct {
+	id         string
+	source     source.Source
+	supervisor SupervisorOptions
+	stages     []Stage
+	sinks      []namedSink
+	graph      *Graph
+}
+
+// NewBuilder starts a pipeline called id; an empty id is filled in when the
+// pipeline is deployed.
+func NewBuilder(id string) *Builder {
+	return &Builder{id: id, supervisor: DefaultSupervisorOptions}
+}
+
+func (b *Builder) SetSource(s source.Source)                { b.source = s }
+func (b *Builder) SetSupervisorOptions(o SupervisorOptions) { b.supervisor = o }
+func (b *Builder) Append(st Stage)                          { b.stages = append(b.stages, st) }
+func (b *Builder) SetGraph(g *Graph)                        { b.graph = g }
+
+// AddNamedSink adds a sink every frame leaving the stages is delivered to.
+func (b *Builder) AddNamedSink(name string, s sink.Adapter) {
+	b.sinks = append(b.sinks, newNamedSink(name, s))
+}
+
+// Build returns the assembled runner, not yet started. On error every part
+// added is closed.
+func (b *Builder) Build() (*Runner, error) {
+	r := NewRunner()
+	if err := b.buildInto(r); err != nil {
+		_ = r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Discard closes every part added without building.
+func (b *Builder) Discard() {
+	_ = b.runner().Close()
+	if b.graph != nil {
+		b.graph.close()
+	}
+}
+
+// runner moves the source, stages and sinks into a runner, so closing it
+// closes them.
+func (b *Builder) runner() *Runner {
+	r := NewRunner()
+	r.source = b.source
+	r.stages = b.stages
+	r.sinks = b.sinks
+	return r
+}
+
+func (b *Builder) buildInto(r *Runner) error {
+	if b.id != "" {
+		r.SetPipelineID(b.id)
+	}
+	r.source = b.source
+	r.SetSupervisorOptions(b.supervisor)
+	for _, st := range b.stages {
+		r.Append(st)
+	}
+	r.sinks = append(r.sinks, b.sinks...)
+
+	if b.graph != nil {
+		if len(b.stages) > 0 || len(b.sinks) > 0 {
+			b.graph.close()
+			return errors.New("pipeline: graph cannot be combined with stages or sinks outside it")
+		}
+		if err := r.SetGraph(b.graph); err != nil {
+			b.graph.close()
+			return err
+		}
+		logging.L().Info("pipeline graph", "pipeline", r.ID(), "graph", "\n"+b.graph.String())
+	}
+
+	if aw, ok := b.source.(source.AckAware); ok {
+		r.SubscribeAck(aw.OnAck)
+	}
+	for _, s := range r.allSinks() {
+		if ackAware, ok := s.Adapter.(sink.AckAware); ok {
+			ackAware.BindAck(r.Ack)
+		}
+	}
+	return nil
+}
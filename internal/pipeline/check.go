@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"quanta/internal/spec"
+	"quanta/internal/transform"
+)
+
+// StageCheck is the outcome of handshaking with one transformer plugin.
+type StageCheck struct {
+	Name     string
+	Protocol int32
+	Healthy  bool
+	Err      string
+}
+
+// Check compiles a spec without connecting to Kafka: the source is checked
+// as Validate does, plugins are dialled or launched, and every transformer
+// negotiates its protocol, accepts its stage config and answers Health. Everything it
+// built is closed before returning. The error covers problems with the spec
+// itself; plugin failures are reported per stage.
+func Check(ctx context.Context, cfg spec.File, confPath string) ([]StageCheck, error) {
+	if errs := validateSource(cfg, confPath); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	b := NewBuilder(cfg.Name)
+	if err := compileBody(cfg, b, newSink); err != nil {
+		b.Discard()
+		return nil, err
+	}
+	r, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var out []StageCheck
+	check := func(st *transformStage) {
+		c := StageCheck{Name: st.name}
+		defer func() { out = append(out, c) }()
+		v, err := transform.Negotiate(ctx, st.client)
+		if err != nil {
+			c.Err = fmt.Sprintf("handshake: %v", err)
+			return
+		}
+		c.Protocol = v
+		if _, err := st.prepare(ctx, cfg.Name); err != nil {
+			c.Err = fmt.Sprintf("configure: %v", err)
+			return
+		}
+		h := st.health(ctx)[0]
+		c.Healthy, c.Err = h.Healthy, h.Err
+	}
+	r.stagesMu.RLock()
+	walkStages(r.stages, check)
+	r.stagesMu.RUnlock()
+	if r.graph != nil {
+		for _, n := range r.graph.order {
+			if n.kind == nodeStage {
+				walkStages([]Stage{n.stage}, check)
+			}
+		}
+	}
+	return out, nil
+}
+
+// walkStages calls fn for every transformer, including those nested in
+// conditions and routes.
+func walkStages(stages []Stage, fn func(*transformStage)) {
+	for _, st := range stages {
+		switch s := st.(type) {
+		case *transformStage:
+			fn(s)
+		case *conditionalStage:
+			walkStages([]Stage{s.Stage}, fn)
+		case *routeStage:
+			for _, rt := range s.routes {
+				walkStages(rt.Stages, fn)
+			}
+			walkStages(s.fallback, fn)
+		}
+	}
+}
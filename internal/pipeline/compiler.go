@@ -1,23 +1,62 @@
 package pipeline
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	pb "quanta/api/proto/v1"
+	"io"
+	"strings"
 	"time"
 
 	"quanta/internal/config"
+	"quanta/internal/spec"
 	"quanta/internal/transform"
 	"quanta/sink"
+	skafka "quanta/sink/kafka"
 	"quanta/sink/stdout"
+	"quanta/source"
 	"quanta/source/kafka"
-)
 
-const supportedPipelineSchema = "v1"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+)
 
 func Compile(path string) (*Runner, error) {
 	r := NewRunner()
 	if err := LoadYAML(path, r); err != nil {
+		_ = r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// CompileBytes builds a runner from an in-memory spec, resolving relative
+// paths against baseDir.
+func CompileBytes(raw []byte, baseDir string) (*Runner, error) {
+	cfg, confPath, err := config.ParsePipelineSpec(raw, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return CompileSpec(cfg, confPath)
+}
+
+// CompileDeployed builds a runner from a spec received over the control
+// plane; see config.ParseDeployedPipelineSpec for what it may reference.
+func CompileDeployed(raw []byte, secretsDir string) (*Runner, error) {
+	cfg, err := config.ParseDeployedPipelineSpec(raw, secretsDir)
+	if err != nil {
+		return nil, err
+	}
+	return CompileSpec(cfg, "")
+}
+
+// CompileSpec builds a runner from an already parsed spec; confPath is the
+// resolved source config path.
+func CompileSpec(cfg spec.File, confPath string) (*Runner, error) {
+	r := NewRunner()
+	if err := load(cfg, confPath, r); err != nil {
+		_ = r.Close()
 		return nil, err
 	}
 	return r, nil
@@ -28,73 +67,310 @@ func LoadYAML(path string, r *Runner) error {
 	if err != nil {
 		return err
 	}
+	return load(cfg, confPath, r)
+}
 
-	if cfg.Source.Kind != "kafka" {
-		return fmt.Errorf("unsupported source %q", cfg.Source.Kind)
-	}
-	kc, err := config.LoadKafkaConfig(confPath)
+func load(cfg spec.File, confPath string, r *Runner) error {
+	src, err := newSource(cfg, confPath)
 	if err != nil {
 		return err
 	}
+	b := NewBuilder(cfg.Name)
+	b.SetSource(src)
+	b.SetSupervisorOptions(supervisorOptions(cfg))
+	if err := compileBody(cfg, b, newSink); err != nil {
+		b.Discard()
+		return err
+	}
+	return b.buildInto(r)
+}
 
+// newSource makes and configures the source a spec names: a Kafka driver
+// with the spec's Kafka config, or a registered source kind given its
+// inline config.
+func newSource(cfg spec.File, confPath string) (source.Source, error) {
+	if cfg.Source.Kind != "kafka" {
+		src, err := source.New(cfg.Source.Kind)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := src.(source.Configurable); ok {
+			if err := c.Configure(cfg.Source.Config); err != nil {
+				_ = src.Close()
+				return nil, fmt.Errorf("source %s: %w", cfg.Source.Kind, err)
+			}
+		}
+		return src, nil
+	}
+	kc, err := config.LoadSourceConfig(cfg, confPath)
+	if err != nil {
+		return nil, err
+	}
 	src, err := kafka.NewAdapter(cfg.Source.Driver)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if err = src.Configure(kc); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// sinkFactory builds the sink adapter for a sink in a spec.
+type sinkFactory func(s spec.SinkSpec) (sink.Adapter, error)
+
+// compileBody adds everything downstream of the source to b: the graph, or
+// the transformer chain and sinks made by newSink.
+func compileBody(cfg spec.File, b *Builder, newSink sinkFactory) error {
+	if cfg.Graph != nil {
+		if len(cfg.Transformers) > 0 {
+			return errors.New("pipeline: graph cannot be combined with transformers")
+		}
+		g, err := compileGraph(cfg, newSink)
+		if err != nil {
+			return err
+		}
+		b.SetGraph(g)
+		return nil
+	}
+
+	stages, err := CompileStages(cfg.Transformers)
+	if err != nil {
 		return err
 	}
-	r.SetSource(src)
+	for _, st := range stages {
+		b.Append(st)
+	}
+	for _, s := range cfg.Sinks {
+		sDrv, err := newSink(s)
+		if err != nil {
+			return err
+		}
+		b.AddNamedSink(s.Name, sDrv)
+	}
+	return nil
+}
 
-	if aw, ok := src.(interface{ OnAck(*pb.ConnectorAck) }); ok {
-		r.SubscribeAck(aw.OnAck)
+// newSink builds and configures the sink adapter for s.
+func newSink(s spec.SinkSpec) (sink.Adapter, error) {
+	sDrv, err := sink.NewAdapter(s.Type)
+	if err != nil {
+		return nil, err
+	}
+	c, err := sinkConfig(s)
+	if err != nil {
+		return nil, fmt.Errorf("sink %s: %w", s.Name, err)
 	}
+	if err := sDrv.Configure(c); err != nil {
+		return nil, fmt.Errorf("sink %s: %w", s.Name, err)
+	}
+	return sDrv, nil
+}
 
-	for _, t := range cfg.Transformers {
-		switch t.Type {
-		case "grpc":
-			cli, err := transform.NewGRPCClient(context.Background(), t.Address)
-			if err != nil {
-				return fmt.Errorf("transform %s: dial %s: %w", t.Name, t.Address, err)
+// sinkConfig decodes a sink's config block into its type's config struct,
+// rejecting unknown keys. Types without one get the block as a map.
+func sinkConfig(s spec.SinkSpec) (any, error) {
+	var err error
+	switch s.Type {
+	case "stdout":
+		var c stdout.Config
+		if err = decodeConfig(s.Config, &c); err == nil {
+			return c, nil
+		}
+	case "kafka":
+		var c skafka.Config
+		if err = decodeConfig(s.Config, &c); err == nil {
+			if len(c.Brokers) == 0 || c.Topic == "" {
+				err = errors.New("kafka sink requires brokers and topic")
+			} else {
+				return c, nil
+			}
+		}
+	default:
+		return s.Config, nil
+	}
+	return nil, fmt.Errorf("config: %w", err)
+}
+
+// decodeConfig strictly decodes a spec's config map into a typed struct.
+func decodeConfig(m map[string]any, into any) error {
+	raw, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	err = dec.Decode(into)
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		// Lines refer to the re-encoded map, not the spec; drop them.
+		msgs := make([]string, len(te.Errors))
+		for i, e := range te.Errors {
+			if _, rest, ok := strings.Cut(e, ": "); ok && strings.HasPrefix(e, "line ") {
+				e = rest
+			}
+			msgs[i] = e
+		}
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func compileGraph(cfg spec.File, newSink sinkFactory) (*Graph, error) {
+	g := NewGraph()
+	for _, n := range cfg.Graph.Nodes {
+		var err error
+		switch n.Type {
+		case "merge":
+			err = g.AddMerge(n.Name)
+		case "sink":
+			var (
+				s    spec.SinkSpec
+				sDrv sink.Adapter
+			)
+			if s, err = cfg.SinkNamed(n.SinkName()); err != nil {
+				break
+			}
+			if sDrv, err = newSink(s); err != nil {
+				break
+			}
+			if err = g.AddSink(n.Name, sDrv); err != nil {
+				_ = sDrv.Close()
 			}
-			to := time.Duration(t.TimeoutMS) * time.Millisecond
-			attempts := t.RetryPolicy.Attempts
-			backoff := time.Duration(t.RetryPolicy.BackoffMS) * time.Millisecond
-			r.AddTransformer(t.Name, cli, to, attempts, backoff)
 		default:
-			return fmt.Errorf("unsupported transformer type %q for %s", t.Type, t.Name)
+			var st Stage
+			if st, err = compileStage(n.TransformerSpec); err != nil {
+				break
+			}
+			if err = g.AddStage(st); err != nil {
+				_ = st.close()
+			}
+		}
+		if err != nil {
+			g.close()
+			return nil, fmt.Errorf("graph node %s: %w", n.Name, err)
 		}
 	}
+	for _, e := range cfg.Graph.Edges {
+		if err := g.Connect(e.From, e.To); err != nil {
+			g.close()
+			return nil, err
+		}
+	}
+	return g, nil
+}
 
-	for _, name := range cfg.Sinks {
-		sDrv, err := sink.NewAdapter(name)
+// CompileStages builds a linear transformer chain; on error every stage built
+// so far is closed.
+func CompileStages(specs []spec.TransformerSpec) ([]Stage, error) {
+	stages := make([]Stage, 0, len(specs))
+	for _, t := range specs {
+		st, err := compileStage(t)
 		if err != nil {
-			return err
+			closeStages(stages)
+			return nil, err
 		}
+		stages = append(stages, st)
+	}
+	return stages, nil
+}
 
-		switch name {
-		case "stdout":
-			delay := time.Duration(cfg.Debug.PerFrameDelayMS) * time.Millisecond
-			err = sDrv.Configure(stdout.Config{
-				DelayMS:       int(delay / time.Millisecond),
-				PrintCounter:  cfg.Debug.PrintCounter,
-				BatchSize:     cfg.Debug.AckBatchSize,
-				FlushMS:       cfg.Debug.AckFlushMS,
-				PrintValue:    cfg.Debug.PrintValue,
-				ValueMaxBytes: cfg.Debug.ValueMaxBytes,
-			})
+func compileStage(t spec.TransformerSpec) (Stage, error) {
+	when, err := CompilePredicate(t.When)
+	if err != nil {
+		return nil, fmt.Errorf("transform %s: when: %w", t.Name, err)
+	}
+	if t.Type == "route" {
+		return compileRoute(t, when)
+	}
 
-		default:
-			err = fmt.Errorf("no config block for sink %q", name)
+	var cli transform.Client
+	switch t.Type {
+	case "grpc":
+		gc, err := transform.SharedPool.Get(context.Background(), t.Address)
+		if err != nil {
+			return nil, fmt.Errorf("transform %s: dial %s: %w", t.Name, t.Address, err)
 		}
+		cli = gc
+	case "inproc":
+		if cli, err = transform.NewInProcess(t.InProcessName()); err != nil {
+			return nil, fmt.Errorf("transform %s: %w", t.Name, err)
+		}
+	case "exec":
+		if t.Command == "" {
+			return nil, fmt.Errorf("transform %s: exec requires command", t.Name)
+		}
+		ec, err := transform.NewExecClient(context.Background(), t.Command, t.Args)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("transform %s: %w", t.Name, err)
 		}
+		cli = ec
+	default:
+		return nil, fmt.Errorf("unsupported transformer type %q for %s", t.Type, t.Name)
+	}
+	var stageCfg *structpb.Struct
+	if len(t.Config) > 0 {
+		if stageCfg, err = structpb.NewStruct(t.Config); err != nil {
+			_ = cli.Close()
+			return nil, fmt.Errorf("transform %s: config: %w", t.Name, err)
+		}
+	}
+	return NewTransformStage(t.Name, cli, StageOptions{
+		Timeout:       time.Duration(t.TimeoutMS) * time.Millisecond,
+		RetryAttempts: t.RetryPolicy.Attempts,
+		RetryBackoff:  time.Duration(t.RetryPolicy.BackoffMS) * time.Millisecond,
+		Config:        stageCfg,
+		When:          when,
+	}), nil
+}
 
-		if ackAware, ok := sDrv.(sink.AckAware); ok {
-			ackAware.BindAck(r.Ack)
+func compileRoute(t spec.TransformerSpec, when Predicate) (Stage, error) {
+	if len(t.Routes) == 0 {
+		return nil, fmt.Errorf("route %s: at least one route is required", t.Name)
+	}
+	var routes []Route
+	cleanup := func() {
+		for _, rt := range routes {
+			closeStages(rt.Stages)
 		}
-		r.AddSink(sDrv)
 	}
-	return nil
+	for i, rs := range t.Routes {
+		if rs.Name == "" {
+			cleanup()
+			return nil, fmt.Errorf("route %s: route %d has no name", t.Name, i)
+		}
+		pred, err := CompilePredicate(rs.When)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("route %s/%s: when: %w", t.Name, rs.Name, err)
+		}
+		stages, err := CompileStages(rs.Transformers)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("route %s/%s: %w", t.Name, rs.Name, err)
+		}
+		routes = append(routes, Route{Name: rs.Name, When: pred, Stages: stages})
+	}
+	fallback, err := CompileStages(t.Default)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("route %s/default: %w", t.Name, err)
+	}
+	return NewRouteStage(t.Name, routes, fallback, when), nil
+}
+
+func supervisorOptions(cfg spec.File) SupervisorOptions {
+	o := DefaultSupervisorOptions
+	rs := cfg.Source.Restart
+	if rs.InitialBackoffMS > 0 {
+		o.InitialBackoff = time.Duration(rs.InitialBackoffMS) * time.Millisecond
+	}
+	if rs.MaxBackoffMS > 0 {
+		o.MaxBackoff = time.Duration(rs.MaxBackoffMS) * time.Millisecond
+	}
+	o.MaxRestarts = rs.MaxRestarts
+	return o
 }
@@ -0,0 +1,173 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/spec"
+	"quanta/sink"
+
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is one input frame of a dry run, as written in a fixtures file.
+type Fixture struct {
+	Key       string            `yaml:"key"`
+	Value     string            `yaml:"value"`
+	Headers   map[string]string `yaml:"headers"`
+	Topic     string            `yaml:"topic"`
+	Partition int32             `yaml:"partition"`
+	// Offset defaults to the fixture's position in the file.
+	Offset *int64 `yaml:"offset"`
+}
+
+// LoadFixtures reads a YAML list of fixtures and turns them into frames
+// carrying Kafka checkpoints. Unknown fields are rejected.
+func LoadFixtures(path string) ([]*pb.Frame, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fx []Fixture
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&fx); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	frames := make([]*pb.Frame, 0, len(fx))
+	for i, x := range fx {
+		off := int64(i)
+		if x.Offset != nil {
+			off = *x.Offset
+		}
+		topic := x.Topic
+		if topic == "" {
+			topic = "fixtures"
+		}
+		f := &pb.Frame{
+			Value: []byte(x.Value),
+			Checkpoint: &pb.CheckpointToken{Kind: &pb.CheckpointToken_Kafka{Kafka: &pb.KafkaOffset{
+				Topic: topic, Partition: x.Partition, Offset: off,
+			}}},
+		}
+		if x.Key != "" {
+			f.Key = []byte(x.Key)
+		}
+		for k, v := range x.Headers {
+			if f.Headers == nil {
+				f.Headers = map[string][]byte{}
+			}
+			f.Headers[k] = []byte(v)
+		}
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
+
+// SinkFrame is a frame as handed to the named sink.
+type SinkFrame struct {
+	Sink  string
+	Frame *pb.Frame
+}
+
+// DryRunResult is what became of one input frame. A frame with no outputs
+// and no error was dropped by a stage or routed nowhere.
+type DryRunResult struct {
+	Input   *pb.Frame
+	Outputs []SinkFrame
+	// Acked reports whether the frame's checkpoint was released upstream,
+	// which is what would commit its offset.
+	Acked bool
+	Err   error
+}
+
+// DryRun compiles the stages of a spec, replaces every sink with an
+// in-memory one and pushes frames through, one at a time. The source is
+// never configured, so no Kafka is needed; transformer plugins are dialled
+// or launched as in a real run. Frames without a checkpoint get one.
+func DryRun(ctx context.Context, cfg spec.File, frames []*pb.Frame) ([]DryRunResult, error) {
+	var (
+		mu  sync.Mutex
+		got []SinkFrame
+	)
+	memory := func(s spec.SinkSpec) (sink.Adapter, error) {
+		if _, err := sink.NewAdapter(s.Type); err != nil {
+			return nil, err
+		}
+		return &memorySink{name: s.Name, mu: &mu, out: &got}, nil
+	}
+	b := NewBuilder(cfg.Name)
+	if err := compileBody(cfg, b, memory); err != nil {
+		b.Discard()
+		return nil, err
+	}
+	r, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	acked := map[*pb.CheckpointToken]bool{}
+	r.SubscribeAck(func(a *pb.ConnectorAck) {
+		mu.Lock()
+		acked[a.Checkpoint] = true
+		mu.Unlock()
+	})
+
+	out := make([]DryRunResult, 0, len(frames))
+	for i, f := range frames {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		if f.Checkpoint == nil {
+			f.Checkpoint = &pb.CheckpointToken{Kind: &pb.CheckpointToken_Kafka{Kafka: &pb.KafkaOffset{Topic: "fixtures", Offset: int64(i)}}}
+		}
+		res := DryRunResult{Input: proto.Clone(f).(*pb.Frame)}
+		mu.Lock()
+		before := len(got)
+		mu.Unlock()
+		// Stage failures drop the frame and surface as the runner's last
+		// error rather than from pushFrame.
+		prev := r.LastError()
+		res.Err = r.pushFrame(f)
+		if last := r.LastError(); res.Err == nil && last != prev {
+			res.Err = last
+		}
+		mu.Lock()
+		res.Outputs = append([]SinkFrame(nil), got[before:]...)
+		res.Acked = acked[f.Checkpoint]
+		mu.Unlock()
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// memorySink records frames instead of delivering them and acks each one
+// straight away.
+type memorySink struct {
+	name string
+	ack  sink.EmitFn
+	mu   *sync.Mutex
+	out  *[]SinkFrame
+}
+
+func (m *memorySink) Configure(any) error    { return nil }
+func (m *memorySink) Close() error           { return nil }
+func (m *memorySink) BindAck(fn sink.EmitFn) { m.ack = fn }
+func (m *memorySink) Push(f *pb.Frame) error {
+	if m.out != nil {
+		m.mu.Lock()
+		*m.out = append(*m.out, SinkFrame{Sink: m.name, Frame: proto.Clone(f).(*pb.Frame)})
+		m.mu.Unlock()
+	}
+	if m.ack != nil {
+		m.ack(f.Checkpoint)
+	}
+	return nil
+}
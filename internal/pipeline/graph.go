@@ -0,0 +1,350 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/logging"
+	"quanta/sink"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// SourceNode is the implicit entry node of every graph.
+const SourceNode = "source"
+
+type nodeKind int
+
+const (
+	nodeSource nodeKind = iota
+	nodeStage
+	nodeMerge
+	nodeSink
+)
+
+func (k nodeKind) String() string {
+	switch k {
+	case nodeSource:
+		return "source"
+	case nodeStage:
+		return "stage"
+	case nodeMerge:
+		return "merge"
+	default:
+		return "sink"
+	}
+}
+
+type graphNode struct {
+	name  string
+	kind  nodeKind
+	stage Stage
+	sink  namedSink
+	in    []*graphNode
+	out   []*graphNode
+	level int
+	// sinks names the sink nodes downstream of this one; a frame routed
+	// to any other sink has nowhere to go.
+	sinks map[string]bool
+}
+
+// Graph is a DAG of stages, merge points and sinks hanging off the source.
+// Each frame walks the graph level by level; nodes on the same level run
+// concurrently and every outgoing edge receives its own copy of the frames.
+type Graph struct {
+	nodes  map[string]*graphNode
+	order  []*graphNode
+	levels [][]*graphNode
+}
+
+func NewGraph() *Graph {
+	g := &Graph{nodes: map[string]*graphNode{}}
+	_ = g.add(&graphNode{name: SourceNode, kind: nodeSource})
+	return g
+}
+
+func (g *Graph) add(n *graphNode) error {
+	if n.name == "" {
+		return errors.New("graph: node name is required")
+	}
+	if _, dup := g.nodes[n.name]; dup {
+		return fmt.Errorf("graph: duplicate node %q", n.name)
+	}
+	g.nodes[n.name] = n
+	g.order = append(g.order, n)
+	g.levels = nil
+	return nil
+}
+
+func (g *Graph) AddStage(st Stage) error {
+	return g.add(&graphNode{name: st.Name(), kind: nodeStage, stage: st})
+}
+
+func (g *Graph) AddMerge(name string) error {
+	return g.add(&graphNode{name: name, kind: nodeMerge})
+}
+
+func (g *Graph) AddSink(name string, s sink.Adapter) error {
+	return g.add(&graphNode{name: name, kind: nodeSink, sink: newNamedSink(name, s)})
+}
+
+func (g *Graph) Connect(from, to string) error {
+	src, ok := g.nodes[from]
+	if !ok {
+		return fmt.Errorf("graph: edge %s -> %s: unknown node %q", from, to, from)
+	}
+	dst, ok := g.nodes[to]
+	if !ok {
+		return fmt.Errorf("graph: edge %s -> %s: unknown node %q", from, to, to)
+	}
+	switch {
+	case src.kind == nodeSink:
+		return fmt.Errorf("graph: edge %s -> %s: sink %q cannot have outgoing edges", from, to, from)
+	case dst.kind == nodeSource:
+		return fmt.Errorf("graph: edge %s -> %s: source cannot have incoming edges", from, to)
+	}
+	for _, n := range src.out {
+		if n == dst {
+			return fmt.Errorf("graph: duplicate edge %s -> %s", from, to)
+		}
+	}
+	src.out = append(src.out, dst)
+	dst.in = append(dst.in, src)
+	g.levels = nil
+	return nil
+}
+
+// Validate rejects cycles, nodes the source cannot reach, non-sink nodes
+// with nowhere to send frames and graphs without sinks, and computes the
+// execution levels.
+func (g *Graph) Validate() error {
+	reached := map[*graphNode]bool{}
+	stack := []*graphNode{g.nodes[SourceNode]}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reached[n] {
+			continue
+		}
+		reached[n] = true
+		stack = append(stack, n.out...)
+	}
+
+	sinks := 0
+	for _, n := range g.order {
+		if !reached[n] {
+			return fmt.Errorf("graph: node %q is unreachable from %s", n.name, SourceNode)
+		}
+		if n.kind == nodeSink {
+			sinks++
+		} else if len(n.out) == 0 {
+			return fmt.Errorf("graph: node %q has no outgoing edges", n.name)
+		}
+	}
+	if sinks == 0 {
+		return errors.New("graph: no sink nodes")
+	}
+
+	indeg := make(map[*graphNode]int, len(g.order))
+	for _, n := range g.order {
+		indeg[n] = len(n.in)
+		n.level = 0
+	}
+	var levels [][]*graphNode
+	ready := []*graphNode{g.nodes[SourceNode]}
+	done := 0
+	for len(ready) > 0 {
+		levels = append(levels, ready)
+		done += len(ready)
+		var next []*graphNode
+		for _, n := range ready {
+			for _, m := range n.out {
+				if m.level < n.level+1 {
+					m.level = n.level + 1
+				}
+				if indeg[m]--; indeg[m] == 0 {
+					next = append(next, m)
+				}
+			}
+		}
+		ready = next
+	}
+	if done != len(g.order) {
+		var cyc []string
+		for _, n := range g.order {
+			if indeg[n] > 0 {
+				cyc = append(cyc, n.name)
+			}
+		}
+		sort.Strings(cyc)
+		return fmt.Errorf("graph: cycle through %s", strings.Join(cyc, ", "))
+	}
+	for i := len(levels) - 1; i >= 0; i-- {
+		for _, n := range levels[i] {
+			n.sinks = map[string]bool{}
+			for _, m := range n.out {
+				if m.kind == nodeSink {
+					n.sinks[m.name] = true
+				}
+				for s := range m.sinks {
+					n.sinks[s] = true
+				}
+			}
+		}
+	}
+	g.levels = levels
+	return nil
+}
+
+// String renders the graph one node per line in execution order, prefixed
+// with its level; nodes sharing a level run in parallel.
+func (g *Graph) String() string {
+	levels := g.levels
+	if levels == nil {
+		levels = [][]*graphNode{g.order}
+	}
+	var b strings.Builder
+	for _, level := range levels {
+		for _, n := range level {
+			fmt.Fprintf(&b, "[%d] %s", n.level, n.name)
+			if n.kind != nodeSource {
+				fmt.Fprintf(&b, " (%s)", n.kind)
+			}
+			if len(n.out) > 0 {
+				names := make([]string, len(n.out))
+				for i, m := range n.out {
+					names[i] = m.name
+				}
+				fmt.Fprintf(&b, " -> %s", strings.Join(names, ", "))
+			}
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func (g *Graph) run(ctx context.Context, r *Runner, f *pb.Frame) error {
+	if g.levels == nil {
+		return errors.New("graph: not validated")
+	}
+	inbox := map[*graphNode][]*pb.Frame{g.nodes[SourceNode]: {f}}
+	for _, level := range g.levels {
+		outs := make([][]*pb.Frame, len(level))
+		errs := make([]error, len(level))
+		var wg sync.WaitGroup
+		for i, n := range level {
+			in := inbox[n]
+			if len(in) == 0 {
+				continue
+			}
+			if len(level) == 1 {
+				outs[i], errs[i] = n.process(ctx, r, in)
+				continue
+			}
+			wg.Add(1)
+			go func(i int, n *graphNode) {
+				defer wg.Done()
+				defer func() {
+					if p := recover(); p != nil {
+						errs[i] = fmt.Errorf("node %s: panic: %v", n.name, p)
+					}
+				}()
+				outs[i], errs[i] = n.process(ctx, r, in)
+			}(i, n)
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+
+		for i, n := range level {
+			if len(n.out) > 0 {
+				outs[i] = routable(r, n, outs[i])
+			}
+			for j, m := range n.out {
+				frames := outs[i]
+				if j > 0 {
+					frames = cloneFrames(frames)
+				}
+				inbox[m] = append(inbox[m], frames...)
+			}
+		}
+	}
+	return nil
+}
+
+// routable drops the frames leaving n whose route names a sink that is not
+// downstream of n, as the linear chain does for unknown sinks.
+func routable(r *Runner, n *graphNode, frames []*pb.Frame) []*pb.Frame {
+	out := frames[:0]
+	for _, f := range frames {
+		rs := f.GetRoute().GetSink()
+		if rs == "" || n.sinks[rs] {
+			out = append(out, f)
+			continue
+		}
+		logging.L().Warn("frame routed to a sink it cannot reach; dropping", "sink", rs, "node", n.name)
+		d := Drop{Frame: f, Cause: DropUnrouted, Reason: fmt.Sprintf("sink %s is not downstream of %s", rs, n.name)}
+		if n.kind == nodeStage {
+			d.Stage = n.name
+		}
+		r.dropped(d)
+	}
+	return out
+}
+
+func (n *graphNode) process(ctx context.Context, r *Runner, in []*pb.Frame) ([]*pb.Frame, error) {
+	switch n.kind {
+	case nodeStage:
+		return runChain(ctx, r, []Stage{n.stage}, in)
+	case nodeSink:
+		for _, f := range in {
+			if rs := f.GetRoute().GetSink(); rs != "" && rs != n.name {
+				continue
+			}
+			if err := r.pushSink(ctx, n.sink, f); err != nil {
+				return nil, fmt.Errorf("sink %s: %w", n.name, err)
+			}
+		}
+		return nil, nil
+	default:
+		return in, nil
+	}
+}
+
+func (g *Graph) close() {
+	for _, n := range g.order {
+		switch n.kind {
+		case nodeStage:
+			_ = n.stage.close()
+		case nodeSink:
+			_ = n.sink.Close()
+		}
+	}
+}
+
+// cloneFrames copies frames for an extra outgoing edge. The checkpoint
+// pointer is shared so acks from every branch count against the same
+// source record.
+func cloneFrames(in []*pb.Frame) []*pb.Frame {
+	out := make([]*pb.Frame, len(in))
+	for i, f := range in {
+		c := proto.Clone(f).(*pb.Frame)
+		c.Checkpoint = f.Checkpoint
+		out[i] = c
+	}
+	return out
+}
+
+func (r *Runner) SetGraph(g *Graph) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	r.graph = g
+	return nil
+}
@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+
+	pb "quanta/api/proto/v1"
+)
+
+// heldSink records frames and acks them only when flush is called.
+type heldSink struct {
+	captureSink
+	held []*pb.CheckpointToken
+}
+
+func (h *heldSink) Push(f *pb.Frame) error {
+	h.pushed = append(h.pushed, f)
+	h.held = append(h.held, f.Checkpoint)
+	return nil
+}
+
+func (h *heldSink) flush() {
+	for _, tok := range h.held {
+		h.ackFn(tok)
+	}
+	h.held = nil
+}
+
+func TestGraph_Validate(t *testing.T) {
+	cases := []struct {
+		name  string
+		edges [][2]string
+		want  string
+	}{
+		{"cycle", [][2]string{{"source", "a"}, {"a", "b"}, {"b", "a"}, {"b", "out"}}, "cycle through a, b"},
+		{"unreachable", [][2]string{{"source", "a"}, {"a", "out"}, {"b", "out"}}, `"b" is unreachable`},
+		{"dead end", [][2]string{{"source", "a"}, {"source", "b"}, {"a", "out"}}, `"b" has no outgoing edges`},
+	}
+	for _, tc := range cases {
+		g := NewGraph()
+		_ = g.AddStage(NewTransformStage("a", &fakeTransform{}, StageOptions{}))
+		_ = g.AddStage(NewTransformStage("b", &fakeTransform{}, StageOptions{}))
+		_ = g.AddSink("out", &captureSink{})
+		for _, e := range tc.edges {
+			if err := g.Connect(e[0], e[1]); err != nil {
+				t.Fatalf("%s: connect: %v", tc.name, err)
+			}
+		}
+		err := g.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: want error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+
+	g := NewGraph()
+	_ = g.AddSink("out", &captureSink{})
+	if err := g.Connect("out", "source"); err == nil {
+		t.Fatal("edges out of a sink must be rejected")
+	}
+	if err := g.Connect("source", "missing"); err == nil {
+		t.Fatal("edges to unknown nodes must be rejected")
+	}
+}
+
+func TestGraph_BranchesMergeAndAggregateAcks(t *testing.T) {
+	r := NewRunner()
+	var acks int
+	r.SubscribeAck(func(*pb.ConnectorAck) { acks++ })
+
+	left := &tagTransform{tag: "L"}
+	right := &tagTransform{tag: "R"}
+	audit := &heldSink{}
+	audit.BindAck(r.Ack)
+	main := &heldSink{}
+	main.BindAck(r.Ack)
+
+	g := NewGraph()
+	for _, err := range []error{
+		g.AddStage(NewTransformStage("left", left, StageOptions{})),
+		g.AddStage(NewTransformStage("right", right, StageOptions{})),
+		g.AddMerge("join"),
+		g.AddSink("audit", audit),
+		g.AddSink("main", main),
+		g.Connect(SourceNode, "left"),
+		g.Connect(SourceNode, "right"),
+		g.Connect(SourceNode, "audit"),
+		g.Connect("left", "join"),
+		g.Connect("right", "join"),
+		g.Connect("join", "main"),
+	} {
+		if err != nil {
+			t.Fatalf("build graph: %v", err)
+		}
+	}
+	if err := r.SetGraph(g); err != nil {
+		t.Fatalf("SetGraph: %v", err)
+	}
+	if !strings.Contains(g.String(), "[1] left (stage) -> join") || !strings.Contains(g.String(), "[2] join (merge) -> main") {
+		t.Fatalf("unexpected graph rendering:\n%s", g)
+	}
+
+	if err := r.pushFrame(makeFrame()); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+	if len(audit.pushed) != 1 || string(audit.pushed[0].Value) != "hello" {
+		t.Fatalf("audit branch should see the raw frame: %+v", audit.pushed)
+	}
+	if len(main.pushed) != 2 || string(main.pushed[0].Value) != "L:hello" || string(main.pushed[1].Value) != "R:hello" {
+		t.Fatalf("merge should deliver both branches in edge order: %+v", main.pushed)
+	}
+
+	audit.flush()
+	if acks != 0 {
+		t.Fatalf("source acked before every branch finished")
+	}
+	main.flush()
+	if acks != 1 {
+		t.Fatalf("want exactly one source ack after all branches, got %d", acks)
+	}
+}
+
+func TestGraph_ReportsFramesRoutedToUnreachableSinks(t *testing.T) {
+	r := NewRunner()
+	var acks int
+	r.SubscribeAck(func(*pb.ConnectorAck) { acks++ })
+	var drops []Drop
+	r.SubscribeDrop(func(d Drop) { drops = append(drops, d) })
+
+	main, audit := &captureSink{}, &captureSink{}
+	main.BindAck(r.Ack)
+	audit.BindAck(r.Ack)
+	g := NewGraph()
+	for _, err := range []error{
+		g.AddStage(NewTransformStage("t1", &v2Transform{}, StageOptions{})),
+		g.AddSink("main", main),
+		g.AddSink("audit", audit),
+		g.Connect(SourceNode, "t1"),
+		g.Connect(SourceNode, "audit"),
+		g.Connect("t1", "main"),
+	} {
+		if err != nil {
+			t.Fatalf("build graph: %v", err)
+		}
+	}
+	if err := r.SetGraph(g); err != nil {
+		t.Fatalf("SetGraph: %v", err)
+	}
+
+	if err := r.pushFrame(makeFrame()); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+	if len(main.pushed) != 1 || len(audit.pushed) != 1 || audit.pushed[0].GetRoute() != nil {
+		t.Fatalf("want the unrouted frame on main and the source frame on audit: main=%d audit=%d", len(main.pushed), len(audit.pushed))
+	}
+	if len(drops) != 1 || drops[0].Cause != DropUnrouted || drops[0].Stage != "t1" || !strings.Contains(drops[0].Reason, "audit") {
+		t.Fatalf("want one unrouted drop from t1, got %+v", drops)
+	}
+	if acks != 1 {
+		t.Fatalf("want the source record acked once, got %d", acks)
+	}
+}
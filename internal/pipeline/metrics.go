@@ -0,0 +1,181 @@
+package pipeline
+
+import (
+	"strconv"
+	"sync"
+
+	"quanta/internal/telemetry"
+	"quanta/source/kafka"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// running holds every started runner so the collectors below can read
+// source state at scrape time instead of polling it. Each runner keeps the
+// sequence it was started at: a replacement runs under its predecessor's
+// id while that one drains, and only the newer one is reported.
+var running = struct {
+	sync.Mutex
+	seq     uint64
+	runners map[*Runner]uint64
+}{runners: map[*Runner]uint64{}}
+
+func trackRunner(r *Runner) {
+	running.Lock()
+	running.seq++
+	running.runners[r] = running.seq
+	running.Unlock()
+}
+
+func untrackRunner(r *Runner) {
+	running.Lock()
+	delete(running.runners, r)
+	running.Unlock()
+}
+
+// runningRunners returns the most recently started runner of every
+// pipeline id.
+func runningRunners() []*Runner {
+	running.Lock()
+	defer running.Unlock()
+	newest := map[string]*Runner{}
+	for r, seq := range running.runners {
+		if cur, ok := newest[r.pipelineID]; !ok || running.runners[cur] < seq {
+			newest[r.pipelineID] = r
+		}
+	}
+	out := make([]*Runner, 0, len(newest))
+	for _, r := range newest {
+		out = append(out, r)
+	}
+	return out
+}
+
+var (
+	sourceLagDesc = prometheus.NewDesc("quanta_source_lag",
+		"Consumer lag per claimed partition.", []string{"pipeline", "topic", "partition"}, nil)
+	sourceCommittedDesc = prometheus.NewDesc("quanta_source_committed_offset",
+		"Next offset marked for commit per claimed partition.", []string{"pipeline", "topic", "partition"}, nil)
+	sourceInFlightDesc = prometheus.NewDesc("quanta_source_in_flight",
+		"Records emitted by the source and not yet acknowledged.", []string{"pipeline"}, nil)
+	backpressureDesc = prometheus.NewDesc("quanta_backpressure_tokens_available",
+		"Fetch tokens the source can still acquire.", []string{"pipeline"}, nil)
+	checkpointPendingDesc = prometheus.NewDesc("quanta_checkpoint_pending",
+		"Records tracked by the checkpoint manager and not yet resolved.", []string{"pipeline"}, nil)
+)
+
+// sourceCollector exports SourceStats for every running pipeline.
+type sourceCollector struct{}
+
+func (sourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sourceLagDesc
+	ch <- sourceCommittedDesc
+	ch <- sourceInFlightDesc
+	ch <- backpressureDesc
+	ch <- checkpointPendingDesc
+}
+
+func (sourceCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, r := range runningRunners() {
+		if _, ok := r.source.(kafka.StatsReporter); !ok {
+			continue
+		}
+		st := r.SourceStats()
+		id := r.pipelineID
+		ch <- prometheus.MustNewConstMetric(sourceInFlightDesc, prometheus.GaugeValue, float64(st.InFlight), id)
+		ch <- prometheus.MustNewConstMetric(backpressureDesc, prometheus.GaugeValue, float64(st.BackpressureAvailable), id)
+		ch <- prometheus.MustNewConstMetric(checkpointPendingDesc, prometheus.GaugeValue, float64(st.CheckpointPending), id)
+		for _, p := range st.Partitions {
+			part := strconv.Itoa(int(p.Partition))
+			ch <- prometheus.MustNewConstMetric(sourceLagDesc, prometheus.GaugeValue, float64(p.Lag), id, p.Topic, part)
+			ch <- prometheus.MustNewConstMetric(sourceCommittedDesc, prometheus.GaugeValue, float64(p.Committed), id, p.Topic, part)
+		}
+	}
+}
+
+// clientCollector bridges each source's client metrics, e.g. Sarama's
+// go-metrics registry. The metric set depends on the client, so it is an
+// unchecked collector.
+type clientCollector struct{}
+
+func (clientCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (clientCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, r := range runningRunners() {
+		if mr, ok := r.source.(kafka.MetricsReporter); ok && mr.Metrics() != nil {
+			telemetry.CollectGoMetrics(mr.Metrics(), "quanta_sarama_", r.pipelineID, ch)
+		}
+	}
+}
+
+// Collectors returns the collectors reporting running pipelines' source
+// and client library metrics.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{sourceCollector{}, clientCollector{}}
+}
+
+func init() {
+	prometheus.MustRegister(Collectors()...)
+}
+
+// StaleMetrics returns a func that deletes the series old recorded that
+// next does not carry on: all of them when next is nil or runs under
+// another id, otherwise those of the stages and sinks next no longer has.
+// Take it before old is closed, which forgets its stages, and call it once
+// old has drained so no frame records a series afterwards.
+func StaleMetrics(old, next *Runner) func() {
+	id := old.ID()
+	if next == nil || next.ID() != id {
+		return func() { telemetry.DeletePipeline(id) }
+	}
+	oldStages, oldSinks := old.metricNames()
+	stages, sinks := next.metricNames()
+	return func() {
+		for name := range oldStages {
+			if !stages[name] {
+				telemetry.DeleteStage(id, name)
+			}
+		}
+		for name := range oldSinks {
+			if !sinks[name] {
+				telemetry.DeleteSink(id, name)
+			}
+		}
+	}
+}
+
+// metricNames lists the stage and sink labels r records series under,
+// including stages nested in routes and graph nodes.
+func (r *Runner) metricNames() (stages, sinks map[string]bool) {
+	stages, sinks = map[string]bool{}, map[string]bool{}
+	r.stagesMu.RLock()
+	stageNames(r.stages, stages)
+	r.stagesMu.RUnlock()
+	if r.graph != nil {
+		for _, n := range r.graph.order {
+			if n.kind == nodeStage {
+				stageNames([]Stage{n.stage}, stages)
+			}
+		}
+	}
+	for _, s := range r.allSinks() {
+		sinks[s.name] = true
+	}
+	return stages, sinks
+}
+
+func stageNames(stages []Stage, into map[string]bool) {
+	for _, st := range stages {
+		switch st := st.(type) {
+		case *conditionalStage:
+			stageNames([]Stage{st.Stage}, into)
+		case *routeStage:
+			for _, rt := range st.routes {
+				stageNames(rt.Stages, into)
+			}
+			stageNames(st.fallback, into)
+		default:
+			into[st.Name()] = true
+		}
+	}
+}
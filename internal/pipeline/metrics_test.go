@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"quanta/internal/telemetry"
+	"quanta/source/kafka"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rcrowley/go-metrics"
+)
+
+func TestRunner_RecordsFramePathMetrics(t *testing.T) {
+	r := NewRunner()
+	r.SetPipelineID("metrics-frames")
+	r.AddTransformer("fan", &fakeTransform{mode: "fanout2"}, 100*time.Millisecond, 0, 0)
+	r.AddTransformer("retry", &fakeTransform{mode: "errorThenOK"}, 100*time.Millisecond, 1, 0)
+	s := &captureSink{}
+	s.BindAck(r.Ack)
+	r.AddNamedSink("out", s)
+
+	if err := r.pushFrame(makeFrame()); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+
+	checks := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"frames in", testutil.ToFloat64(telemetry.FramesIn.WithLabelValues("metrics-frames")), 1},
+		{"fan in", testutil.ToFloat64(telemetry.StageFramesIn.WithLabelValues("metrics-frames", "fan")), 1},
+		{"fan out", testutil.ToFloat64(telemetry.StageFramesOut.WithLabelValues("metrics-frames", "fan")), 2},
+		{"retries", testutil.ToFloat64(telemetry.StageRetries.WithLabelValues("metrics-frames", "retry")), 1},
+		{"frames out", testutil.ToFloat64(telemetry.FramesOut.WithLabelValues("metrics-frames", "out")), 2},
+		{"acks", testutil.ToFloat64(telemetry.Acks.WithLabelValues("metrics-frames")), 1},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+type statsSource struct {
+	drainSource
+	reg metrics.Registry
+}
+
+func (s *statsSource) Stats() kafka.Stats {
+	return kafka.Stats{
+		InFlight: 3, BackpressureAvailable: 97, CheckpointPending: 3,
+		Partitions: []kafka.PartitionStats{{Topic: "orders", Partition: 0, Lag: 12, Committed: 40}},
+	}
+}
+func (s *statsSource) Metrics() metrics.Registry { return s.reg }
+
+func TestCollectors_ExportSourceStatsAndClientMetrics(t *testing.T) {
+	var events []string
+	reg := metrics.NewRegistry()
+	metrics.GetOrRegisterMeter("incoming-byte-rate-for-broker-1", reg).Mark(10)
+	metrics.GetOrRegisterHistogram("batch-size-for-topic-orders", reg, metrics.NewUniformSample(10)).Update(4)
+
+	r := NewRunner()
+	r.SetPipelineID("metrics-source")
+	r.SetSource(&statsSource{drainSource: drainSource{events: &events}, reg: reg})
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer r.Close()
+
+	want := `
+# HELP quanta_source_lag Consumer lag per claimed partition.
+# TYPE quanta_source_lag gauge
+quanta_source_lag{partition="0",pipeline="metrics-source",topic="orders"} 12
+# HELP quanta_backpressure_tokens_available Fetch tokens the source can still acquire.
+# TYPE quanta_backpressure_tokens_available gauge
+quanta_backpressure_tokens_available{pipeline="metrics-source"} 97
+`
+	if err := testutil.CollectAndCompare(sourceCollector{}, strings.NewReader(want),
+		"quanta_source_lag", "quanta_backpressure_tokens_available"); err != nil {
+		t.Fatal(err)
+	}
+
+	want = `
+# HELP quanta_sarama_incoming_byte_rate_total Sarama metric incoming-byte-rate.
+# TYPE quanta_sarama_incoming_byte_rate_total counter
+quanta_sarama_incoming_byte_rate_total{broker="1",pipeline="metrics-source",topic=""} 10
+`
+	if err := testutil.CollectAndCompare(clientCollector{}, strings.NewReader(want),
+		"quanta_sarama_incoming_byte_rate_total"); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(clientCollector{}, "quanta_sarama_batch_size"); n != 1 {
+		t.Fatalf("want batch size summary, got %d series", n)
+	}
+}
+
+func TestStaleMetrics_DeletesSeriesThePipelineNoLongerHas(t *testing.T) {
+	const id = "metrics-forget"
+	old := NewRunner()
+	old.SetPipelineID(id)
+	old.AddTransformer("keep", &fakeTransform{}, time.Second, 0, 0)
+	old.Append(NewRouteStage("split", []Route{{Name: "a", Stages: []Stage{
+		NewTransformStage("nested", &fakeTransform{}, StageOptions{Timeout: time.Second}),
+	}}}, nil, nil))
+	old.AddNamedSink("out", &captureSink{})
+	old.AddNamedSink("old-out", &captureSink{})
+
+	next := NewRunner()
+	next.SetPipelineID(id)
+	next.AddTransformer("keep", &fakeTransform{}, time.Second, 0, 0)
+	next.AddNamedSink("out", &captureSink{})
+
+	record := func() {
+		telemetry.FramesIn.WithLabelValues(id).Inc()
+		telemetry.StageFramesIn.WithLabelValues(id, "keep").Inc()
+		telemetry.StageDrops.WithLabelValues(id, "nested").Inc()
+		telemetry.FramesOut.WithLabelValues(id, "out").Inc()
+		telemetry.SinkFailures.WithLabelValues(id, "old-out").Inc()
+	}
+	record()
+	StaleMetrics(old, next)()
+	// DeleteLabelValues reports whether the series was still there.
+	if !telemetry.FramesIn.DeleteLabelValues(id) || !telemetry.StageFramesIn.DeleteLabelValues(id, "keep") ||
+		!telemetry.FramesOut.DeleteLabelValues(id, "out") {
+		t.Fatal("series of the pipeline, stages and sinks the replacement keeps must stay")
+	}
+	if telemetry.StageDrops.DeleteLabelValues(id, "nested") || telemetry.SinkFailures.DeleteLabelValues(id, "old-out") {
+		t.Fatal("series of stages and sinks the replacement dropped must be deleted")
+	}
+
+	record()
+	StaleMetrics(next, nil)()
+	if telemetry.FramesIn.DeleteLabelValues(id) || telemetry.StageFramesIn.DeleteLabelValues(id, "keep") ||
+		telemetry.StageDrops.DeleteLabelValues(id, "nested") || telemetry.FramesOut.DeleteLabelValues(id, "out") ||
+		telemetry.SinkFailures.DeleteLabelValues(id, "old-out") {
+		t.Fatal("every series of a removed pipeline must be deleted")
+	}
+}
+
+func TestCollectors_ReportTheNewestRunnerOfAPipeline(t *testing.T) {
+	start := func(rate int64) *Runner {
+		var events []string
+		reg := metrics.NewRegistry()
+		metrics.GetOrRegisterMeter("incoming-byte-rate-for-broker-1", reg).Mark(rate)
+		r := NewRunner()
+		r.SetPipelineID("metrics-replaced")
+		r.SetSource(&statsSource{drainSource: drainSource{events: &events}, reg: reg})
+		if err := r.Start(context.Background()); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		return r
+	}
+	// The replacement starts while the old runner is still draining.
+	old := start(10)
+	defer old.Close()
+	next := start(20)
+	defer next.Close()
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(Collectors()...)
+	want := `
+# HELP quanta_sarama_incoming_byte_rate_total Sarama metric incoming-byte-rate.
+# TYPE quanta_sarama_incoming_byte_rate_total counter
+quanta_sarama_incoming_byte_rate_total{broker="1",pipeline="metrics-replaced",topic=""} 20
+# HELP quanta_source_in_flight Records emitted by the source and not yet acknowledged.
+# TYPE quanta_source_in_flight gauge
+quanta_source_in_flight{pipeline="metrics-replaced"} 3
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"quanta_sarama_incoming_byte_rate_total", "quanta_source_in_flight"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,197 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/spec"
+)
+
+// Predicate reports whether a frame matches a stage or route condition.
+type Predicate func(*pb.Frame) bool
+
+func CompilePredicate(p *spec.Predicate) (Predicate, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return compilePredicate(*p)
+}
+
+func compilePredicate(p spec.Predicate) (Predicate, error) {
+	switch {
+	case len(p.All) > 0:
+		preds, err := compilePredicates(p.All)
+		if err != nil {
+			return nil, err
+		}
+		return func(f *pb.Frame) bool {
+			for _, fn := range preds {
+				if !fn(f) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case len(p.Any) > 0:
+		preds, err := compilePredicates(p.Any)
+		if err != nil {
+			return nil, err
+		}
+		return func(f *pb.Frame) bool {
+			for _, fn := range preds {
+				if fn(f) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case p.Not != nil:
+		inner, err := compilePredicate(*p.Not)
+		if err != nil {
+			return nil, err
+		}
+		return func(f *pb.Frame) bool { return !inner(f) }, nil
+	}
+
+	subject, err := compileSubject(p)
+	if err != nil {
+		return nil, err
+	}
+	match, err := compileOperator(p)
+	if err != nil {
+		return nil, err
+	}
+	return func(f *pb.Frame) bool {
+		v, ok := subject(f)
+		return match(v, ok)
+	}, nil
+}
+
+func compilePredicates(ps []spec.Predicate) ([]Predicate, error) {
+	out := make([]Predicate, 0, len(ps))
+	for _, p := range ps {
+		fn, err := compilePredicate(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, fn)
+	}
+	return out, nil
+}
+
+type subjectFunc func(*pb.Frame) (string, bool)
+
+func compileSubject(p spec.Predicate) (subjectFunc, error) {
+	n := 0
+	for _, set := range []bool{p.Header != "", p.Key, p.Topic, p.JSON != ""} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, errors.New("predicate: exactly one of header, key, topic or json is required")
+	}
+
+	switch {
+	case p.Header != "":
+		name := p.Header
+		return func(f *pb.Frame) (string, bool) {
+			v, ok := f.GetHeaders()[name]
+			return string(v), ok
+		}, nil
+	case p.Key:
+		return func(f *pb.Frame) (string, bool) {
+			return string(f.GetKey()), len(f.GetKey()) > 0
+		}, nil
+	case p.Topic:
+		return func(f *pb.Frame) (string, bool) {
+			t := f.GetCheckpoint().GetKafka().GetTopic()
+			return t, t != ""
+		}, nil
+	default:
+		path := strings.Split(strings.TrimPrefix(p.JSON, "$."), ".")
+		return func(f *pb.Frame) (string, bool) { return jsonLookup(f.GetValue(), path) }, nil
+	}
+}
+
+func compileOperator(p spec.Predicate) (func(string, bool) bool, error) {
+	n := 0
+	for _, set := range []bool{p.Equals != nil, len(p.In) > 0, p.Prefix != "", p.Matches != "", p.Exists != nil} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return nil, errors.New("predicate: at most one of equals, in, prefix, matches or exists is allowed")
+	}
+
+	switch {
+	case p.Equals != nil:
+		want := *p.Equals
+		return func(v string, ok bool) bool { return ok && v == want }, nil
+	case len(p.In) > 0:
+		set := make(map[string]struct{}, len(p.In))
+		for _, s := range p.In {
+			set[s] = struct{}{}
+		}
+		return func(v string, ok bool) bool {
+			_, hit := set[v]
+			return ok && hit
+		}, nil
+	case p.Prefix != "":
+		prefix := p.Prefix
+		return func(v string, ok bool) bool { return ok && strings.HasPrefix(v, prefix) }, nil
+	case p.Matches != "":
+		re, err := regexp.Compile(p.Matches)
+		if err != nil {
+			return nil, fmt.Errorf("predicate: matches: %w", err)
+		}
+		return func(v string, ok bool) bool { return ok && re.MatchString(v) }, nil
+	case p.Exists != nil && !*p.Exists:
+		return func(_ string, ok bool) bool { return !ok }, nil
+	default:
+		return func(_ string, ok bool) bool { return ok }, nil
+	}
+}
+
+// jsonLookup walks a dotted path through a JSON document; numeric segments
+// index into arrays. Scalars are rendered the way they appear in the source.
+func jsonLookup(raw []byte, path []string) (string, bool) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return "", false
+	}
+	cur := doc
+	for _, seg := range path {
+		switch node := cur.(type) {
+		case map[string]any:
+			v, ok := node[seg]
+			if !ok {
+				return "", false
+			}
+			cur = v
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			cur = node[i]
+		default:
+			return "", false
+		}
+	}
+	switch v := cur.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	default:
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+}
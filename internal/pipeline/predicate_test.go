@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"testing"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/spec"
+)
+
+func strp(s string) *string { return &s }
+func boolp(b bool) *bool    { return &b }
+
+func TestCompilePredicate(t *testing.T) {
+	f := makeFrame()
+	f.Key = []byte("user-7")
+	f.Headers = map[string][]byte{"type": []byte("order")}
+	f.Value = []byte(`{"event":{"name":"checkout","items":[{"sku":"A1","qty":2}]}}`)
+
+	cases := []struct {
+		name string
+		p    spec.Predicate
+		want bool
+	}{
+		{"header equals", spec.Predicate{Header: "type", Equals: strp("order")}, true},
+		{"header missing", spec.Predicate{Header: "tenant"}, false},
+		{"header absent", spec.Predicate{Header: "tenant", Exists: boolp(false)}, true},
+		{"key prefix", spec.Predicate{Key: true, Prefix: "user-"}, true},
+		{"topic in", spec.Predicate{Topic: true, In: []string{"x", "t"}}, true},
+		{"json matches", spec.Predicate{JSON: "event.name", Matches: "^check"}, true},
+		{"json array index", spec.Predicate{JSON: "$.event.items.0.qty", Equals: strp("2")}, true},
+		{"json missing path", spec.Predicate{JSON: "event.items.3.sku"}, false},
+		{"all", spec.Predicate{All: []spec.Predicate{{Key: true}, {Header: "type", Equals: strp("refund")}}}, false},
+		{"any", spec.Predicate{Any: []spec.Predicate{{Header: "tenant"}, {Key: true}}}, true},
+		{"not", spec.Predicate{Not: &spec.Predicate{Header: "type"}}, false},
+	}
+	for _, tc := range cases {
+		fn, err := CompilePredicate(&tc.p)
+		if err != nil {
+			t.Fatalf("%s: compile: %v", tc.name, err)
+		}
+		if got := fn(f); got != tc.want {
+			t.Fatalf("%s: want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+
+	if fn, _ := CompilePredicate(&spec.Predicate{JSON: "event.name"}); fn(&pb.Frame{Value: []byte("not json")}) {
+		t.Fatal("json predicate must not match a non-JSON value")
+	}
+}
+
+func TestCompilePredicate_Invalid(t *testing.T) {
+	bad := []spec.Predicate{
+		{},
+		{Header: "a", Key: true},
+		{Header: "a", Equals: strp("x"), Prefix: "y"},
+		{Header: "a", Matches: "("},
+	}
+	for i, p := range bad {
+		if _, err := CompilePredicate(&p); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+}
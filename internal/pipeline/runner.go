@@ -1,53 +1,132 @@
 package pipeline
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
 	pb "quanta/api/proto/v1"
+	"quanta/internal/logging"
+	"quanta/internal/telemetry"
 	"quanta/internal/transform"
 	"quanta/sink"
+	"quanta/source"
 	"quanta/source/kafka"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Runner struct {
-	source kafka.Adapter
-	sinks  []sink.Adapter
+	pipelineID string
+	source     source.Source
+	sinks      []namedSink
+
+	stagesMu sync.RWMutex
+	stages   []Stage
+	graph    *Graph
+
+	acks  ackTracker
+	spans frameSpans
+	taps  tapHub
+	mu    sync.Mutex
+	subs  []func(*pb.ConnectorAck)
+	drops []func(Drop)
+
+	cancel  context.CancelFunc
+	lastErr error
+	done    chan struct{}
+	srcErr  error
+
+	state      string
+	supervisor SupervisorOptions
+
+	active atomic.Int64
+}
+
+type namedSink struct {
+	name string
+	acks bool
+	sink.Adapter
+}
 
-	stages []transformStage
+func NewRunner() *Runner { return &Runner{supervisor: DefaultSupervisorOptions} }
 
-	mu   sync.Mutex
-	subs []func(*pb.ConnectorAck)
+func (r *Runner) AddSink(s sink.Adapter) { r.AddNamedSink("", s) }
+func (r *Runner) AddNamedSink(name string, s sink.Adapter) {
+	r.sinks = append(r.sinks, newNamedSink(name, s))
 }
+func (r *Runner) SetSource(s source.Source) { r.source = s }
+func (r *Runner) SetPipelineID(id string)   { r.pipelineID = id }
+func (r *Runner) ID() string                { return r.pipelineID }
 
-type transformStage struct {
-	name          string
-	client        transform.Client
-	timeout       time.Duration
-	retryAttempts int
-	retryBackoff  time.Duration
+func (r *Runner) SubscribeAck(fn func(*pb.ConnectorAck)) {
+	r.mu.Lock()
+	r.subs = append(r.subs, fn)
+	r.mu.Unlock()
 }
 
-func NewRunner() *Runner { return &Runner{} }
+// Drop causes: a stage answered DROP, a stage failed after its retries, or
+// a frame was routed to a sink the pipeline does not have or, in a graph,
+// that is not downstream of the node that routed it.
+const (
+	DropFiltered = "filtered"
+	DropFailed   = "failed"
+	DropUnrouted = "unrouted"
+)
 
-func (r *Runner) AddSink(s sink.Adapter)    { r.sinks = append(r.sinks, s) }
-func (r *Runner) SetSource(s kafka.Adapter) { r.source = s }
+// Drop describes a frame that left the pipeline without reaching a sink.
+// Reason is the transformer's message, the stage error or the unknown sink.
+type Drop struct {
+	Frame  *pb.Frame
+	Stage  string
+	Cause  string
+	Reason string
+}
 
-func (r *Runner) AddTransformer(name string, c transform.Client, timeout time.Duration, attempts int, backoff time.Duration) {
-	r.stages = append(r.stages, transformStage{name: name, client: c, timeout: timeout, retryAttempts: attempts, retryBackoff: backoff})
+// SubscribeDrop calls fn for every frame a stage drops or fails on and
+// every frame routed nowhere. fn runs on the pipeline's goroutine.
+func (r *Runner) SubscribeDrop(fn func(Drop)) {
+	r.mu.Lock()
+	r.drops = append(r.drops, fn)
+	r.mu.Unlock()
 }
 
-func (r *Runner) SubscribeAck(fn func(*pb.ConnectorAck)) {
+func (r *Runner) dropped(d Drop) {
+	r.dropEvent(d)
 	r.mu.Lock()
-	r.subs = append(r.subs, fn)
+	handlers := append([]func(Drop){}, r.drops...)
 	r.mu.Unlock()
+	for _, fn := range handlers {
+		fn(d)
+	}
 }
 
+func newNamedSink(name string, s sink.Adapter) namedSink {
+	_, acks := s.(sink.AckAware)
+	return namedSink{name: name, acks: acks, Adapter: s}
+}
+
+// Ack releases one reference on a checkpoint and forwards it to subscribers
+// once nothing derived from the source record is still in flight.
 func (r *Runner) Ack(tok *pb.CheckpointToken) {
+	if !r.acks.release(tok) {
+		if span := r.spans.get(tok); span != nil {
+			span.AddEvent("sink ack")
+		}
+		return
+	}
+	telemetry.Acks.WithLabelValues(r.pipelineID).Inc()
+	if span := r.spans.take(tok); span != nil {
+		defer span.End()
+		defer span.AddEvent("ack")
+	}
 	ack := &pb.ConnectorAck{Checkpoint: tok}
 
 	r.mu.Lock()
@@ -59,15 +138,24 @@ func (r *Runner) Ack(tok *pb.CheckpointToken) {
 	}
 }
 
-func toRequest(f *pb.Frame) *pb.TransformRequest {
+func toRequest(f *pb.Frame, protocol int32) *pb.TransformRequest {
 	md := &pb.EventMetadata{}
 	if f.Ts != nil {
 		md.TimestampMs = f.Ts.AsTime().UnixMilli()
 	}
 	if len(f.Headers) > 0 {
-		md.Headers = make(map[string]string, len(f.Headers))
-		for k, v := range f.Headers {
-			md.Headers[k] = string(v)
+		if protocol >= transform.ProtocolV2 {
+			// A copy, so an in-process transformer that edits its request
+			// cannot reach the frame, its clones or tap copies.
+			md.BinaryHeaders = make(map[string][]byte, len(f.Headers))
+			for k, v := range f.Headers {
+				md.BinaryHeaders[k] = bytes.Clone(v)
+			}
+		} else {
+			md.Headers = make(map[string]string, len(f.Headers))
+			for k, v := range f.Headers {
+				md.Headers[k] = strings.ToValidUTF8(string(v), "\uFFFD")
+			}
 		}
 	}
 	if k := f.GetCheckpoint().GetKafka(); k != nil {
@@ -78,16 +166,20 @@ func toRequest(f *pb.Frame) *pb.TransformRequest {
 		}
 		md.Attributes["source.topic"] = k.Topic
 	}
-	return &pb.TransformRequest{
+	req := &pb.TransformRequest{
 		PipelineId: "",
 		PluginId:   "",
 		Payload:    f.Value,
 		Metadata:   md,
 		BatchMode:  false,
 	}
+	if protocol >= transform.ProtocolV2 {
+		req.Key = f.Key
+	}
+	return req
 }
 
-func toFrames(orig *pb.Frame, events []*pb.Event) []*pb.Frame {
+func toFrames(orig *pb.Frame, events []*pb.Event, protocol int32) []*pb.Frame {
 	if len(events) == 0 {
 		return nil
 	}
@@ -105,116 +197,357 @@ func toFrames(orig *pb.Frame, events []*pb.Event) []*pb.Frame {
 
 				g.Ts = timestamppb.New(time.UnixMilli(md.TimestampMs))
 			}
-			if len(md.Headers) > 0 {
+			if protocol >= transform.ProtocolV2 && len(md.BinaryHeaders) > 0 {
+				g.Headers = md.BinaryHeaders
+			} else if len(md.Headers) > 0 {
 				g.Headers = make(map[string][]byte, len(md.Headers))
 				for k, v := range md.Headers {
 					g.Headers[k] = []byte(v)
 				}
 			}
 		}
+		if protocol >= transform.ProtocolV2 {
+			if ev.Key != nil {
+				g.Key = ev.Key
+			}
+			g.Route = ev.GetRoute()
+		}
 		out = append(out, g)
 	}
 	return out
 }
 
-func (r *Runner) pushFrame(f *pb.Frame) error {
-	frames := []*pb.Frame{f}
+// pushFrame is the source's emit callback. A panic anywhere in the chain
+// becomes an error for this pipeline's source instead of killing the process.
+// The frame's root span stays open until its checkpoint is acked.
+func (r *Runner) pushFrame(f *pb.Frame) (err error) {
+	ctx, span := r.startFrame(f)
+	defer func() {
+		if p := recover(); p != nil {
+			r.acks.forget(f.Checkpoint)
+			err = fmt.Errorf("pipeline %s: panic: %v", r.pipelineID, p)
+			r.setErr(err)
+			r.failFrame(f, span, err)
+		}
+	}()
+	r.active.Add(1)
+	defer r.active.Add(-1)
+	telemetry.FramesIn.WithLabelValues(r.pipelineID).Inc()
+	r.acks.hold(f.Checkpoint)
+	if r.taps.active() {
+		r.taps.emit(r.pipelineID, TapPointSource, f)
+	}
+	if r.graph != nil {
+		err = r.graph.run(ctx, r, f)
+	} else {
+		err = r.pushLinear(ctx, f)
+	}
+	if err != nil {
+		r.acks.forget(f.Checkpoint)
+		r.failFrame(f, span, err)
+		return err
+	}
+	r.Ack(f.Checkpoint)
+	if f.Checkpoint == nil {
+		span.End()
+	}
+	return nil
+}
 
-	for _, st := range r.stages {
-		next := make([]*pb.Frame, 0)
-		for _, in := range frames {
-			var (
-				resp *pb.TransformResponse
-				err  error
-			)
-
-			req := toRequest(in)
-
-			req.PluginId = st.name
-
-			attempts := st.retryAttempts
-			for try := 0; ; try++ {
-				ctx := context.Background()
-				var cancel context.CancelFunc
-				if st.timeout > 0 {
-					ctx, cancel = context.WithTimeout(ctx, st.timeout)
-				}
-				resp, err = st.client.Transform(ctx, req)
-				if cancel != nil {
-					cancel()
-				}
+func (r *Runner) pushLinear(ctx context.Context, f *pb.Frame) error {
+	r.stagesMu.RLock()
+	frames, err := runChain(ctx, r, r.stages, []*pb.Frame{f})
+	r.stagesMu.RUnlock()
+	if err != nil {
+		return err
+	}
 
-				if err != nil {
-					if try < attempts {
-						time.Sleep(st.retryBackoff)
-						continue
-					}
+	for _, fr := range frames {
+		targets := r.sinksFor(fr)
+		if len(targets) == 0 {
+			logging.L().Warn("frame routed to unknown sink; dropping", "sink", fr.GetRoute().GetSink())
+			r.dropped(Drop{Frame: fr, Cause: DropUnrouted, Reason: "unknown sink " + fr.GetRoute().GetSink()})
+			continue
+		}
+		for _, s := range targets {
+			if err := r.pushSink(ctx, s, fr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-					r.Ack(in.Checkpoint)
-					resp = nil
-					break
-				}
+// pushSink pushes f inside a sink span whose trace context is written into
+// the headers of the frame handed to the sink, so records produced
+// downstream continue the trace. Each sink gets its own headers map: in
+// linear mode every sink is handed the same frame, which a sink may keep.
+func (r *Runner) pushSink(ctx context.Context, s namedSink, f *pb.Frame) error {
+	if s.acks {
+		r.acks.hold(f.Checkpoint)
+	}
+	ctx, span := telemetry.Tracer().Start(ctx, "sink "+s.name, trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+	if span.SpanContext().IsValid() {
+		h := make(map[string][]byte, len(f.Headers)+2)
+		maps.Copy(h, f.Headers)
+		telemetry.InjectHeaders(ctx, h)
+		f = withHeaders(f, h)
+	}
+	if r.taps.active() {
+		r.taps.emit(r.pipelineID, "sink:"+s.name, f)
+	}
+	start := time.Now()
+	err := s.Push(f)
+	telemetry.SinkLatency.WithLabelValues(r.pipelineID, s.name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		// The sink will never ack a frame it refused; pushFrame forgets
+		// only its own reference.
+		if s.acks {
+			r.acks.forget(f.Checkpoint)
+		}
+		telemetry.SinkFailures.WithLabelValues(r.pipelineID, s.name).Inc()
+		spanError(span, err)
+		return err
+	}
+	telemetry.FramesOut.WithLabelValues(r.pipelineID, s.name).Inc()
+	return nil
+}
 
-				switch resp.GetStatus() {
-				case pb.Status_OK:
+func (r *Runner) sinksFor(f *pb.Frame) []namedSink {
+	name := f.GetRoute().GetSink()
+	if name == "" {
+		return r.sinks
+	}
+	for i, s := range r.sinks {
+		if s.name == name {
+			return r.sinks[i : i+1]
+		}
+	}
+	return nil
+}
 
-				case pb.Status_DROP:
+func (r *Runner) Start(ctx context.Context) error {
+	if r.source == nil {
+		return errors.New("runner: no source configured")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	r.mu.Lock()
+	r.cancel, r.done = cancel, done
+	r.mu.Unlock()
+	r.setState(StateRunning)
+	trackRunner(r)
+	go func() {
+		defer close(done)
+		err := r.supervise(ctx)
+		if err == nil {
+			return
+		}
+		logging.L().Error("source failed", "pipeline", r.pipelineID, "err", err)
+		r.mu.Lock()
+		r.srcErr, r.lastErr = err, err
+		r.mu.Unlock()
+		r.setState(StateFailed)
+	}()
+	return nil
+}
 
-					r.Ack(in.Checkpoint)
-					resp.Events = nil
+// Done is closed once the source goroutine has returned, either because the
+// runner was closed or because the source failed with a fatal error or ran
+// out of restarts; Err tells the two apart.
+func (r *Runner) Done() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.done
+}
 
-				default:
-					if try < attempts {
-						time.Sleep(st.retryBackoff)
-						continue
-					}
+// Err returns the error the source stopped with, or nil if it is still
+// running or was stopped deliberately.
+func (r *Runner) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.srcErr
+}
 
-					r.Ack(in.Checkpoint)
-					resp.Events = nil
-				}
-				break
-			}
+// Drain shuts the pipeline down in order: stop fetching, wait for frames in
+// transformers and sinks, flush sinks, wait for their acks to reach the
+// source, commit offsets, then close everything. If ctx expires first the
+// remaining steps still run and ctx's error is returned.
+func (r *Runner) Drain(ctx context.Context) error {
+	err := r.Quiesce(ctx)
+	_ = r.Close()
+	return err
+}
 
-			if resp == nil || len(resp.GetEvents()) == 0 {
-				continue
-			}
+// Quiesce runs Drain's steps up to the commit and leaves the pipeline
+// paused but open, so it holds no uncommitted frames and can either be
+// resumed or closed.
+func (r *Runner) Quiesce(ctx context.Context) error {
+	if p, ok := r.source.(source.Pausable); ok {
+		p.Pause()
+	}
+	err := r.waitFor(ctx, func() bool { return r.active.Load() == 0 })
 
-			outs := toFrames(in, resp.GetEvents())
-			next = append(next, outs...)
+	for _, s := range r.allSinks() {
+		if f, ok := s.Adapter.(sink.Flusher); ok {
+			if ferr := f.Flush(); ferr != nil {
+				logging.L().Warn("sink flush failed", "pipeline", r.pipelineID, "sink", s.name, "err", ferr)
+			}
 		}
-		frames = next
-		if len(frames) == 0 {
+	}
+	if err == nil {
+		err = r.waitFor(ctx, func() bool { return r.acks.len() == 0 && r.SourceStats().InFlight == 0 })
+	}
+	if c, ok := r.source.(source.Committer); ok {
+		c.Commit()
+	}
+	if err != nil {
+		logging.L().Warn("drain deadline reached", "pipeline", r.pipelineID,
+			"active", r.active.Load(), "unacked", r.acks.len())
+	}
+	return err
+}
 
-			return nil
+func (r *Runner) waitFor(ctx context.Context, cond func() bool) error {
+	t := time.NewTicker(10 * time.Millisecond)
+	defer t.Stop()
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
 		}
 	}
+	return nil
+}
 
-	for _, fr := range frames {
-		for _, s := range r.sinks {
-			if err := s.Push(fr); err != nil {
-				return err
+func (r *Runner) allSinks() []namedSink {
+	out := append([]namedSink{}, r.sinks...)
+	if r.graph != nil {
+		for _, n := range r.graph.order {
+			if n.kind == nodeSink {
+				out = append(out, n.sink)
 			}
 		}
 	}
+	return out
+}
+
+// Pause stops the source fetching; frames already in flight still finish.
+func (r *Runner) Pause() error {
+	p, ok := r.source.(source.Pausable)
+	if !ok {
+		return errors.New("runner: source does not support pause")
+	}
+	p.Pause()
 	return nil
 }
 
-func (r *Runner) Start(ctx context.Context) error {
-	if r.source == nil {
-		return errors.New("runner: no source configured")
+func (r *Runner) Resume() error {
+	p, ok := r.source.(source.Pausable)
+	if !ok {
+		return errors.New("runner: source does not support pause")
 	}
-	go func() { _ = r.source.Run(ctx, r.pushFrame) }()
+	p.Resume()
 	return nil
 }
 
-func (r *Runner) Close() error {
+func (r *Runner) SourceStats() kafka.Stats {
+	if sr, ok := r.source.(kafka.StatsReporter); ok {
+		return sr.Stats()
+	}
+	return kafka.Stats{}
+}
 
+type StageHealth struct {
+	Name    string
+	Healthy bool
+	Err     string
+}
+
+// StageHealth probes every transformer in the pipeline, including those
+// nested in routes and graph nodes.
+func (r *Runner) StageHealth(ctx context.Context) []StageHealth {
+	var out []StageHealth
+	r.stagesMu.RLock()
 	for _, st := range r.stages {
-		_ = st.client.Close()
+		out = append(out, st.health(ctx)...)
+	}
+	r.stagesMu.RUnlock()
+	if r.graph != nil {
+		for _, n := range r.graph.order {
+			if n.kind == nodeStage {
+				out = append(out, n.stage.health(ctx)...)
+			}
+		}
+	}
+	return out
+}
+
+// Ready reports why the pipeline cannot process frames yet, if it cannot:
+// the source must be running and, when it can tell, connected with
+// partitions assigned; every transformer plugin must pass its health check
+// and at least one sink must be configured.
+func (r *Runner) Ready(ctx context.Context) error {
+	if st := r.State(); st != StateRunning {
+		return fmt.Errorf("source is %s", st)
+	}
+	if rr, ok := r.source.(source.ReadyReporter); ok {
+		if err := rr.Ready(); err != nil {
+			return err
+		}
+	}
+	for _, h := range r.StageHealth(ctx) {
+		if !h.Healthy {
+			return fmt.Errorf("stage %s unhealthy: %s", h.Name, h.Err)
+		}
+	}
+	if len(r.allSinks()) == 0 {
+		return errors.New("no sinks configured")
+	}
+	return nil
+}
+
+func (r *Runner) setErr(err error) {
+	r.mu.Lock()
+	r.lastErr = err
+	r.mu.Unlock()
+}
+
+func (r *Runner) LastError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+func (r *Runner) Close() error {
+	r.mu.Lock()
+	cancel := r.cancel
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		untrackRunner(r)
+		if r.State() != StateFailed {
+			r.setState(StateStopped)
+		}
+	}
+	if r.source != nil {
+		_ = r.source.Close()
+	}
+	r.taps.closeAll()
+
+	r.stagesMu.Lock()
+	closeStages(r.stages)
+	r.stages = nil
+	r.stagesMu.Unlock()
+	if r.graph != nil {
+		r.graph.close()
 	}
 
 	for _, s := range r.sinks {
 		_ = s.Close()
 	}
+	r.spans.endAll("pipeline closed before the frame was acked")
 	return nil
 }
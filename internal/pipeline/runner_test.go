@@ -2,14 +2,21 @@ package pipeline
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	pb "quanta/api/proto/v1"
+	"quanta/internal/spec"
+	"quanta/internal/transform"
 	"quanta/sink"
+	"quanta/source/kafka"
 
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type fakeTransform struct {
@@ -27,6 +34,9 @@ func (f *fakeTransform) Stream(ctx context.Context, opts ...grpc.CallOption) (pb
 	return nil, nil
 }
 func (f *fakeTransform) Close() error { return nil }
+func (f *fakeTransform) Configure(ctx context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	return &pb.ConfigureResponse{Ok: true}, nil
+}
 func (f *fakeTransform) Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
 	c := atomic.AddInt32(&f.calls, 1)
 	switch f.mode {
@@ -103,6 +113,21 @@ func TestRunner_TransformerDrop_AcksNoPush(t *testing.T) {
 	}
 }
 
+func TestRunner_ReportsDropsWithCause(t *testing.T) {
+	r := NewRunner()
+	r.AddTransformer("t1", &fakeTransform{mode: "drop"}, 100*time.Millisecond, 0, 0)
+	var drops []Drop
+	r.SubscribeDrop(func(d Drop) { drops = append(drops, d) })
+
+	f := makeFrame()
+	if err := r.pushFrame(f); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+	if len(drops) != 1 || drops[0].Stage != "t1" || drops[0].Cause != DropFiltered || drops[0].Frame.Checkpoint != f.Checkpoint {
+		t.Fatalf("unexpected drops %+v", drops)
+	}
+}
+
 func TestRunner_TransformerRetryThenOK(t *testing.T) {
 	r := NewRunner()
 	fake := &fakeTransform{mode: "errorThenOK"}
@@ -140,3 +165,355 @@ func TestRunner_MultiStageFanout(t *testing.T) {
 		t.Fatalf("expected 2 pushed frames after fanout, got %d", len(cs.pushed))
 	}
 }
+
+type v2Transform struct {
+	fakeTransform
+	lastReq *pb.TransformRequest
+}
+
+func (f *v2Transform) Metadata(ctx context.Context) (*pb.MetadataResponse, error) {
+	return &pb.MetadataResponse{ProtocolVersion: &pb.PluginVersion{Major: 2}}, nil
+}
+func (f *v2Transform) Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
+	f.lastReq = req
+	md := &pb.EventMetadata{BinaryHeaders: req.GetMetadata().GetBinaryHeaders()}
+	return &pb.TransformResponse{Status: pb.Status_OK, Events: []*pb.Event{
+		{Value: req.Payload, Metadata: md},
+		{Value: req.Payload, Key: []byte("new-key"), Metadata: md, Route: &pb.Route{Sink: "audit", Topic: "audit-topic"}},
+	}}, nil
+}
+
+func TestRunner_ProtocolV2_KeysHeadersAndRouting(t *testing.T) {
+	r := NewRunner()
+	fake := &v2Transform{}
+	r.AddTransformer("t1", fake, 100*time.Millisecond, 0, 0)
+	main, audit := &captureSink{}, &captureSink{}
+	r.AddNamedSink("main", main)
+	r.AddNamedSink("audit", audit)
+
+	f := makeFrame()
+	f.Key = []byte("orig-key")
+	f.Headers = map[string][]byte{"bin": {0xff, 0x00}}
+	if err := r.pushFrame(f); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+
+	if string(fake.lastReq.GetKey()) != "orig-key" {
+		t.Fatalf("plugin did not see key: %q", fake.lastReq.GetKey())
+	}
+	if got := fake.lastReq.GetMetadata().GetBinaryHeaders()["bin"]; string(got) != "\xff\x00" {
+		t.Fatalf("binary header corrupted: %q", got)
+	}
+	if len(main.pushed) != 1 || string(main.pushed[0].Key) != "orig-key" {
+		t.Fatalf("unrouted event should keep original key and go to every sink: %+v", main.pushed)
+	}
+	if string(main.pushed[0].Headers["bin"]) != "\xff\x00" {
+		t.Fatalf("binary header lost on output: %q", main.pushed[0].Headers["bin"])
+	}
+	if len(audit.pushed) != 2 {
+		t.Fatalf("expected audit sink to get both events, got %d", len(audit.pushed))
+	}
+	routed := audit.pushed[1]
+	if string(routed.Key) != "new-key" || routed.GetRoute().GetTopic() != "audit-topic" {
+		t.Fatalf("routed event lost key or topic: %+v", routed)
+	}
+}
+
+func TestRunner_ProtocolV1_BinaryHeadersDoNotBreakRequests(t *testing.T) {
+	f := makeFrame()
+	f.Headers = map[string][]byte{"bin": {0xff, 0x00}}
+	req := toRequest(f, 1)
+	if _, err := proto.Marshal(req); err != nil {
+		t.Fatalf("v1 request with binary headers must marshal: %v", err)
+	}
+}
+
+func TestToRequest_V2HeadersAreACopy(t *testing.T) {
+	f := makeFrame()
+	f.Headers = map[string][]byte{"bin": {0xff, 0x00}}
+	h := toRequest(f, transform.ProtocolV2).Metadata.BinaryHeaders
+	h["bin"][0] = 0
+	h["added"] = []byte("x")
+	if len(f.Headers) != 1 || f.Headers["bin"][0] != 0xff {
+		t.Fatalf("a transformer's edits to its request reached the frame: %v", f.Headers)
+	}
+}
+
+type configRecorder struct {
+	fakeTransform
+	configs []*pb.ConfigureRequest
+	seen    []*pb.TransformRequest
+}
+
+func (f *configRecorder) Configure(ctx context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	f.configs = append(f.configs, req)
+	return &pb.ConfigureResponse{Ok: true}, nil
+}
+func (f *configRecorder) Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
+	f.seen = append(f.seen, req)
+	return f.fakeTransform.Transform(ctx, req)
+}
+
+func TestRunner_StageConfigSentOnceAndAfterReconnect(t *testing.T) {
+	r := NewRunner()
+	r.SetPipelineID("orders")
+	fake := &configRecorder{fakeTransform: fakeTransform{mode: "ok"}}
+	cfg, _ := structpb.NewStruct(map[string]any{"threshold": 3})
+	r.AddStage("enrich", fake, StageOptions{Timeout: 100 * time.Millisecond, Config: cfg})
+	r.AddSink(&captureSink{})
+
+	for i := 0; i < 2; i++ {
+		if err := r.pushFrame(makeFrame()); err != nil {
+			t.Fatalf("pushFrame: %v", err)
+		}
+	}
+	if len(fake.configs) != 1 {
+		t.Fatalf("expected one Configure call, got %d", len(fake.configs))
+	}
+	got := fake.configs[0]
+	if got.PipelineId != "orders" || got.PluginId != "enrich" || got.Config.GetFields()["threshold"].GetNumberValue() != 3 {
+		t.Fatalf("unexpected configure request: %+v", got)
+	}
+	if fake.seen[0].PipelineId != "orders" {
+		t.Fatalf("pipeline_id not set on transform request: %q", fake.seen[0].PipelineId)
+	}
+
+	r.stages[0].(*transformStage).reset()
+	if err := r.pushFrame(makeFrame()); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+	if len(fake.configs) != 2 {
+		t.Fatalf("expected Configure to be resent after reconnect, got %d calls", len(fake.configs))
+	}
+}
+
+type rejectingConfig struct{ configRecorder }
+
+func (f *rejectingConfig) Configure(ctx context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	f.configs = append(f.configs, req)
+	return &pb.ConfigureResponse{Ok: false, ErrorMessage: "threshold must be positive"}, nil
+}
+
+func TestRunner_RejectedStageConfigIsNotAcked(t *testing.T) {
+	r := NewRunner()
+	r.SetPipelineID("orders")
+	fake := &rejectingConfig{configRecorder{fakeTransform: fakeTransform{mode: "ok"}}}
+	r.AddStage("enrich", fake, StageOptions{Timeout: 100 * time.Millisecond})
+	out := &captureSink{}
+	r.AddSink(out)
+	var acked, drops int
+	r.SubscribeAck(func(*pb.ConnectorAck) { acked++ })
+	r.SubscribeDrop(func(Drop) { drops++ })
+
+	err := r.pushFrame(makeFrame())
+	if err == nil || !strings.Contains(err.Error(), "rejected config: threshold must be positive") {
+		t.Fatalf("want the config rejection as the source's error, got %v", err)
+	}
+	if acked != 0 || drops != 0 || len(out.pushed) != 0 || len(fake.seen) != 0 {
+		t.Fatalf("frame must stall unacked: acked=%d drops=%d pushed=%d transforms=%d", acked, drops, len(out.pushed), len(fake.seen))
+	}
+	if r.LastError() == nil {
+		t.Fatal("config rejection must surface as the pipeline's last error")
+	}
+}
+
+type tagTransform struct {
+	fakeTransform
+	tag string
+}
+
+func (f *tagTransform) Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
+	atomic.AddInt32(&f.calls, 1)
+	return &pb.TransformResponse{Status: pb.Status_OK, Events: []*pb.Event{{Value: append([]byte(f.tag+":"), req.Payload...)}}}, nil
+}
+
+func TestRunner_WhenSkipsNonMatchingFrames(t *testing.T) {
+	r := NewRunner()
+	fake := &fakeTransform{mode: "drop"}
+	when, _ := CompilePredicate(&spec.Predicate{Header: "type", Equals: strp("spam")})
+	r.AddStage("filter", fake, StageOptions{Timeout: 100 * time.Millisecond, When: when})
+	cs := &captureSink{}
+	r.AddSink(cs)
+
+	spam := makeFrame()
+	spam.Headers = map[string][]byte{"type": []byte("spam")}
+	for _, f := range []*pb.Frame{makeFrame(), spam} {
+		if err := r.pushFrame(f); err != nil {
+			t.Fatalf("pushFrame: %v", err)
+		}
+	}
+	if fake.calls != 1 {
+		t.Fatalf("stage should only see matching frames, got %d calls", fake.calls)
+	}
+	if len(cs.pushed) != 1 || cs.pushed[0].Headers != nil {
+		t.Fatalf("non-matching frame should pass through untouched: %+v", cs.pushed)
+	}
+}
+
+func TestRunner_RouteStageSelectsSubChain(t *testing.T) {
+	r := NewRunner()
+	orders := &tagTransform{tag: "orders"}
+	other := &tagTransform{tag: "default"}
+	when, _ := CompilePredicate(&spec.Predicate{Header: "type", In: []string{"order", "refund"}})
+	r.Append(NewRouteStage("by-type", []Route{
+		{Name: "orders", When: when, Stages: []Stage{NewTransformStage("orders", orders, StageOptions{})}},
+	}, []Stage{NewTransformStage("default", other, StageOptions{})}, nil))
+	cs := &captureSink{}
+	r.AddSink(cs)
+
+	order := makeFrame()
+	order.Headers = map[string][]byte{"type": []byte("refund")}
+	for _, f := range []*pb.Frame{order, makeFrame()} {
+		if err := r.pushFrame(f); err != nil {
+			t.Fatalf("pushFrame: %v", err)
+		}
+	}
+	if len(cs.pushed) != 2 || string(cs.pushed[0].Value) != "orders:hello" || string(cs.pushed[1].Value) != "default:hello" {
+		t.Fatalf("unexpected routing: %q, %q", cs.pushed[0].Value, cs.pushed[1].Value)
+	}
+	if orders.calls != 1 || other.calls != 1 {
+		t.Fatalf("each branch should run once: orders=%d default=%d", orders.calls, other.calls)
+	}
+}
+
+func TestRunner_FanoutAcksSourceOnce(t *testing.T) {
+	r := NewRunner()
+	var acks int
+	r.SubscribeAck(func(*pb.ConnectorAck) { acks++ })
+	r.AddTransformer("s1", &fakeTransform{mode: "fanout2"}, 100*time.Millisecond, 0, 0)
+	for _, name := range []string{"a", "b"} {
+		cs := &captureSink{}
+		cs.BindAck(r.Ack)
+		r.AddNamedSink(name, cs)
+	}
+
+	if err := r.pushFrame(makeFrame()); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+	if acks != 1 {
+		t.Fatalf("want one source ack for two events across two sinks, got %d", acks)
+	}
+}
+
+type panicTransform struct{ fakeTransform }
+
+func (f *panicTransform) Transform(context.Context, *pb.TransformRequest) (*pb.TransformResponse, error) {
+	panic("plugin client bug")
+}
+
+func TestRunner_PanicBecomesPipelineError(t *testing.T) {
+	r := NewRunner()
+	r.SetPipelineID("p1")
+	r.AddTransformer("t1", &panicTransform{}, 100*time.Millisecond, 0, 0)
+	r.AddSink(&captureSink{})
+
+	err := r.pushFrame(makeFrame())
+	if err == nil || r.LastError() == nil {
+		t.Fatalf("want panic surfaced as an error, got %v", err)
+	}
+}
+
+type drainSource struct{ events *[]string }
+
+func (s *drainSource) Configure(kafka.Config) error { return nil }
+func (s *drainSource) Run(ctx context.Context, _ kafka.EmitFunc) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+func (s *drainSource) Close() error       { *s.events = append(*s.events, "close"); return nil }
+func (s *drainSource) Pause()             { *s.events = append(*s.events, "pause") }
+func (s *drainSource) Resume()            {}
+func (s *drainSource) Commit()            { *s.events = append(*s.events, "commit") }
+func (s *drainSource) Stats() kafka.Stats { return kafka.Stats{} }
+
+type flushSink struct {
+	heldSink
+	events *[]string
+}
+
+func (f *flushSink) Flush() error {
+	*f.events = append(*f.events, "flush")
+	f.flush()
+	return nil
+}
+
+func TestRunner_DrainFlushesAcksAndCommitsInOrder(t *testing.T) {
+	var events []string
+	r := NewRunner()
+	r.SetSource(&drainSource{events: &events})
+	r.SubscribeAck(func(*pb.ConnectorAck) { events = append(events, "ack") })
+	s := &flushSink{events: &events}
+	s.BindAck(r.Ack)
+	r.AddSink(s)
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	if err := r.pushFrame(makeFrame()); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("held frame must not be acked yet: %v", events)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.Drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	if got := strings.Join(events, ","); got != "pause,flush,ack,commit,close" {
+		t.Fatalf("unexpected drain order %q", got)
+	}
+	select {
+	case <-r.Done():
+	case <-time.After(time.Second):
+		t.Fatal("source goroutine still running after drain")
+	}
+	if r.Err() != nil {
+		t.Fatalf("deliberate stop must not report a source error: %v", r.Err())
+	}
+}
+
+func TestAckTracker_UnknownTokensAndFailedCheckpoints(t *testing.T) {
+	var acks ackTracker
+	tok := makeFrame().Checkpoint
+	if acks.release(tok) {
+		t.Fatal("a token that is not held must not be acked")
+	}
+
+	acks.hold(tok)
+	acks.hold(tok)
+	if acks.release(tok) || !acks.release(tok) {
+		t.Fatal("want the ack once the last reference is released")
+	}
+	if acks.release(tok) {
+		t.Fatal("a second ack for a released token must not be forwarded")
+	}
+
+	acks.hold(tok)
+	acks.hold(tok)
+	acks.forget(tok)
+	if acks.len() != 1 {
+		t.Fatal("forget must drop one reference, not every one")
+	}
+	if acks.release(tok) || acks.len() != 0 {
+		t.Fatal("a failed checkpoint must never be acked upstream")
+	}
+}
+
+// refusingSink is ack-aware but fails every push.
+type refusingSink struct{ captureSink }
+
+func (*refusingSink) Push(*pb.Frame) error { return errors.New("broker down") }
+
+func TestRunner_FailedAckAwarePushHoldsNoCheckpoint(t *testing.T) {
+	r := NewRunner()
+	s := &refusingSink{}
+	s.BindAck(r.Ack)
+	r.AddNamedSink("out", s)
+	if err := r.pushFrame(makeFrame()); err == nil {
+		t.Fatal("want the sink error")
+	}
+	if n := r.acks.len(); n != 0 {
+		t.Fatalf("a refused frame must leave no checkpoint pending, got %d", n)
+	}
+}
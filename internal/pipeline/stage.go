@@ -0,0 +1,389 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/logging"
+	"quanta/internal/telemetry"
+	"quanta/internal/transform"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type StageOptions struct {
+	Timeout       time.Duration
+	RetryAttempts int
+	RetryBackoff  time.Duration
+	Config        *structpb.Struct
+	When          Predicate
+}
+
+// Stage is one step of a runner's chain. A stage turns one input frame into
+// zero or more output frames, acking the inputs it consumes.
+type Stage interface {
+	Name() string
+	// apply returns the frames in turns into. An error means the frame
+	// can go no further and must not be acked.
+	apply(ctx context.Context, r *Runner, in *pb.Frame) ([]*pb.Frame, error)
+	health(ctx context.Context) []StageHealth
+	close() error
+}
+
+type transformStage struct {
+	name          string
+	client        transform.Client
+	timeout       time.Duration
+	retryAttempts int
+	retryBackoff  time.Duration
+	config        *structpb.Struct
+
+	protocol   atomic.Int32
+	configured atomic.Bool
+	// unwatch stops the client's reconnect notifications.
+	unwatch func()
+}
+
+func (r *Runner) AddTransformer(name string, c transform.Client, timeout time.Duration, attempts int, backoff time.Duration) {
+	r.AddStage(name, c, StageOptions{Timeout: timeout, RetryAttempts: attempts, RetryBackoff: backoff})
+}
+
+func (r *Runner) AddStage(name string, c transform.Client, o StageOptions) {
+	r.Append(NewTransformStage(name, c, o))
+}
+
+func (r *Runner) Append(st Stage) {
+	r.stagesMu.Lock()
+	r.stages = append(r.stages, st)
+	r.stagesMu.Unlock()
+}
+
+// ReplaceStages swaps the linear chain in place. Frames already in the old
+// chain finish first; the old stages are closed afterwards and the series
+// of those that are gone deleted.
+func (r *Runner) ReplaceStages(stages []Stage) {
+	r.stagesMu.Lock()
+	old := r.stages
+	r.stages = stages
+	r.stagesMu.Unlock()
+	closeStages(old)
+
+	gone, kept := map[string]bool{}, map[string]bool{}
+	stageNames(old, gone)
+	stageNames(stages, kept)
+	for name := range gone {
+		if !kept[name] {
+			telemetry.DeleteStage(r.pipelineID, name)
+		}
+	}
+}
+
+func NewTransformStage(name string, c transform.Client, o StageOptions) Stage {
+	st := &transformStage{
+		name:          name,
+		client:        c,
+		timeout:       o.Timeout,
+		retryAttempts: o.RetryAttempts,
+		retryBackoff:  o.RetryBackoff,
+		config:        o.Config,
+	}
+	if rc, ok := c.(interface {
+		NotifyReconnect(context.Context, func()) func()
+	}); ok {
+		st.unwatch = rc.NotifyReconnect(context.Background(), st.reset)
+	}
+	if o.When != nil {
+		return &conditionalStage{when: o.When, Stage: st}
+	}
+	return st
+}
+
+func (st *transformStage) Name() string { return st.name }
+func (st *transformStage) close() error {
+	if st.unwatch != nil {
+		st.unwatch()
+	}
+	return st.client.Close()
+}
+
+func (st *transformStage) health(ctx context.Context) []StageHealth {
+	h := StageHealth{Name: st.name}
+	resp, err := st.client.Health(ctx)
+	switch {
+	case err != nil:
+		h.Err = err.Error()
+	case !resp.GetOk():
+		h.Err = resp.GetDetails()
+	default:
+		h.Healthy = true
+	}
+	return []StageHealth{h}
+}
+
+func (st *transformStage) apply(ctx context.Context, r *Runner, in *pb.Frame) ([]*pb.Frame, error) {
+	start := time.Now()
+	telemetry.StageFramesIn.WithLabelValues(r.pipelineID, st.name).Inc()
+	ctx, span := telemetry.Tracer().Start(ctx, "stage "+st.name)
+	out, err := st.call(ctx, r, in)
+	span.SetAttributes(attribute.Int("quanta.frames_out", len(out)))
+	span.End()
+	telemetry.StageLatency.WithLabelValues(r.pipelineID, st.name).Observe(time.Since(start).Seconds())
+	telemetry.StageFramesOut.WithLabelValues(r.pipelineID, st.name).Add(float64(len(out)))
+	telemetry.StageFanout.WithLabelValues(r.pipelineID, st.name).Observe(float64(len(out)))
+	return out, err
+}
+
+// call runs one frame through the plugin with the stage's retry policy.
+// Dropped and failed frames need no ack here: pushFrame releases its hold on
+// the checkpoint once the frame has left the pipeline. A plugin that rejects
+// the stage config fails every frame the same way, so that is returned as an
+// error instead: the frame is not acked and the source backs off and
+// redelivers it until the plugin accepts the config.
+func (st *transformStage) call(ctx context.Context, r *Runner, in *pb.Frame) ([]*pb.Frame, error) {
+	var (
+		resp     *pb.TransformResponse
+		err      error
+		protocol int32
+	)
+
+	span := trace.SpanFromContext(ctx)
+	attempts := st.retryAttempts
+	for try := 0; ; try++ {
+		actx, aspan := telemetry.Tracer().Start(ctx, "transform "+st.name,
+			trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attribute.Int("quanta.attempt", try+1)))
+		actx = telemetry.InjectOutgoing(actx)
+		var cancel context.CancelFunc
+		if st.timeout > 0 {
+			actx, cancel = context.WithTimeout(actx, st.timeout)
+		}
+		protocol, err = st.prepare(actx, r.pipelineID)
+		if rej := (*configRejectedError)(nil); errors.As(err, &rej) {
+			if cancel != nil {
+				cancel()
+			}
+			spanError(aspan, err)
+			aspan.End()
+			telemetry.StageErrors.WithLabelValues(r.pipelineID, st.name).Inc()
+			spanError(span, err)
+			r.setErr(err)
+			return nil, err
+		}
+		if err == nil {
+			req := toRequest(in, protocol)
+			req.PipelineId = r.pipelineID
+			req.PluginId = st.name
+			resp, err = st.client.Transform(actx, req)
+		}
+		if cancel != nil {
+			cancel()
+		}
+		switch {
+		case err != nil:
+			spanError(aspan, err)
+		case resp.GetStatus() != pb.Status_OK && resp.GetStatus() != pb.Status_DROP:
+			aspan.SetStatus(otelcodes.Error, resp.GetStatus().String()+": "+resp.GetErrorMessage())
+		}
+		aspan.End()
+
+		if err != nil {
+			if try < attempts {
+				telemetry.StageRetries.WithLabelValues(r.pipelineID, st.name).Inc()
+				time.Sleep(st.retryBackoff)
+				continue
+			}
+			telemetry.StageErrors.WithLabelValues(r.pipelineID, st.name).Inc()
+			err = fmt.Errorf("stage %s: %w", st.name, err)
+			spanError(span, err)
+			r.setErr(err)
+			r.dropped(Drop{Frame: in, Stage: st.name, Cause: DropFailed, Reason: err.Error()})
+			return nil, nil
+		}
+
+		switch resp.GetStatus() {
+		case pb.Status_OK:
+			return toFrames(in, resp.GetEvents(), protocol), nil
+
+		case pb.Status_DROP:
+			telemetry.StageDrops.WithLabelValues(r.pipelineID, st.name).Inc()
+			span.AddEvent("drop")
+			r.dropped(Drop{Frame: in, Stage: st.name, Cause: DropFiltered, Reason: resp.GetErrorMessage()})
+			return nil, nil
+
+		default:
+			if try < attempts {
+				telemetry.StageRetries.WithLabelValues(r.pipelineID, st.name).Inc()
+				time.Sleep(st.retryBackoff)
+				continue
+			}
+			telemetry.StageErrors.WithLabelValues(r.pipelineID, st.name).Inc()
+			err = fmt.Errorf("stage %s: %s: %s", st.name, resp.GetStatus(), resp.GetErrorMessage())
+			spanError(span, err)
+			r.setErr(err)
+			r.dropped(Drop{Frame: in, Stage: st.name, Cause: DropFailed, Reason: err.Error()})
+			return nil, nil
+		}
+	}
+}
+
+// conditionalStage runs the wrapped stage only for frames matching when;
+// everything else passes through untouched.
+type conditionalStage struct {
+	when Predicate
+	Stage
+}
+
+func (c *conditionalStage) apply(ctx context.Context, r *Runner, in *pb.Frame) ([]*pb.Frame, error) {
+	if !c.when(in) {
+		return []*pb.Frame{in}, nil
+	}
+	return c.Stage.apply(ctx, r, in)
+}
+
+// Route is one named branch of a route stage.
+type Route struct {
+	Name   string
+	When   Predicate
+	Stages []Stage
+}
+
+// routeStage sends each frame down the first route whose predicate matches,
+// or down the default chain when none does. An empty default passes the
+// frame through.
+type routeStage struct {
+	name     string
+	routes   []Route
+	fallback []Stage
+}
+
+func NewRouteStage(name string, routes []Route, fallback []Stage, when Predicate) Stage {
+	st := &routeStage{name: name, routes: routes, fallback: fallback}
+	if when != nil {
+		return &conditionalStage{when: when, Stage: st}
+	}
+	return st
+}
+
+func (rs *routeStage) Name() string { return rs.name }
+
+func (rs *routeStage) apply(ctx context.Context, r *Runner, in *pb.Frame) ([]*pb.Frame, error) {
+	for _, rt := range rs.routes {
+		if rt.When == nil || rt.When(in) {
+			return runChain(ctx, r, rt.Stages, []*pb.Frame{in})
+		}
+	}
+	return runChain(ctx, r, rs.fallback, []*pb.Frame{in})
+}
+
+func (rs *routeStage) health(ctx context.Context) []StageHealth {
+	var out []StageHealth
+	add := func(branch string, stages []Stage) {
+		for _, st := range stages {
+			for _, h := range st.health(ctx) {
+				h.Name = rs.name + "/" + branch + "/" + h.Name
+				out = append(out, h)
+			}
+		}
+	}
+	for _, rt := range rs.routes {
+		add(rt.Name, rt.Stages)
+	}
+	add("default", rs.fallback)
+	return out
+}
+
+func (rs *routeStage) close() error {
+	for _, rt := range rs.routes {
+		closeStages(rt.Stages)
+	}
+	closeStages(rs.fallback)
+	return nil
+}
+
+func runChain(ctx context.Context, r *Runner, stages []Stage, frames []*pb.Frame) ([]*pb.Frame, error) {
+	for _, st := range stages {
+		next := make([]*pb.Frame, 0, len(frames))
+		for _, in := range frames {
+			out, err := st.apply(ctx, r, in)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		if r.taps.active() && len(next) > 0 {
+			r.taps.emit(r.pipelineID, "stage:"+st.Name(), next...)
+		}
+		frames = next
+		if len(frames) == 0 {
+			return nil, nil
+		}
+	}
+	return frames, nil
+}
+
+func closeStages(stages []Stage) {
+	for _, st := range stages {
+		_ = st.close()
+	}
+}
+
+func (st *transformStage) reset() {
+	logging.L().Info("transformer reconnected; renegotiating", "stage", st.name)
+	st.protocol.Store(0)
+	st.configured.Store(false)
+}
+
+func (st *transformStage) prepare(ctx context.Context, pipelineID string) (int32, error) {
+	protocol := st.negotiated()
+	if st.configured.Load() {
+		return protocol, nil
+	}
+	resp, err := st.client.Configure(ctx, &pb.ConfigureRequest{PipelineId: pipelineID, PluginId: st.name, Config: st.config})
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		if len(st.config.GetFields()) > 0 {
+			logging.L().Warn("transformer does not implement Configure; stage config ignored", "stage", st.name)
+		}
+	case err != nil:
+		return protocol, err
+	case !resp.GetOk():
+		return protocol, &configRejectedError{stage: st.name, msg: resp.GetErrorMessage()}
+	}
+	st.configured.Store(true)
+	return protocol, nil
+}
+
+// configRejectedError is a plugin's refusal of its stage config.
+type configRejectedError struct{ stage, msg string }
+
+func (e *configRejectedError) Error() string {
+	return fmt.Sprintf("transform %s rejected config: %s", e.stage, e.msg)
+}
+
+func (st *transformStage) negotiated() int32 {
+	if v := st.protocol.Load(); v != 0 {
+		return v
+	}
+	to := st.timeout
+	if to <= 0 {
+		to = time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), to)
+	defer cancel()
+	v, err := transform.Negotiate(ctx, st.client)
+	if err != nil {
+		logging.L().Debug("protocol negotiation failed; using v1", "stage", st.name, "err", err)
+		return transform.ProtocolV1
+	}
+	st.protocol.Store(v)
+	logging.L().Info("transformer protocol negotiated", "stage", st.name, "version", v)
+	return v
+}
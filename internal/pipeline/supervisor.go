@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"quanta/internal/logging"
+	"quanta/internal/telemetry"
+	"quanta/source/kafka"
+)
+
+const (
+	StateRunning    = "running"
+	StateRestarting = "restarting"
+	StateFailed     = "failed"
+	StateStopped    = "stopped"
+)
+
+var runnerStates = []string{StateRunning, StateRestarting, StateFailed, StateStopped}
+
+// SupervisorOptions controls how a runner restarts its source after a
+// retryable error. The backoff doubles from InitialBackoff up to MaxBackoff
+// and starts over once the source has stayed up for MaxBackoff. MaxRestarts
+// caps consecutive restarts; zero retries forever.
+type SupervisorOptions struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxRestarts    int
+}
+
+var DefaultSupervisorOptions = SupervisorOptions{
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+func (r *Runner) SetSupervisorOptions(o SupervisorOptions) { r.supervisor = o }
+
+// State reports whether the source is running, waiting to be restarted,
+// failed for good or stopped.
+func (r *Runner) State() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+func (r *Runner) setState(state string) {
+	r.mu.Lock()
+	if r.state == StateStopped {
+		r.mu.Unlock()
+		return
+	}
+	r.state = state
+	r.mu.Unlock()
+	telemetry.SetPipelineState(r.pipelineID, state, runnerStates...)
+}
+
+// supervise runs the source until ctx is done, restarting it with backoff
+// after retryable errors. It returns the error that made the pipeline fail,
+// or nil when it was stopped.
+func (r *Runner) supervise(ctx context.Context) error {
+	o := r.supervisor
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = DefaultSupervisorOptions.InitialBackoff
+	}
+	if o.MaxBackoff < o.InitialBackoff {
+		o.MaxBackoff = o.InitialBackoff
+	}
+	backoff, restarts := o.InitialBackoff, 0
+	for {
+		r.setState(StateRunning)
+		started := time.Now()
+		err := r.source.Run(ctx, r.pushFrame)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			err = errors.New("source stopped")
+		}
+		r.setErr(err)
+		if kafka.IsFatal(err) {
+			return err
+		}
+		if time.Since(started) >= o.MaxBackoff {
+			backoff, restarts = o.InitialBackoff, 0
+		}
+		if o.MaxRestarts > 0 && restarts >= o.MaxRestarts {
+			return fmt.Errorf("giving up after %d restarts: %w", restarts, err)
+		}
+		restarts++
+
+		r.setState(StateRestarting)
+		// The restarted source redelivers whatever it had not committed;
+		// the traces of those frames end here.
+		r.spans.endAll("source restarted before the frame was acked")
+		telemetry.SourceRestarts.WithLabelValues(r.pipelineID).Inc()
+		logging.L().Warn("source failed, restarting", "pipeline", r.pipelineID,
+			"attempt", restarts, "backoff", backoff, "err", err)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+		backoff = min(backoff*2, o.MaxBackoff)
+	}
+}
@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"quanta/source/kafka"
+
+	"github.com/IBM/sarama"
+)
+
+// flakySource fails with errs in turn, then runs until cancelled.
+type flakySource struct {
+	errs []error
+	runs atomic.Int32
+}
+
+func (s *flakySource) Configure(kafka.Config) error { return nil }
+func (s *flakySource) Close() error                 { return nil }
+func (s *flakySource) Run(ctx context.Context, _ kafka.EmitFunc) error {
+	n := int(s.runs.Add(1))
+	if n <= len(s.errs) {
+		return s.errs[n-1]
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func waitState(t *testing.T, r *Runner, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for r.State() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %q, want %q", r.State(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSupervisor_RestartsRetryableErrors(t *testing.T) {
+	src := &flakySource{errs: []error{sarama.ErrOutOfBrokers, sarama.ErrOutOfBrokers}}
+	r := NewRunner()
+	r.SetSource(src)
+	r.SetSupervisorOptions(SupervisorOptions{InitialBackoff: 5 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer r.Close()
+
+	for src.runs.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	waitState(t, r, StateRunning)
+	if r.Err() != nil || !errors.Is(r.LastError(), sarama.ErrOutOfBrokers) {
+		t.Fatalf("err=%v last=%v", r.Err(), r.LastError())
+	}
+	_ = r.Close()
+	if r.State() != StateStopped {
+		t.Fatalf("state after close = %q", r.State())
+	}
+}
+
+func TestSupervisor_FatalErrorFailsPipeline(t *testing.T) {
+	src := &flakySource{errs: []error{sarama.ErrSASLAuthenticationFailed}}
+	r := NewRunner()
+	r.SetSource(src)
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer r.Close()
+
+	select {
+	case <-r.Done():
+	case <-time.After(time.Second):
+		t.Fatal("fatal error was retried")
+	}
+	if !errors.Is(r.Err(), sarama.ErrSASLAuthenticationFailed) || r.State() != StateFailed {
+		t.Fatalf("err=%v state=%q", r.Err(), r.State())
+	}
+	if n := src.runs.Load(); n != 1 {
+		t.Fatalf("source ran %d times", n)
+	}
+}
+
+func TestSupervisor_GivesUpAfterMaxRestarts(t *testing.T) {
+	src := &flakySource{errs: []error{sarama.ErrOutOfBrokers, sarama.ErrOutOfBrokers, sarama.ErrOutOfBrokers}}
+	r := NewRunner()
+	r.SetSource(src)
+	r.SetSupervisorOptions(SupervisorOptions{InitialBackoff: time.Millisecond, MaxBackoff: time.Second, MaxRestarts: 2})
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer r.Close()
+
+	select {
+	case <-r.Done():
+	case <-time.After(time.Second):
+		t.Fatal("supervisor kept restarting")
+	}
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "giving up after 2 restarts") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if r.State() != StateFailed {
+		t.Fatalf("state = %q", r.State())
+	}
+}
@@ -0,0 +1,226 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/telemetry"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// TapPointSource is the tap point for frames as the source emitted them.
+const TapPointSource = "source"
+
+// DefaultTapBuffer is used when a tap does not ask for a buffer size.
+const DefaultTapBuffer = 256
+
+// TapOptions selects which frames a tap copies.
+type TapOptions struct {
+	// Key, when set, must equal the frame key.
+	Key []byte
+	// Headers must all be present on the frame; a non-empty value must
+	// also match.
+	Headers map[string]string
+	// SampleRate in (0, 1) keeps that share of matching frames; anything
+	// else keeps all of them.
+	SampleRate float64
+	// Buffer bounds the frames queued for the reader; DefaultTapBuffer when
+	// zero.
+	Buffer int
+}
+
+// Tap receives copies of the frames passing one point of a pipeline. It
+// never blocks the pipeline: frames that do not fit in the buffer are
+// dropped and counted.
+type Tap struct {
+	point   string
+	opts    TapOptions
+	ch      chan *pb.Frame
+	dropped atomic.Uint64
+	hub     *tapHub
+	once    sync.Once
+}
+
+// Point is the resolved tap point, e.g. "stage:enrich".
+func (t *Tap) Point() string { return t.point }
+
+// Frames yields the copied frames; it is closed when the tap or its runner
+// is closed.
+func (t *Tap) Frames() <-chan *pb.Frame { return t.ch }
+
+// Dropped returns the frames dropped since the previous call.
+func (t *Tap) Dropped() uint64 { return t.dropped.Swap(0) }
+
+// Close detaches the tap from its runner.
+func (t *Tap) Close() { t.hub.remove(t) }
+
+func (t *Tap) matches(f *pb.Frame) bool {
+	if t.opts.Key != nil && !bytes.Equal(t.opts.Key, f.GetKey()) {
+		return false
+	}
+	for k, want := range t.opts.Headers {
+		got, ok := f.GetHeaders()[k]
+		if !ok || (want != "" && string(got) != want) {
+			return false
+		}
+	}
+	if r := t.opts.SampleRate; r > 0 && r < 1 && rand.Float64() >= r {
+		return false
+	}
+	return true
+}
+
+// tapHub fans frames out to the taps attached to a runner. The frame path
+// only pays for an atomic load while nothing is attached.
+type tapHub struct {
+	n    atomic.Int32
+	mu   sync.RWMutex
+	taps map[*Tap]struct{}
+}
+
+func (h *tapHub) add(t *Tap) {
+	h.mu.Lock()
+	if h.taps == nil {
+		h.taps = map[*Tap]struct{}{}
+	}
+	h.taps[t] = struct{}{}
+	h.n.Store(int32(len(h.taps)))
+	h.mu.Unlock()
+}
+
+func (h *tapHub) remove(t *Tap) {
+	h.mu.Lock()
+	if _, ok := h.taps[t]; ok {
+		delete(h.taps, t)
+		h.n.Store(int32(len(h.taps)))
+	}
+	h.mu.Unlock()
+	t.once.Do(func() { close(t.ch) })
+}
+
+func (h *tapHub) closeAll() {
+	h.mu.Lock()
+	taps := h.taps
+	h.taps = nil
+	h.n.Store(0)
+	h.mu.Unlock()
+	for t := range taps {
+		t.once.Do(func() { close(t.ch) })
+	}
+}
+
+// active reports whether any tap is attached; callers check it before
+// building a point name.
+func (h *tapHub) active() bool { return h.n.Load() > 0 }
+
+// emit copies frames to every tap on point. Sends happen under the read
+// lock so a tap cannot be closed underneath them.
+func (h *tapHub) emit(pipelineID, point string, frames ...*pb.Frame) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for t := range h.taps {
+		if t.point != point {
+			continue
+		}
+		for _, f := range frames {
+			if !t.matches(f) {
+				continue
+			}
+			select {
+			case t.ch <- proto.Clone(f).(*pb.Frame):
+			default:
+				t.dropped.Add(1)
+				telemetry.TapDrops.WithLabelValues(pipelineID).Inc()
+			}
+		}
+	}
+}
+
+// Tap attaches a tap at point: TapPointSource, "stage:<name>" or
+// "sink:<name>". A numeric stage selects a top-level stage by position.
+func (r *Runner) Tap(point string, o TapOptions) (*Tap, error) {
+	point, err := r.resolveTapPoint(point)
+	if err != nil {
+		return nil, err
+	}
+	if o.Buffer <= 0 {
+		o.Buffer = DefaultTapBuffer
+	}
+	t := &Tap{point: point, opts: o, ch: make(chan *pb.Frame, o.Buffer), hub: &r.taps}
+	r.taps.add(t)
+	return t, nil
+}
+
+func (r *Runner) resolveTapPoint(point string) (string, error) {
+	if point == "" || point == TapPointSource {
+		return TapPointSource, nil
+	}
+	kind, name, ok := strings.Cut(point, ":")
+	if !ok || name == "" {
+		return "", fmt.Errorf("tap: invalid point %q: want source, stage:<name> or sink:<name>", point)
+	}
+	switch kind {
+	case "stage":
+		if i, err := strconv.Atoi(name); err == nil {
+			r.stagesMu.RLock()
+			defer r.stagesMu.RUnlock()
+			if i < 0 || i >= len(r.stages) {
+				return "", fmt.Errorf("tap: pipeline %s has %d top-level stages, no stage %d", r.pipelineID, len(r.stages), i)
+			}
+			return "stage:" + r.stages[i].Name(), nil
+		}
+		if _, ok := r.stageNames()[name]; !ok {
+			return "", fmt.Errorf("tap: pipeline %s has no stage %q", r.pipelineID, name)
+		}
+	case "sink":
+		found := false
+		for _, s := range r.allSinks() {
+			found = found || s.name == name
+		}
+		if !found {
+			return "", fmt.Errorf("tap: pipeline %s has no sink %q", r.pipelineID, name)
+		}
+	default:
+		return "", fmt.Errorf("tap: invalid point %q: want source, stage:<name> or sink:<name>", point)
+	}
+	return kind + ":" + name, nil
+}
+
+// stageNames lists every stage a frame can pass through, including those
+// nested in routes and graph nodes.
+func (r *Runner) stageNames() map[string]struct{} {
+	names := map[string]struct{}{}
+	var walk func([]Stage)
+	walk = func(stages []Stage) {
+		for _, st := range stages {
+			names[st.Name()] = struct{}{}
+			if c, ok := st.(*conditionalStage); ok {
+				st = c.Stage
+			}
+			if rs, ok := st.(*routeStage); ok {
+				for _, rt := range rs.routes {
+					walk(rt.Stages)
+				}
+				walk(rs.fallback)
+			}
+		}
+	}
+	r.stagesMu.RLock()
+	walk(r.stages)
+	r.stagesMu.RUnlock()
+	if r.graph != nil {
+		for _, n := range r.graph.order {
+			if n.kind == nodeStage {
+				walk([]Stage{n.stage})
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	pb "quanta/api/proto/v1"
+)
+
+func drainTap(tp *Tap) []*pb.Frame {
+	var out []*pb.Frame
+	for {
+		select {
+		case f := <-tp.Frames():
+			out = append(out, f)
+		default:
+			return out
+		}
+	}
+}
+
+func TestRunner_TapPointsAndFilters(t *testing.T) {
+	r := NewRunner()
+	r.SetPipelineID("tapped")
+	r.AddTransformer("t1", &fakeTransform{mode: "fanout2"}, 100*time.Millisecond, 0, 0)
+	s := &captureSink{}
+	s.BindAck(r.Ack)
+	r.AddNamedSink("out", s)
+
+	for _, bad := range []string{"stage:nope", "stage:3", "sink:nope", "queue:x", "stage:"} {
+		if _, err := r.Tap(bad, TapOptions{}); err == nil {
+			t.Fatalf("point %q: want error", bad)
+		}
+	}
+	src, err := r.Tap("source", TapOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage, err := r.Tap("stage:0", TapOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stage.Point() != "stage:t1" {
+		t.Fatalf("stage:0 resolved to %q", stage.Point())
+	}
+	keyed, err := r.Tap("sink:out", TapOptions{Key: []byte("k1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenant, err := r.Tap("source", TapOptions{Headers: map[string]string{"tenant": "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1 := makeFrame()
+	f1.Key, f1.Headers = []byte("k1"), map[string][]byte{"tenant": []byte("a")}
+	f2 := makeFrame()
+	f2.Key = []byte("k2")
+	for _, f := range []*pb.Frame{f1, f2} {
+		if err := r.pushFrame(f); err != nil {
+			t.Fatalf("pushFrame: %v", err)
+		}
+	}
+
+	if got := len(drainTap(src)); got != 2 {
+		t.Fatalf("source tap: want 2 frames, got %d", got)
+	}
+	if got := len(drainTap(stage)); got != 4 {
+		t.Fatalf("stage tap: want 4 frames after fanout, got %d", got)
+	}
+	if got := drainTap(tenant); len(got) != 1 || string(got[0].Key) != "k1" {
+		t.Fatalf("header-filtered tap: got %v", got)
+	}
+	got := drainTap(keyed)
+	if len(got) != 2 || string(got[0].Key) != "k1" {
+		t.Fatalf("key-filtered sink tap: got %v", got)
+	}
+	got[0].Value = []byte("mutated")
+	if string(s.pushed[0].Value) != "hello" {
+		t.Fatal("tap must receive copies, not the frames in flight")
+	}
+}
+
+func TestRunner_TapDropsInsteadOfBlocking(t *testing.T) {
+	r := NewRunner()
+	r.AddSink(&captureSink{})
+	tp, err := r.Tap(TapPointSource, TapOptions{Buffer: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := r.pushFrame(makeFrame()); err != nil {
+			t.Fatalf("pushFrame: %v", err)
+		}
+	}
+	if len(tp.Frames()) != 1 {
+		t.Fatalf("want a full buffer of 1, got %d", len(tp.Frames()))
+	}
+	if d := tp.Dropped(); d != 2 {
+		t.Fatalf("want 2 dropped, got %d", d)
+	}
+	if d := tp.Dropped(); d != 0 {
+		t.Fatalf("Dropped must reset, got %d", d)
+	}
+
+	_ = r.Close()
+	<-tp.Frames()
+	if _, ok := <-tp.Frames(); ok {
+		t.Fatal("closing the runner must close its taps")
+	}
+	tp.Close()
+}
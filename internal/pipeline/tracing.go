@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// frameSpans keeps each frame's root span open until its checkpoint is
+// acked upstream, so the trace covers the whole receive-to-ack lifetime.
+// Spans whose ack cannot come any more, because the source restarted or the
+// runner closed, are ended by endAll.
+type frameSpans struct {
+	mu sync.Mutex
+	m  map[*pb.CheckpointToken]trace.Span
+}
+
+func (s *frameSpans) put(tok *pb.CheckpointToken, span trace.Span) {
+	s.mu.Lock()
+	if s.m == nil {
+		s.m = map[*pb.CheckpointToken]trace.Span{}
+	}
+	s.m[tok] = span
+	s.mu.Unlock()
+}
+
+func (s *frameSpans) get(tok *pb.CheckpointToken) trace.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m[tok]
+}
+
+func (s *frameSpans) take(tok *pb.CheckpointToken) trace.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := s.m[tok]
+	delete(s.m, tok)
+	return span
+}
+
+// endAll ends and forgets every open span with reason as its error.
+func (s *frameSpans) endAll(reason string) {
+	s.mu.Lock()
+	spans := s.m
+	s.m = nil
+	s.mu.Unlock()
+	for _, span := range spans {
+		span.SetStatus(codes.Error, reason)
+		span.End()
+	}
+}
+
+// startFrame opens the root span of a frame, parented on any trace context
+// the producer left in the record headers.
+func (r *Runner) startFrame(f *pb.Frame) (context.Context, trace.Span) {
+	ctx := telemetry.ExtractHeaders(context.Background(), f.Headers)
+	attrs := []attribute.KeyValue{attribute.String("quanta.pipeline", r.pipelineID)}
+	if k := f.GetCheckpoint().GetKafka(); k != nil {
+		attrs = append(attrs,
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", k.Topic),
+			attribute.Int("messaging.kafka.destination.partition", int(k.Partition)),
+			attribute.Int64("messaging.kafka.message.offset", k.Offset),
+		)
+	}
+	ctx, span := telemetry.Tracer().Start(ctx, "frame",
+		trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(attrs...))
+	span.AddEvent("received")
+	if f.Checkpoint != nil {
+		r.spans.put(f.Checkpoint, span)
+	}
+	return ctx, span
+}
+
+// failFrame ends a frame's root span with err.
+func (r *Runner) failFrame(f *pb.Frame, span trace.Span, err error) {
+	r.spans.take(f.Checkpoint)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.End()
+}
+
+// dropEvent notes a drop on the root span of the dropped frame's source
+// record. The span still ends at the record's ack, which follows once
+// nothing derived from it is in flight.
+func (r *Runner) dropEvent(d Drop) {
+	if span := r.spans.get(d.Frame.GetCheckpoint()); span != nil {
+		span.AddEvent("dropped", trace.WithAttributes(
+			attribute.String("quanta.drop.cause", string(d.Cause)),
+			attribute.String("quanta.drop.stage", d.Stage),
+		))
+	}
+}
+
+// withHeaders returns a shallow copy of f carrying h instead of its headers.
+func withHeaders(f *pb.Frame, h map[string][]byte) *pb.Frame {
+	return &pb.Frame{Key: f.Key, Value: f.Value, Headers: h, Ts: f.Ts, Checkpoint: f.Checkpoint, Route: f.Route}
+}
+
+func spanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "quanta/api/proto/v1"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/metadata"
+)
+
+type traceTransform struct {
+	fakeTransform
+	traceparents []string
+}
+
+func (f *traceTransform) Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	f.traceparents = append(f.traceparents, strings.Join(md.Get("traceparent"), ","))
+	return f.fakeTransform.Transform(ctx, req)
+}
+
+func TestRunner_TracesFrameFromHeadersToAck(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(prevTP); otel.SetTextMapPropagator(prevProp) })
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	r := NewRunner()
+	r.SetPipelineID("traced")
+	plugin := &traceTransform{fakeTransform: fakeTransform{mode: "errorThenOK"}}
+	r.AddTransformer("t1", plugin, 100*time.Millisecond, 1, 0)
+	s := &heldSink{}
+	s.BindAck(r.Ack)
+	r.AddNamedSink("out", s)
+
+	f := makeFrame()
+	f.Headers = map[string][]byte{"traceparent": []byte("00-" + traceID + "-00f067aa0ba902b7-01")}
+	if err := r.pushFrame(f); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+
+	for _, tp := range plugin.traceparents {
+		if !strings.Contains(tp, traceID) {
+			t.Fatalf("plugin call without frame trace context: %q", tp)
+		}
+	}
+	if len(plugin.traceparents) != 2 {
+		t.Fatalf("want one traced call per attempt, got %v", plugin.traceparents)
+	}
+	if len(s.pushed) != 1 || !strings.Contains(string(s.pushed[0].Headers["traceparent"]), traceID) {
+		t.Fatalf("sink frame must carry the trace context in its headers")
+	}
+
+	names := func() map[string]int {
+		out := map[string]int{}
+		for _, sp := range rec.Ended() {
+			if sp.SpanContext().TraceID().String() != traceID {
+				t.Fatalf("span %s left the frame trace", sp.Name())
+			}
+			out[sp.Name()]++
+		}
+		return out
+	}
+	got := names()
+	if got["stage t1"] != 1 || got["transform t1"] != 2 || got["sink out"] != 1 || got["frame"] != 0 {
+		t.Fatalf("unexpected spans before ack: %v", got)
+	}
+
+	s.flush()
+	if names()["frame"] != 1 {
+		t.Fatal("frame span must end once the sink acks")
+	}
+	var frame sdktrace.ReadOnlySpan
+	for _, sp := range rec.Ended() {
+		if sp.Name() == "frame" {
+			frame = sp
+		}
+	}
+	events := frame.Events()
+	if len(events) == 0 || events[len(events)-1].Name != "ack" {
+		t.Fatalf("frame span must end with an ack event, got %v", events)
+	}
+}
+
+func TestRunner_TraceHeadersPerSinkAndSpansEndOnClose(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(prevTP); otel.SetTextMapPropagator(prevProp) })
+
+	r := NewRunner()
+	a, b := &heldSink{}, &heldSink{}
+	a.BindAck(r.Ack)
+	b.BindAck(r.Ack)
+	r.AddNamedSink("a", a)
+	r.AddNamedSink("b", b)
+
+	f := makeFrame()
+	f.Headers = map[string][]byte{"traceparent": []byte("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")}
+	if err := r.pushFrame(f); err != nil {
+		t.Fatalf("pushFrame: %v", err)
+	}
+	ta, tb := string(a.pushed[0].Headers["traceparent"]), string(b.pushed[0].Headers["traceparent"])
+	if ta == tb {
+		t.Fatalf("sinks must each carry their own sink span, both got %q", ta)
+	}
+	if string(f.Headers["traceparent"]) != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("source frame headers were rewritten: %q", f.Headers["traceparent"])
+	}
+
+	_ = r.Close()
+	var frame sdktrace.ReadOnlySpan
+	for _, sp := range rec.Ended() {
+		if sp.Name() == "frame" {
+			frame = sp
+		}
+	}
+	if frame == nil || frame.Status().Description == "" {
+		t.Fatal("an unacked frame span must end with an error when the runner closes")
+	}
+	r.spans.mu.Lock()
+	n := len(r.spans.m)
+	r.spans.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("closed runner still holds %d frame spans", n)
+	}
+}
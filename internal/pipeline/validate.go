@@ -0,0 +1,220 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/config"
+	"quanta/internal/spec"
+	"quanta/internal/transform"
+	"quanta/sink"
+	"quanta/source"
+	"quanta/source/kafka"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Validate checks a parsed spec without dialling plugins, launching exec
+// transformers or connecting to Kafka: the source kind and driver, that the
+// source config exists and parses, every stage's type, required fields and
+// predicates, the sinks' types and configs and the graph's shape. Every
+// problem found is returned, joined, so one run reports all of them.
+func Validate(cfg spec.File, confPath string) error {
+	errs := validateSource(cfg, confPath)
+
+	seen := map[string]bool{}
+	for i, s := range cfg.Sinks {
+		at := fmt.Sprintf("sinks[%d] %s", i, s.Name)
+		if s.Name == "" {
+			errs = append(errs, fmt.Errorf("sinks[%d]: name is required", i))
+		} else if seen[s.Name] {
+			errs = append(errs, fmt.Errorf("%s: listed twice", at))
+		}
+		seen[s.Name] = true
+		if err := validateSink(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", at, err))
+		}
+	}
+	if cfg.Graph != nil {
+		if len(cfg.Transformers) > 0 {
+			errs = append(errs, errors.New("graph cannot be combined with transformers"))
+		}
+		return errors.Join(append(errs, validateGraph(cfg)...)...)
+	}
+	errs = append(errs, validateStages("transformers", cfg.Transformers)...)
+	if len(cfg.Sinks) == 0 {
+		errs = append(errs, errors.New("sinks: at least one sink is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// validateStages checks a list of transformer specs, descending into routes.
+// path names the list in error messages.
+func validateStages(path string, specs []spec.TransformerSpec) []error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, t := range specs {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		if t.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", at))
+		} else {
+			at += " " + t.Name
+			if seen[t.Name] {
+				errs = append(errs, fmt.Errorf("%s: duplicate stage name", at))
+			}
+			seen[t.Name] = true
+		}
+		errs = append(errs, validateStage(at, t)...)
+	}
+	return errs
+}
+
+func validateStage(at string, t spec.TransformerSpec) []error {
+	var errs []error
+	if _, err := CompilePredicate(t.When); err != nil {
+		errs = append(errs, fmt.Errorf("%s: when: %w", at, err))
+	}
+	switch t.Type {
+	case "grpc":
+		if t.Address == "" {
+			errs = append(errs, fmt.Errorf("%s: grpc requires address", at))
+		}
+	case "exec":
+		if t.Command == "" {
+			errs = append(errs, fmt.Errorf("%s: exec requires command", at))
+		}
+	case "inproc":
+		if !transform.HasInProcess(t.InProcessName()) {
+			errs = append(errs, fmt.Errorf("%s: unknown in-process transformer %q", at, t.InProcessName()))
+		}
+	case "route":
+		if len(t.Routes) == 0 {
+			errs = append(errs, fmt.Errorf("%s: at least one route is required", at))
+		}
+		seen := map[string]bool{}
+		for i, rs := range t.Routes {
+			if rs.Name == "" {
+				errs = append(errs, fmt.Errorf("%s: route %d has no name", at, i))
+				continue
+			}
+			if seen[rs.Name] {
+				errs = append(errs, fmt.Errorf("%s: duplicate route %q", at, rs.Name))
+			}
+			seen[rs.Name] = true
+			if _, err := CompilePredicate(rs.When); err != nil {
+				errs = append(errs, fmt.Errorf("%s/%s: when: %w", at, rs.Name, err))
+			}
+			errs = append(errs, validateStages(at+"/"+rs.Name, rs.Transformers)...)
+		}
+		errs = append(errs, validateStages(at+"/default", t.Default)...)
+		return errs
+	default:
+		errs = append(errs, fmt.Errorf("%s: unsupported transformer type %q", at, t.Type))
+	}
+	if t.TimeoutMS < 0 || t.RetryPolicy.Attempts < 0 || t.RetryPolicy.BackoffMS < 0 {
+		errs = append(errs, fmt.Errorf("%s: timeout_ms and retry_policy must not be negative", at))
+	}
+	if len(t.Config) > 0 {
+		if _, err := structpb.NewStruct(t.Config); err != nil {
+			errs = append(errs, fmt.Errorf("%s: config: %w", at, err))
+		}
+	}
+	return errs
+}
+
+// validateSource checks the source kind is known and, for Kafka, the driver
+// and config. A registered source is made and closed again, unconfigured.
+func validateSource(cfg spec.File, confPath string) []error {
+	if cfg.Source.Kind != "kafka" {
+		src, err := source.New(cfg.Source.Kind)
+		if err != nil {
+			return []error{fmt.Errorf("source: %w", err)}
+		}
+		_ = src.Close()
+		return nil
+	}
+	var errs []error
+	if _, err := kafka.NewAdapter(cfg.Source.Driver); err != nil {
+		errs = append(errs, fmt.Errorf("source: %w", err))
+	}
+	if confPath != "" {
+		// The engine tolerates a missing source config and relies on the
+		// QUANTA_KAFKA__ overlay; a spec that names one should have it.
+		if _, err := os.Stat(confPath); err != nil {
+			errs = append(errs, fmt.Errorf("source: config: %w", err))
+		} else if _, err := config.LoadKafkaConfig(confPath); err != nil {
+			errs = append(errs, fmt.Errorf("source: config %s: %w", confPath, err))
+		}
+	} else if _, err := config.LoadSourceConfig(cfg, ""); err != nil {
+		errs = append(errs, fmt.Errorf("source: config: %w", err))
+	}
+	return errs
+}
+
+// validateSink checks the sink's type is registered and decodes its config
+// the way the compiler would, without configuring it: configuring a kafka
+// sink connects to the brokers.
+func validateSink(s spec.SinkSpec) error {
+	if _, err := sink.NewAdapter(s.Type); err != nil {
+		return err
+	}
+	_, err := sinkConfig(s)
+	return err
+}
+
+// validateGraph checks the graph's nodes and builds its shape with
+// placeholder stages and sinks, so cycles, unknown edges and unreachable
+// nodes are found the same way SetGraph finds them.
+func validateGraph(cfg spec.File) []error {
+	var errs []error
+	used := map[string]bool{}
+	g := NewGraph()
+	for i, n := range cfg.Graph.Nodes {
+		at := fmt.Sprintf("graph.nodes[%d] %s", i, n.Name)
+		var err error
+		switch n.Type {
+		case "merge":
+			err = g.AddMerge(n.Name)
+		case "sink":
+			name := n.SinkName()
+			if _, serr := cfg.SinkNamed(name); serr != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", at, serr))
+			}
+			used[name] = true
+			err = g.AddSink(n.Name, &memorySink{name: n.Name})
+		default:
+			errs = append(errs, validateStage(at, n.TransformerSpec)...)
+			err = g.AddStage(shapeStage(n.Name))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", at, err))
+		}
+	}
+	for _, s := range cfg.Sinks {
+		if !used[s.Name] {
+			errs = append(errs, fmt.Errorf("sinks: %q is not used by any graph node", s.Name))
+		}
+	}
+	for _, e := range cfg.Graph.Edges {
+		if err := g.Connect(e.From, e.To); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		if err := g.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// shapeStage stands in for a stage when only the graph's shape matters.
+type shapeStage string
+
+func (s shapeStage) Name() string                                                 { return string(s) }
+func (shapeStage) apply(context.Context, *Runner, *pb.Frame) ([]*pb.Frame, error) { return nil, nil }
+func (shapeStage) health(context.Context) []StageHealth                           { return nil }
+func (shapeStage) close() error                                                   { return nil }
@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/config"
+	"quanta/sdk"
+	"quanta/source/kafka"
+
+	"google.golang.org/grpc"
+)
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	kafka.Register("sarama", func() kafka.Adapter { return &kafka.SaramaDriver{} })
+	dir := t.TempDir()
+	cfg, confPath, err := config.ParsePipelineSpec([]byte(`schema_version: v1
+source: { kind: kafka, driver: sarama, config: missing.yml }
+transformers:
+  - { name: enrich, type: grpc }
+  - { name: enrich, type: exec, command: ./plugin }
+  - { name: split, type: route, routes: [{ name: a, when: { header: x, matches: "(" } }] }
+  - { name: odd, type: lambda }
+sinks: [stdout, nope]
+`), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Validate(cfg, confPath)
+	if err == nil {
+		t.Fatal("want errors")
+	}
+	for _, want := range []string{
+		"source: config",
+		"transformers[0] enrich: grpc requires address",
+		"transformers[1] enrich: duplicate stage name",
+		"transformers[2] split/a: when",
+		`transformers[3] odd: unsupported transformer type "lambda"`,
+		`unknown sink "nope"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "kafka.yml"), []byte("brokers: [localhost:9092]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, confPath, err = config.ParsePipelineSpec([]byte(`source: { kind: kafka, driver: sarama, config: kafka.yml }
+graph:
+  nodes:
+    - { name: a, type: grpc, address: "localhost:1" }
+    - { name: b, type: grpc, address: "localhost:1" }
+    - { name: out, type: sink, sink: stdout }
+  edges:
+    - { from: source, to: a }
+    - { from: a, to: b }
+    - { from: b, to: a }
+    - { from: b, to: out }
+`), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(cfg, confPath); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("want a cycle error, got %v", err)
+	}
+
+	cfg, confPath, err = config.ParsePipelineSpec([]byte(`schema_version: v2
+source: { kind: kafka, driver: sarama, config: { brokers: [localhost:9092], topic: typo } }
+graph:
+  nodes:
+    - { name: out, type: sink }
+    - { name: mirror, type: sink, sink: replica }
+  edges:
+    - { from: source, to: out }
+    - { from: source, to: mirror }
+sinks:
+  - { name: out, type: stdout, config: { ack_batch: 1 } }
+  - { name: spare, type: kafka, config: { brokers: [localhost:9092] } }
+`), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Validate(cfg, confPath)
+	for _, want := range []string{
+		"unknown kafka config keys: topic",
+		"sinks[0] out: config: field ack_batch not found",
+		"sinks[1] spare: config: kafka sink requires brokers and topic",
+		`graph.nodes[1] mirror: unknown sink "replica"`,
+		`sinks: "spare" is not used by any graph node`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestDryRun_ReportsOutputsDropsAndAcks(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := grpc.NewServer()
+	pb.RegisterTransformServiceServer(g, sdk.NewServer(func(_ context.Context, ev sdk.Event) ([]sdk.Event, sdk.Status, error) {
+		if string(ev.Value) == "drop" {
+			return nil, sdk.StatusDrop, nil
+		}
+		ev.Value = []byte(strings.ToUpper(string(ev.Value)))
+		return []sdk.Event{ev}, sdk.StatusOK, nil
+	}))
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	cfg, _, err := config.ParsePipelineSpec([]byte(`name: dry
+source: { kind: kafka, driver: sarama }
+transformers:
+  - { name: upper, type: grpc, address: "`+lis.Addr().String()+`", timeout_ms: 2000 }
+sinks: [stdout]
+`), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	fixtures := filepath.Join(dir, "frames.yml")
+	if err := os.WriteFile(fixtures, []byte("- { key: k, value: hello }\n- { value: drop, offset: 9 }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	frames, err := LoadFixtures(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := DryRun(context.Background(), cfg, frames)
+	if err != nil {
+		t.Fatalf("DryRun: %v", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("want 2 results, got %d", len(res))
+	}
+	if r := res[0]; r.Err != nil || len(r.Outputs) != 1 || r.Outputs[0].Sink != "stdout" || string(r.Outputs[0].Frame.Value) != "HELLO" || !r.Acked {
+		t.Fatalf("first frame: %+v", r)
+	}
+	if r := res[1]; r.Err != nil || len(r.Outputs) != 0 || !r.Acked || r.Input.GetCheckpoint().GetKafka().GetOffset() != 9 {
+		t.Fatalf("dropped frame must be acked without outputs: %+v", r)
+	}
+
+	if err := os.WriteFile(fixtures, []byte("- { value: x, partiton: 1 }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFixtures(fixtures); err == nil || !strings.Contains(err.Error(), "partiton") {
+		t.Fatalf("want unknown field error, got %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+// Package registry persists pipelines deployed over the control plane so an
+// engine can restore them after a restart.
+//
+// Each pipeline is a directory under the registry root holding one file per
+// spec version (v000001.yml, ...) and a meta.json with the version history,
+// the current version and whether the pipeline is paused. Files are replaced
+// atomically, so a crash leaves either the old or the new state.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("pipeline not in registry")
+
+// Version is one deployed revision of a pipeline spec.
+type Version struct {
+	Version    int       `json:"version"`
+	DeployedAt time.Time `json:"deployed_at"`
+	DeployedBy string    `json:"deployed_by,omitempty"`
+	// BaseDir is the deploy request's base_dir, recorded by engines that
+	// resolved relative paths against it. Deployed specs are now
+	// self-contained and it is no longer written or read.
+	BaseDir string `json:"base_dir,omitempty"`
+	// RollbackOf is the version this one restored, if it is a rollback.
+	RollbackOf int `json:"rollback_of,omitempty"`
+}
+
+// Record is a pipeline's stored state.
+type Record struct {
+	ID       string    `json:"id"`
+	Current  int       `json:"current"`
+	Paused   bool      `json:"paused"`
+	Versions []Version `json:"versions"`
+}
+
+// Version returns the entry for version v.
+func (r Record) Version(v int) (Version, bool) {
+	for _, ver := range r.Versions {
+		if ver.Version == v {
+			return ver, true
+		}
+	}
+	return Version{}, false
+}
+
+// Store is a registry rooted at a directory.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Open creates dir if needed and returns the store in it.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("registry: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Put stores raw as the next version of id and makes it current.
+func (s *Store) Put(id string, raw []byte, v Version) (Version, error) {
+	if err := checkID(id); err != nil {
+		return Version{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, err := s.read(id)
+	if errors.Is(err, ErrNotFound) {
+		rec, err = Record{ID: id}, os.MkdirAll(filepath.Join(s.dir, id), 0o700)
+	}
+	if err != nil {
+		return Version{}, err
+	}
+	v.Version = 1
+	if n := len(rec.Versions); n > 0 {
+		v.Version = rec.Versions[n-1].Version + 1
+	}
+	if v.DeployedAt.IsZero() {
+		v.DeployedAt = time.Now().UTC()
+	}
+	if err := writeFile(s.specPath(id, v.Version), raw); err != nil {
+		return Version{}, err
+	}
+	rec.Versions = append(rec.Versions, v)
+	rec.Current = v.Version
+	return v, s.write(rec)
+}
+
+// SetPaused records whether id should come back paused.
+func (s *Store) SetPaused(id string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, err := s.read(id)
+	if err != nil {
+		return err
+	}
+	if rec.Paused == paused {
+		return nil
+	}
+	rec.Paused = paused
+	return s.write(rec)
+}
+
+// Delete removes id and its history.
+func (s *Store) Delete(id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.read(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(s.dir, id))
+}
+
+// Get returns id's record, or an error wrapping ErrNotFound.
+func (s *Store) Get(id string) (Record, error) {
+	if err := checkID(id); err != nil {
+		return Record{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(id)
+}
+
+// Spec returns the spec stored as version v of id.
+func (s *Store) Spec(id string, v int) ([]byte, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(s.specPath(id, v))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s version %d", ErrNotFound, id, v)
+	}
+	return raw, err
+}
+
+// List returns every stored pipeline, sorted by id.
+func (s *Store) List() ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("registry: %w", err)
+	}
+	var out []Record
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		rec, err := s.read(e.Name())
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) read(id string) (Record, error) {
+	raw, err := os.ReadFile(filepath.Join(s.dir, id, "meta.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Record{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("registry: %w", err)
+	}
+	var rec Record
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return Record{}, fmt.Errorf("registry: %s: %w", id, err)
+	}
+	return rec, nil
+}
+
+func (s *Store) write(rec Record) error {
+	raw, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(s.dir, rec.ID, "meta.json"), raw)
+}
+
+func (s *Store) specPath(id string, v int) string {
+	return filepath.Join(s.dir, id, fmt.Sprintf("v%06d.yml", v))
+}
+
+// checkID keeps ids from escaping the registry directory.
+func checkID(id string) error {
+	if id == "" || id == "." || id == ".." || filepath.Base(id) != id {
+		return fmt.Errorf("registry: invalid pipeline id %q", id)
+	}
+	return nil
+}
+
+// writeFile replaces path atomically.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("registry: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("registry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+	return nil
+}
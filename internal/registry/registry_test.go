@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStore_VersionsSurviveReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Put("orders", []byte("a: 1\n"), Version{DeployedBy: "ci", BaseDir: "/etc/quanta"}); err != nil || v.Version != 1 {
+		t.Fatalf("first put: %+v %v", v, err)
+	}
+	if v, err := s.Put("orders", []byte("a: 2\n"), Version{RollbackOf: 1}); err != nil || v.Version != 2 {
+		t.Fatalf("second put: %+v %v", v, err)
+	}
+	if err := s.SetPaused("orders", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put("billing", []byte("b: 1\n"), Version{}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, err := s.List()
+	if err != nil || len(recs) != 2 || recs[0].ID != "billing" || recs[1].ID != "orders" {
+		t.Fatalf("List: %+v %v", recs, err)
+	}
+	rec := recs[1]
+	if rec.Current != 2 || !rec.Paused || len(rec.Versions) != 2 {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	if v, _ := rec.Version(1); v.DeployedBy != "ci" || v.BaseDir != "/etc/quanta" || v.DeployedAt.IsZero() {
+		t.Fatalf("version 1: %+v", v)
+	}
+	if v, _ := rec.Version(2); v.RollbackOf != 1 {
+		t.Fatalf("version 2: %+v", v)
+	}
+	if raw, err := s.Spec("orders", 1); err != nil || string(raw) != "a: 1\n" {
+		t.Fatalf("Spec: %q %v", raw, err)
+	}
+
+	if err := s.Delete("orders"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("orders"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted pipeline: want ErrNotFound, got %v", err)
+	}
+	if err := s.SetPaused("orders", false); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("SetPaused on deleted pipeline: want ErrNotFound, got %v", err)
+	}
+	if _, err := s.Spec("orders", 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Spec of deleted pipeline: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestStore_RejectsIDsOutsideTheDirectory(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"", ".", "..", "../x", "a/b"} {
+		if _, err := s.Put(id, []byte("x"), Version{}); err == nil {
+			t.Errorf("Put(%q): want error", id)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Package secrets resolves ${ENV:NAME} and ${FILE:path} references in
+// config values and remembers the secret ones resolved to, so logs can
+// redact them. A value is secret when it comes from a file or lands under a
+// credential-like key such as sasl_pass; other references, such as a topic
+// or an environment name, are plain configuration and are logged as is.
+//
+// References are expanded after a config is decoded, inside string values
+// only, so a secret containing YAML syntax cannot change the document's
+// structure. "$${" is a literal "${".
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// Redacted replaces resolved values in redacted text.
+const Redacted = "[REDACTED]"
+
+// minRedactLen is the shortest value a secret reference may resolve to.
+// Shorter ones could not be redacted without blanking out unrelated log
+// text, so they are rejected rather than logged in the clear.
+const minRedactLen = 6
+
+// sensitiveKeys are substrings of config keys whose values are secret.
+var sensitiveKeys = []string{"pass", "secret", "token", "credential", "private", "api_key", "apikey", "auth"}
+
+// SensitiveKey reports whether values under the config key k hold
+// credentials.
+func SensitiveKey(k string) bool {
+	k = strings.ToLower(k)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mu     sync.RWMutex
+	values = map[string]bool{}
+	// sorted lists values longest first so a value containing another is
+	// replaced whole.
+	sorted []string
+)
+
+func remember(v string) {
+	if v == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if values[v] {
+		return
+	}
+	values[v] = true
+	sorted = append(sorted, v)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+}
+
+// Redact replaces every value resolved so far in s with Redacted.
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, v := range sorted {
+		if strings.Contains(s, v) {
+			s = strings.ReplaceAll(s, v, Redacted)
+		}
+	}
+	return s
+}
+
+// Active reports whether any value has been resolved, letting callers skip
+// redaction entirely otherwise.
+func Active() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(sorted) > 0
+}
+
+// Scope says what references may read. The zero Scope, used for configs
+// read from the engine host's own disk, allows any variable and any file.
+type Scope struct {
+	// BaseDir resolves relative FILE paths.
+	BaseDir string
+	// Confined rejects ENV references and FILE paths outside BaseDir, for
+	// configs that arrive over the network. A confined scope without a
+	// BaseDir rejects FILE references too.
+	Confined bool
+}
+
+// Expand resolves the references in s. Relative FILE paths are taken from
+// baseDir; one trailing newline is trimmed from file contents, as mounted
+// secrets usually end with one. Only FILE values are treated as secret, as
+// s has no key to judge by.
+func Expand(s, baseDir string) (string, error) {
+	return expand(s, Scope{BaseDir: baseDir}, false)
+}
+
+// expand resolves the references in s; sensitive says s sits under a
+// credential-like key, which makes every value it resolves secret.
+func expand(s string, sc Scope, sensitive bool) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated reference in %q", s[i:])
+		}
+		ref := s[i+2 : i+end]
+		v, err := resolve(ref, sc)
+		secret := sensitive || strings.HasPrefix(ref, "FILE:")
+		if err == nil && secret && v != "" && len(v) < minRedactLen {
+			err = fmt.Errorf("resolves to a secret of fewer than %d bytes, too short to redact from logs", minRedactLen)
+		}
+		if err != nil {
+			return "", fmt.Errorf("${%s}: %w", ref, err)
+		}
+		if secret {
+			remember(v)
+		}
+		b.WriteString(v)
+		s = s[i+end+1:]
+	}
+}
+
+func resolve(ref string, sc Scope) (string, error) {
+	kind, arg, ok := strings.Cut(ref, ":")
+	if !ok || arg == "" {
+		return "", fmt.Errorf("want ENV:NAME or FILE:path")
+	}
+	var (
+		raw []byte
+		err error
+	)
+	switch kind {
+	case "ENV":
+		if sc.Confined {
+			return "", errors.New("environment references are not allowed here")
+		}
+		v, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", fmt.Errorf("%s is not set", arg)
+		}
+		return v, nil
+	case "FILE":
+		if sc.Confined {
+			raw, err = readConfined(sc.BaseDir, arg)
+		} else {
+			if !filepath.IsAbs(arg) && sc.BaseDir != "" {
+				arg = filepath.Join(sc.BaseDir, arg)
+			}
+			raw, err = os.ReadFile(arg)
+		}
+		if err != nil {
+			return "", err
+		}
+		v := strings.TrimSuffix(string(raw), "\n")
+		return strings.TrimSuffix(v, "\r"), nil
+	}
+	return "", fmt.Errorf("unknown reference kind %q (want ENV or FILE)", kind)
+}
+
+// readConfined reads path, relative to dir or absolute within it, refusing
+// anything that leaves dir, including through symlinks.
+func readConfined(dir, path string) ([]byte, error) {
+	if dir == "" {
+		return nil, errors.New("file references are not allowed here")
+	}
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		path = rel
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	f, err := root.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// ExpandAll resolves references in every string reachable from v, which
+// must be a pointer: struct fields, slices, map values and interfaces,
+// including the map[string]any trees YAML decodes into. Values under a
+// credential-like field or map key are secret.
+func ExpandAll(v any, baseDir string) error {
+	return ExpandAllIn(v, Scope{BaseDir: baseDir})
+}
+
+// ExpandAllIn is ExpandAll with references limited to sc.
+func ExpandAllIn(v any, sc Scope) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("secrets: ExpandAll needs a non-nil pointer, got %T", v)
+	}
+	return expandValue(rv.Elem(), sc, false)
+}
+
+func expandValue(v reflect.Value, sc Scope, sensitive bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		s, err := expand(v.String(), sc, sensitive)
+		if err != nil {
+			return err
+		}
+		if v.CanSet() {
+			v.SetString(s)
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return expandValue(v.Elem(), sc, sensitive)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.IsExported() {
+				key := sensitive || SensitiveKey(f.Name) || SensitiveKey(f.Tag.Get("yaml"))
+				if err := expandValue(v.Field(i), sc, key); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := expandValue(v.Index(i), sc, sensitive); err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		// Values inside an interface are not addressable; expand a copy
+		// and store it back.
+		cp := reflect.New(v.Elem().Type()).Elem()
+		cp.Set(v.Elem())
+		if err := expandValue(cp, sc, sensitive); err != nil {
+			return err
+		}
+		if v.CanSet() {
+			v.Set(cp)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			cp := reflect.New(iter.Value().Type()).Elem()
+			cp.Set(iter.Value())
+			key := sensitive || SensitiveKey(fmt.Sprint(iter.Key().Interface()))
+			if err := expandValue(cp, sc, key); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), cp)
+		}
+	}
+	return nil
+}
+
+// ExpandKoanf resolves references in every string and string list loaded
+// into k. Values under a credential-like key are secret.
+func ExpandKoanf(k *koanf.Koanf, baseDir string) error {
+	sc := Scope{BaseDir: baseDir}
+	for _, key := range k.Keys() {
+		var (
+			v   any
+			err error
+		)
+		sensitive := SensitiveKey(key)
+		switch raw := k.Get(key).(type) {
+		case string:
+			v, err = expand(raw, sc, sensitive)
+		case []any, []string:
+			var list []string
+			for _, item := range k.Strings(key) {
+				s, xerr := expand(item, sc, sensitive)
+				if xerr != nil {
+					err = xerr
+					break
+				}
+				list = append(list, s)
+			}
+			v = list
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if err := k.Set(key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpand_ResolvesEnvAndFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pass"), []byte("s3cr3t-from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("QUANTA_TEST_HOST", "broker-a")
+
+	got, err := Expand("${ENV:QUANTA_TEST_HOST}:9092/${FILE:pass} $${ENV:X}", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "broker-a:9092/s3cr3t-from-file ${ENV:X}"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if red := Redact("login with s3cr3t-from-file failed"); red != "login with "+Redacted+" failed" {
+		t.Fatalf("not redacted: %q", red)
+	}
+
+	for in, want := range map[string]string{
+		"${ENV:QUANTA_TEST_UNSET}": "QUANTA_TEST_UNSET is not set",
+		"${FILE:missing}":          "missing",
+		"${VAULT:x}":               "unknown reference kind",
+		"${ENV:X":                  "unterminated",
+	} {
+		if _, err := Expand(in, dir); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expand(%q) = %v, want error containing %q", in, err, want)
+		}
+	}
+}
+
+func TestExpandAll_WalksStructsSlicesAndMaps(t *testing.T) {
+	t.Setenv("QUANTA_TEST_TOKEN", "token-123456")
+	type stage struct {
+		Name   string
+		Config map[string]any
+	}
+	v := struct {
+		Stages []stage
+		Ptr    *string
+		hidden string
+	}{
+		Stages: []stage{{Name: "${ENV:QUANTA_TEST_TOKEN}", Config: map[string]any{
+			"auth":  "Bearer ${ENV:QUANTA_TEST_TOKEN}",
+			"list":  []any{"${ENV:QUANTA_TEST_TOKEN}", 3},
+			"inner": map[string]any{"k": "${ENV:QUANTA_TEST_TOKEN}"},
+		}}},
+		Ptr:    new(string),
+		hidden: "${ENV:QUANTA_TEST_UNSET}",
+	}
+	*v.Ptr = "${ENV:QUANTA_TEST_TOKEN}"
+	if err := ExpandAll(&v, ""); err != nil {
+		t.Fatal(err)
+	}
+	c := v.Stages[0].Config
+	if v.Stages[0].Name != "token-123456" || *v.Ptr != "token-123456" || c["auth"] != "Bearer token-123456" ||
+		c["list"].([]any)[0] != "token-123456" || c["inner"].(map[string]any)["k"] != "token-123456" {
+		t.Fatalf("not expanded: %+v", v)
+	}
+}
+
+func TestExpandAllIn_ConfinedScope(t *testing.T) {
+	dir := t.TempDir()
+	secretsDir := filepath.Join(dir, "secrets")
+	if err := os.Mkdir(secretsDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(secretsDir, "token"), []byte("token-in-dir\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "host"), []byte("host-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "host"), filepath.Join(secretsDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("QUANTA_TEST_TOKEN", "token-123456")
+
+	sc := Scope{BaseDir: secretsDir, Confined: true}
+	v := struct{ A, B string }{"${FILE:token}", "${FILE:" + filepath.Join(secretsDir, "token") + "}"}
+	if err := ExpandAllIn(&v, sc); err != nil || v.A != "token-in-dir" || v.B != "token-in-dir" {
+		t.Fatalf("got %+v, %v", v, err)
+	}
+	for in, want := range map[string]string{
+		"${ENV:QUANTA_TEST_TOKEN}":                   "not allowed",
+		"${FILE:../host}":                            "escapes",
+		"${FILE:" + filepath.Join(dir, "host") + "}": "escapes",
+		"${FILE:link}":                               "escapes",
+	} {
+		s := in
+		if err := ExpandAllIn(&s, sc); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want an error containing %q", in, err, want)
+		}
+	}
+	s := "${FILE:token}"
+	if err := ExpandAllIn(&s, Scope{Confined: true}); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("want FILE rejected without a directory, got %v", err)
+	}
+}
+
+func TestExpand_RedactsOnlySecretValues(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "short"), []byte("abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("QUANTA_TEST_ENVNAME", "e2e")
+	t.Setenv("QUANTA_TEST_TOPIC", "orders-e2e-topic")
+	t.Setenv("QUANTA_TEST_PASS", "pass-123456")
+	t.Setenv("QUANTA_TEST_EMPTY", "")
+
+	// Plain configuration may be short and stays readable in logs.
+	v := map[string]any{"env": "${ENV:QUANTA_TEST_ENVNAME}", "topic": "${ENV:QUANTA_TEST_TOPIC}"}
+	if err := ExpandAll(&v, dir); err != nil || v["env"] != "e2e" {
+		t.Fatalf("got %v, %v", v, err)
+	}
+	if red := Redact("consuming orders-e2e-topic in e2e"); red != "consuming orders-e2e-topic in e2e" {
+		t.Fatalf("non-secret values must not be redacted: %q", red)
+	}
+
+	sasl := struct {
+		Pass string `yaml:"sasl_pass"`
+	}{"${ENV:QUANTA_TEST_PASS}"}
+	if err := ExpandAll(&sasl, dir); err != nil || Redact("login pass-123456") != "login "+Redacted {
+		t.Fatalf("a value under a credential key must be redacted: %+v, %v", sasl, err)
+	}
+
+	for name, v := range map[string]any{
+		"file":           &struct{ Host string }{"${FILE:short}"},
+		"credential key": &map[string]any{"token": "${ENV:QUANTA_TEST_ENVNAME}"},
+	} {
+		if err := ExpandAll(v, dir); err == nil || !strings.Contains(err.Error(), "too short") {
+			t.Errorf("%s: want a short secret rejected, got %v", name, err)
+		}
+	}
+	if got, err := Expand("x${ENV:QUANTA_TEST_EMPTY}y", ""); err != nil || got != "xy" {
+		t.Fatalf("empty value: %q, %v", got, err)
+	}
+}
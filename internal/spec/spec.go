@@ -1,44 +1,143 @@
 package spec
 
-type sinkConfigs struct {
-	Kafka  any `yaml:"kafka"`
-	Stdout any `yaml:"stdout"`
+import "fmt"
+
+type TransformerSpec struct {
+	Name    string `yaml:"name,omitempty"`
+	Type    string `yaml:"type,omitempty"`
+	Address string `yaml:"address,omitempty"`
+	Command string `yaml:"command,omitempty"`
+	// Handler names the registered in-process transformer a type "inproc"
+	// stage runs; it defaults to the stage's name.
+	Handler     string   `yaml:"handler,omitempty"`
+	Args        []string `yaml:"args,omitempty"`
+	MaxInFlight int      `yaml:"max_in_flight,omitempty"`
+	TimeoutMS   int      `yaml:"timeout_ms,omitempty"`
+	ContentType string   `yaml:"content_type,omitempty"`
+	RetryPolicy struct {
+		Attempts  int `yaml:"attempts,omitempty"`
+		BackoffMS int `yaml:"backoff_ms,omitempty"`
+	} `yaml:"retry_policy,omitempty"`
+	Config map[string]any `yaml:"config,omitempty"`
+
+	When    *Predicate        `yaml:"when,omitempty"`
+	Routes  []RouteSpec       `yaml:"routes,omitempty"`
+	Default []TransformerSpec `yaml:"default,omitempty"`
 }
 
-type debugSection struct {
-	PerFrameDelayMS int  `yaml:"per_frame_delay_ms"`
-	PrintCounter    bool `yaml:"print_counter"`
-	AckBatchSize    int  `yaml:"ack_batch_size"`
-	AckFlushMS      int  `yaml:"ack_flush_ms"`
-	PrintValue      bool `yaml:"print_value"`
-	ValueMaxBytes   int  `yaml:"value_max_bytes"`
+// InProcessName is the registered transformer an "inproc" stage runs.
+func (t TransformerSpec) InProcessName() string {
+	if t.Handler != "" {
+		return t.Handler
+	}
+	return t.Name
 }
 
-type TransformerSpec struct {
-	Name        string `yaml:"name"`
-	Type        string `yaml:"type"`
-	Address     string `yaml:"address"`
-	MaxInFlight int    `yaml:"max_in_flight"`
-	TimeoutMS   int    `yaml:"timeout_ms"`
-	ContentType string `yaml:"content_type"`
-	RetryPolicy struct {
-		Attempts  int `yaml:"attempts"`
-		BackoffMS int `yaml:"backoff_ms"`
-	} `yaml:"retry_policy"`
+// Predicate selects frames by one subject (header, key, topic or a JSON path
+// into the value) and one operator, or combines nested predicates.
+type Predicate struct {
+	Header string `yaml:"header,omitempty"`
+	Key    bool   `yaml:"key,omitempty"`
+	Topic  bool   `yaml:"topic,omitempty"`
+	JSON   string `yaml:"json,omitempty"`
+
+	Equals  *string  `yaml:"equals,omitempty"`
+	In      []string `yaml:"in,omitempty"`
+	Prefix  string   `yaml:"prefix,omitempty"`
+	Matches string   `yaml:"matches,omitempty"`
+	Exists  *bool    `yaml:"exists,omitempty"`
+
+	All []Predicate `yaml:"all,omitempty"`
+	Any []Predicate `yaml:"any,omitempty"`
+	Not *Predicate  `yaml:"not,omitempty"`
+}
+
+type RouteSpec struct {
+	Name         string            `yaml:"name,omitempty"`
+	When         *Predicate        `yaml:"when,omitempty"`
+	Transformers []TransformerSpec `yaml:"transformers,omitempty"`
 }
 
+// File is a pipeline spec. It is the shape of a v2 document; v1 documents
+// are decoded into V1File and converted with V1File.File, keeping "v1" as
+// their SchemaVersion.
 type File struct {
-	SchemaVersion string `yaml:"schema_version"`
+	SchemaVersion string `yaml:"schema_version,omitempty"`
+	Name          string `yaml:"name,omitempty"`
+
+	Source SourceSpec `yaml:"source"`
+
+	Transformers []TransformerSpec `yaml:"transformers,omitempty"`
+	Graph        *GraphSpec        `yaml:"graph,omitempty"`
+
+	// Sinks are the pipeline's sinks. In graph form they are the sinks that
+	// "sink" nodes name.
+	Sinks []SinkSpec `yaml:"sinks,omitempty"`
+}
 
-	Source struct {
-		Kind   string `yaml:"kind"`
-		Driver string `yaml:"driver"`
-		Config string `yaml:"config"`
-	} `yaml:"source"`
+type SourceSpec struct {
+	Kind   string `yaml:"kind"`
+	Driver string `yaml:"driver"`
+	// Config is the source's settings, inline; for kafka the keys of
+	// kafka_source.yml.
+	Config map[string]any `yaml:"config,omitempty"`
+	// ConfigFile is a v1 spec's separate source config file. It is never
+	// set on a v2 spec.
+	ConfigFile string `yaml:"-"`
 
-	Transformers []TransformerSpec `yaml:"transformers"`
+	// Restart tunes how the supervisor restarts the source after a
+	// retryable error.
+	Restart RestartSpec `yaml:"restart,omitempty"`
+}
+
+type RestartSpec struct {
+	InitialBackoffMS int `yaml:"initial_backoff_ms,omitempty"`
+	MaxBackoffMS     int `yaml:"max_backoff_ms,omitempty"`
+	MaxRestarts      int `yaml:"max_restarts,omitempty"`
+}
+
+// SinkSpec is one sink: Type picks the sink driver, Config holds its
+// type-specific settings.
+type SinkSpec struct {
+	Name   string         `yaml:"name"`
+	Type   string         `yaml:"type"`
+	Config map[string]any `yaml:"config,omitempty"`
+}
+
+// GraphSpec is the DAG form of a pipeline, used instead of transformers and
+// sinks. Nodes are transformer specs plus type "merge" and type "sink"; the
+// implicit "source" node is where edges start.
+type GraphSpec struct {
+	Nodes []NodeSpec `yaml:"nodes,omitempty"`
+	Edges []EdgeSpec `yaml:"edges,omitempty"`
+}
+
+type NodeSpec struct {
+	TransformerSpec `yaml:",inline"`
+	// Sink names the sink a "sink" node delivers to; it defaults to the
+	// node's name.
+	Sink string `yaml:"sink,omitempty"`
+}
+
+// SinkName is the name of the sink a "sink" node delivers to.
+func (n NodeSpec) SinkName() string {
+	if n.Sink != "" {
+		return n.Sink
+	}
+	return n.Name
+}
+
+type EdgeSpec struct {
+	From string `yaml:"from,omitempty"`
+	To   string `yaml:"to,omitempty"`
+}
 
-	Sinks       []string     `yaml:"sinks"`
-	SinkConfigs sinkConfigs  `yaml:"sink_configs"`
-	Debug       debugSection `yaml:"debug"`
+// SinkNamed returns the sink called name.
+func (f File) SinkNamed(name string) (SinkSpec, error) {
+	for _, s := range f.Sinks {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return SinkSpec{}, fmt.Errorf("unknown sink %q", name)
 }
@@ -0,0 +1,112 @@
+package spec
+
+import "errors"
+
+// V1File is a schema_version v1 pipeline spec: the source config lives in a
+// separate file, sinks are bare names and stdout is tuned through debug.
+type V1File struct {
+	SchemaVersion string `yaml:"schema_version"`
+	Name          string `yaml:"name"`
+
+	Source struct {
+		Kind    string      `yaml:"kind"`
+		Driver  string      `yaml:"driver"`
+		Config  string      `yaml:"config"`
+		Restart RestartSpec `yaml:"restart"`
+	} `yaml:"source"`
+
+	Transformers []TransformerSpec `yaml:"transformers"`
+	Graph        *GraphSpec        `yaml:"graph"`
+
+	Sinks       []string       `yaml:"sinks"`
+	SinkConfigs V1SinkConfigs  `yaml:"sink_configs"`
+	Debug       V1DebugSection `yaml:"debug"`
+}
+
+// V1SinkConfigs holds per-sink blocks; only kafka's is read, stdout is
+// configured through debug.
+type V1SinkConfigs struct {
+	Kafka  map[string]any `yaml:"kafka"`
+	Stdout map[string]any `yaml:"stdout"`
+}
+
+type V1DebugSection struct {
+	PerFrameDelayMS int  `yaml:"per_frame_delay_ms"`
+	PrintCounter    bool `yaml:"print_counter"`
+	AckBatchSize    int  `yaml:"ack_batch_size"`
+	AckFlushMS      int  `yaml:"ack_flush_ms"`
+	PrintValue      bool `yaml:"print_value"`
+	ValueMaxBytes   int  `yaml:"value_max_bytes"`
+}
+
+// File converts v to the v2 model, keeping its schema_version. Every sink
+// becomes a sink of the same name and type; graph sink nodes are pointed at
+// those sinks. The source config stays a file reference in
+// Source.ConfigFile.
+func (v V1File) File() (File, error) {
+	f := File{
+		SchemaVersion: v.SchemaVersion,
+		Name:          v.Name,
+		Transformers:  v.Transformers,
+		Graph:         v.Graph,
+	}
+	f.Source.Kind = v.Source.Kind
+	f.Source.Driver = v.Source.Driver
+	f.Source.ConfigFile = v.Source.Config
+	f.Source.Restart = v.Source.Restart
+
+	if v.Graph == nil {
+		for _, name := range v.Sinks {
+			f.Sinks = append(f.Sinks, v.sink(name))
+		}
+		return f, nil
+	}
+	if len(v.Transformers) > 0 || len(v.Sinks) > 0 {
+		return f, errors.New("graph cannot be combined with transformers or sinks")
+	}
+	// Copy the nodes so converting does not rewrite v's graph.
+	g := &GraphSpec{Nodes: append([]NodeSpec(nil), v.Graph.Nodes...), Edges: v.Graph.Edges}
+	f.Graph = g
+	seen := map[string]bool{}
+	for i, n := range g.Nodes {
+		if n.Type != "sink" {
+			continue
+		}
+		// A v1 sink node names a driver rather than a sink.
+		driver := n.SinkName()
+		g.Nodes[i].Sink = driver
+		if !seen[driver] {
+			seen[driver] = true
+			f.Sinks = append(f.Sinks, v.sink(driver))
+		}
+	}
+	return f, nil
+}
+
+// sink builds the v2 sink for a v1 sink name.
+func (v V1File) sink(name string) SinkSpec {
+	s := SinkSpec{Name: name, Type: name}
+	switch name {
+	case "stdout":
+		d := v.Debug
+		s.Config = map[string]any{}
+		for key, val := range map[string]any{
+			"delay_ms":        d.PerFrameDelayMS,
+			"print_counter":   d.PrintCounter,
+			"ack_batch_size":  d.AckBatchSize,
+			"ack_flush_ms":    d.AckFlushMS,
+			"print_value":     d.PrintValue,
+			"value_max_bytes": d.ValueMaxBytes,
+		} {
+			if val != 0 && val != false {
+				s.Config[key] = val
+			}
+		}
+	case "kafka":
+		s.Config = v.SinkConfigs.Kafka
+	}
+	if len(s.Config) == 0 {
+		s.Config = nil
+	}
+	return s
+}
@@ -0,0 +1,15 @@
+package telemetry
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Collectors returns the engine's frame, stage, sink and pipeline metrics.
+// They are registered with the default registry; embedders that serve their
+// own registry register them there too.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		FramesIn, FramesOut, Acks,
+		StageFramesIn, StageFramesOut, StageDrops, StageRetries, StageErrors, StageLatency, StageFanout,
+		SinkLatency, SinkFailures, TapDrops,
+		SourceRestarts, PipelineState, PipelineReloads,
+	}
+}
@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Frame path metrics. Every series carries the pipeline id; stage series add
+// the transformer name and sink series the sink name.
+var (
+	FramesIn = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_frames_in_total",
+		Help: "Frames received from the source.",
+	}, []string{"pipeline"})
+
+	FramesOut = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_frames_out_total",
+		Help: "Frames pushed to a sink successfully.",
+	}, []string{"pipeline", "sink"})
+
+	Acks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_acks_total",
+		Help: "Checkpoints acknowledged back to the source.",
+	}, []string{"pipeline"})
+
+	StageFramesIn = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_stage_frames_in_total",
+		Help: "Frames entering a transformer stage.",
+	}, []string{"pipeline", "stage"})
+
+	StageFramesOut = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_stage_frames_out_total",
+		Help: "Frames emitted by a transformer stage.",
+	}, []string{"pipeline", "stage"})
+
+	StageDrops = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_stage_drops_total",
+		Help: "Frames a transformer stage dropped on purpose.",
+	}, []string{"pipeline", "stage"})
+
+	StageRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_stage_retries_total",
+		Help: "Transformer calls retried after an error.",
+	}, []string{"pipeline", "stage"})
+
+	StageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_stage_errors_total",
+		Help: "Frames a transformer stage gave up on after exhausting retries.",
+	}, []string{"pipeline", "stage"})
+
+	StageLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "quanta_stage_latency_seconds",
+		Help:    "Time spent in a transformer stage per frame, retries included.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 16),
+	}, []string{"pipeline", "stage"})
+
+	StageFanout = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "quanta_stage_fanout_ratio",
+		Help:    "Output frames per input frame of a transformer stage.",
+		Buckets: []float64{0, 1, 2, 4, 8, 16, 32, 64},
+	}, []string{"pipeline", "stage"})
+
+	SinkLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "quanta_sink_push_seconds",
+		Help:    "Time taken by a sink push.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 16),
+	}, []string{"pipeline", "sink"})
+
+	SinkFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_sink_failures_total",
+		Help: "Sink pushes that returned an error.",
+	}, []string{"pipeline", "sink"})
+
+	TapDrops = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "quanta_tap_drops_total",
+		Help: "Frame copies dropped because a tap reader fell behind.",
+	}, []string{"pipeline"})
+)
@@ -0,0 +1,70 @@
+package telemetry
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rcrowley/go-metrics"
+)
+
+var invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+var goMetricsQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+// CollectGoMetrics bridges a go-metrics registry, such as the one Sarama
+// keeps per client, into a Prometheus collection. Metric names are prefixed
+// and sanitized, and the "-for-broker-N" and "-for-topic-T" suffixes Sarama
+// uses become broker and topic labels next to the given pipeline label.
+// Meters export their count and one-minute rate, histograms and timers a
+// summary.
+func CollectGoMetrics(reg metrics.Registry, prefix, pipeline string, ch chan<- prometheus.Metric) {
+	reg.Each(func(name string, m any) {
+		base, broker, topic := splitGoMetricName(name)
+		fq := prefix + invalidMetricChars.ReplaceAllString(base, "_")
+		labels := []string{"pipeline", "broker", "topic"}
+		values := []string{pipeline, broker, topic}
+		desc := func(suffix, help string) *prometheus.Desc {
+			return prometheus.NewDesc(fq+suffix, help, labels, nil)
+		}
+		help := "Sarama metric " + base + "."
+
+		switch m := m.(type) {
+		case metrics.Counter:
+			ch <- prometheus.MustNewConstMetric(desc("", help), prometheus.GaugeValue, float64(m.Count()), values...)
+		case metrics.Gauge:
+			ch <- prometheus.MustNewConstMetric(desc("", help), prometheus.GaugeValue, float64(m.Value()), values...)
+		case metrics.GaugeFloat64:
+			ch <- prometheus.MustNewConstMetric(desc("", help), prometheus.GaugeValue, m.Value(), values...)
+		case metrics.Meter:
+			s := m.Snapshot()
+			ch <- prometheus.MustNewConstMetric(desc("_total", help), prometheus.CounterValue, float64(s.Count()), values...)
+			ch <- prometheus.MustNewConstMetric(desc("_rate1m", help), prometheus.GaugeValue, s.Rate1(), values...)
+		case metrics.Histogram:
+			s := m.Snapshot()
+			ch <- summary(desc("", help), s.Count(), s.Sum(), s.Percentiles(goMetricsQuantiles), values)
+		case metrics.Timer:
+			s := m.Snapshot()
+			ch <- summary(desc("", help), s.Count(), s.Sum(), s.Percentiles(goMetricsQuantiles), values)
+		}
+	})
+}
+
+func summary(d *prometheus.Desc, count, sum int64, ps []float64, values []string) prometheus.Metric {
+	q := make(map[float64]float64, len(ps))
+	for i, p := range ps {
+		q[goMetricsQuantiles[i]] = p
+	}
+	return prometheus.MustNewConstSummary(d, uint64(count), float64(sum), q, values...)
+}
+
+func splitGoMetricName(name string) (base, broker, topic string) {
+	base = name
+	if i := strings.Index(base, "-for-broker-"); i >= 0 {
+		base, broker = base[:i], base[i+len("-for-broker-"):]
+	}
+	if i := strings.Index(base, "-for-topic-"); i >= 0 {
+		base, topic = base[:i], base[i+len("-for-topic-"):]
+	}
+	return base, broker, topic
+}
@@ -1,15 +1,72 @@
 package telemetry
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func Expose(port int) {
-	go func() {
-		http.Handle("/metrics", promhttp.Handler())
-		_ = http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
-	}()
+// Probe returns nil when the checked component is healthy.
+type Probe func(context.Context) error
+
+// probeTimeout bounds a single /healthz or /readyz request.
+const probeTimeout = 2 * time.Second
+
+// AdminEndpoints selects what the admin HTTP listener serves.
+type AdminEndpoints struct {
+	Metrics bool // /metrics
+	Probes  bool // /healthz and /readyz
+	Pprof   bool // /debug/pprof/
+}
+
+// Expose serves the enabled admin endpoints on addr, with /healthz and
+// /readyz backed by live and ready; a nil probe always passes. Nothing is
+// started when no endpoint is enabled. Only listen errors are returned; the
+// returned shutdown func closes the listener and waits for requests in
+// flight.
+func Expose(addr string, ep AdminEndpoints, live, ready Probe) (shutdown func(context.Context) error, err error) {
+	if !ep.Metrics && !ep.Probes && !ep.Pprof {
+		return func(context.Context) error { return nil }, nil
+	}
+	mux := http.NewServeMux()
+	if ep.Metrics {
+		mux.Handle("/metrics", promhttp.Handler())
+	}
+	if ep.Probes {
+		mux.Handle("/healthz", probeHandler(live))
+		mux.Handle("/readyz", probeHandler(ready))
+	}
+	if ep.Pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: mux}
+	go func() { _ = srv.Serve(lis) }()
+	return srv.Shutdown, nil
+}
+
+func probeHandler(p Probe) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if p != nil {
+			ctx, cancel := context.WithTimeout(req.Context(), probeTimeout)
+			defer cancel()
+			if err := p(ctx); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+	})
 }
@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// SourceRestarts counts supervisor restarts of a pipeline's source after a
+// retryable error.
+var SourceRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "quanta_source_restarts_total",
+	Help: "Source restarts after retryable errors.",
+}, []string{"pipeline"})
+
+// PipelineState is 1 for the state a pipeline is currently in and 0 for the
+// others.
+var PipelineState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "quanta_pipeline_state",
+	Help: "Current pipeline state (1 for the active state).",
+}, []string{"pipeline", "state"})
+
+// SetPipelineState flips the PipelineState gauges for pipeline to state.
+func SetPipelineState(pipeline, state string, states ...string) {
+	for _, s := range states {
+		v := 0.0
+		if s == state {
+			v = 1
+		}
+		PipelineState.WithLabelValues(pipeline, s).Set(v)
+	}
+}
+
+// DeletePipeline removes every series labelled with pipeline, once the
+// engine no longer runs it.
+func DeletePipeline(pipeline string) {
+	l := prometheus.Labels{"pipeline": pipeline}
+	for _, v := range []*prometheus.MetricVec{
+		FramesIn.MetricVec, Acks.MetricVec, TapDrops.MetricVec,
+		SourceRestarts.MetricVec, PipelineState.MetricVec, PipelineReloads.MetricVec,
+	} {
+		v.DeletePartialMatch(l)
+	}
+	for _, v := range stageVecs() {
+		v.DeletePartialMatch(l)
+	}
+	for _, v := range sinkVecs() {
+		v.DeletePartialMatch(l)
+	}
+}
+
+// DeleteStage removes the series of a stage pipeline no longer has.
+func DeleteStage(pipeline, stage string) {
+	for _, v := range stageVecs() {
+		v.DeletePartialMatch(prometheus.Labels{"pipeline": pipeline, "stage": stage})
+	}
+}
+
+// DeleteSink removes the series of a sink pipeline no longer has.
+func DeleteSink(pipeline, sink string) {
+	for _, v := range sinkVecs() {
+		v.DeletePartialMatch(prometheus.Labels{"pipeline": pipeline, "sink": sink})
+	}
+}
+
+func stageVecs() []*prometheus.MetricVec {
+	return []*prometheus.MetricVec{
+		StageFramesIn.MetricVec, StageFramesOut.MetricVec, StageDrops.MetricVec, StageRetries.MetricVec,
+		StageErrors.MetricVec, StageLatency.MetricVec, StageFanout.MetricVec,
+	}
+}
+
+func sinkVecs() []*prometheus.MetricVec {
+	return []*prometheus.MetricVec{FramesOut.MetricVec, SinkLatency.MetricVec, SinkFailures.MetricVec}
+}
@@ -0,0 +1,13 @@
+package telemetry
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// PipelineReloads counts spec reloads per pipeline. result is one of
+// "unchanged", "stages" (swapped in place), "restart" or "rejected".
+var PipelineReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "quanta_pipeline_reloads_total",
+	Help: "Pipeline spec reloads by outcome.",
+}, []string{"pipeline", "result"})
@@ -0,0 +1,138 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// TracingConfig selects where frame traces are exported.
+type TracingConfig struct {
+	// Exporter is "otlp" (gRPC to a collector), "stdout" or empty to only
+	// propagate trace context without recording spans.
+	Exporter string
+	// Endpoint is the collector address for otlp; empty falls back to
+	// OTEL_EXPORTER_OTLP_ENDPOINT and then localhost:4317.
+	Endpoint string
+	Insecure bool
+	// SampleRatio is the fraction of new traces recorded; zero means all.
+	// Frames carrying a sampled parent are always recorded.
+	SampleRatio float64
+	ServiceName string
+	// InstanceID is recorded as service.instance.id, typically the node id.
+	InstanceID string
+}
+
+// SetupTracing installs the global tracer provider and W3C propagators and
+// returns a function that flushes and stops the exporter.
+func SetupTracing(ctx context.Context, cfg TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+	switch cfg.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		opts := []otlptracegrpc.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exp, err = otlptracegrpc.New(ctx, opts...)
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+
+	name := cfg.ServiceName
+	if name == "" {
+		name = "quanta-engine"
+	}
+	attrs := []attribute.KeyValue{semconv.ServiceName(name)}
+	if cfg.InstanceID != "" {
+		attrs = append(attrs, semconv.ServiceInstanceID(cfg.InstanceID))
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the engine's tracer from the global provider.
+func Tracer() trace.Tracer { return otel.Tracer("quanta") }
+
+// HeaderCarrier adapts frame (Kafka) headers to the propagation API.
+type HeaderCarrier map[string][]byte
+
+func (c HeaderCarrier) Get(key string) string { return string(c[key]) }
+func (c HeaderCarrier) Set(key, value string) { c[key] = []byte(value) }
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// ExtractHeaders returns ctx carrying the trace context found in headers.
+func ExtractHeaders(ctx context.Context, headers map[string][]byte) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, HeaderCarrier(headers))
+}
+
+// InjectHeaders writes ctx's trace context into headers.
+func InjectHeaders(ctx context.Context, headers map[string][]byte) {
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier(headers))
+}
+
+// InjectOutgoing adds ctx's trace context to the outgoing gRPC metadata so
+// plugin spans join the frame's trace.
+func InjectOutgoing(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+func (c metadataCarrier) Set(key, value string) { metadata.MD(c).Set(key, value) }
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
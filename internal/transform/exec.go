@@ -0,0 +1,116 @@
+package transform
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"quanta/internal/logging"
+	"quanta/sdk"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	handshakeTimeout = 10 * time.Second
+	execStopTimeout  = 5 * time.Second
+)
+
+type ExecClient struct {
+	*GRPCClient
+	cmd    *exec.Cmd
+	exited chan struct{}
+}
+
+func NewExecClient(ctx context.Context, command string, args []string, opts ...grpc.DialOption) (*ExecClient, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = append(os.Environ(), sdk.HandshakeCookieKey+"="+sdk.HandshakeCookieValue)
+	cmd.Stderr = os.Stderr
+	// An os.Pipe rather than StdoutPipe: Wait, which runs as soon as the
+	// plugin starts, would close the latter under the handshake reader and
+	// lose the line of a plugin that exits right after printing it.
+	stdout, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = w
+	err = cmd.Start()
+	_ = w.Close()
+	if err != nil {
+		_ = stdout.Close()
+		return nil, err
+	}
+	c := &ExecClient{cmd: cmd, exited: make(chan struct{})}
+	go func() {
+		_ = cmd.Wait()
+		close(c.exited)
+	}()
+
+	log := logging.L().With("component", "transform.exec", "command", command)
+	lines := bufio.NewScanner(stdout)
+	first := make(chan error, 1)
+	var hs sdk.Handshake
+	go func() {
+		defer stdout.Close()
+		if !lines.Scan() {
+			first <- errors.New("plugin exited before handshake")
+			return
+		}
+		var err error
+		hs, err = sdk.ParseHandshake(lines.Text())
+		first <- err
+		for lines.Scan() {
+			log.Info("plugin stdout", "line", lines.Text())
+		}
+	}()
+
+	select {
+	case err = <-first:
+	case <-time.After(handshakeTimeout):
+		err = fmt.Errorf("no handshake within %s", handshakeTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		c.stop()
+		return nil, fmt.Errorf("exec %s: %w", command, err)
+	}
+
+	cli, err := NewGRPCClient(ctx, hs.Target(), opts...)
+	if err != nil {
+		c.stop()
+		return nil, err
+	}
+	c.GRPCClient = cli
+	log.Info("plugin launched", "pid", cmd.Process.Pid, "addr", hs.Target())
+	return c, nil
+}
+
+func (c *ExecClient) Close() error {
+	var err error
+	if c.GRPCClient != nil {
+		err = c.GRPCClient.Close()
+	}
+	c.stop()
+	return err
+}
+
+func (c *ExecClient) stop() {
+	select {
+	case <-c.exited:
+		return
+	default:
+	}
+	_ = c.cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-c.exited:
+	case <-time.After(execStopTimeout):
+		_ = c.cmd.Process.Kill()
+		<-c.exited
+	}
+}
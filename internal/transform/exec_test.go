@@ -0,0 +1,24 @@
+package transform
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewExecClient_ReadsTheHandshakeOfAPluginThatExits(t *testing.T) {
+	// A plugin that prints its handshake and exits at once must still be
+	// read, not reported as gone or as a closed pipe.
+	for i := 0; i < 20; i++ {
+		c, err := NewExecClient(context.Background(), "sh", []string{"-c", "echo 'quanta|1|tcp|127.0.0.1:1'"})
+		if err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		_ = c.Close()
+	}
+
+	_, err := NewExecClient(context.Background(), "sh", []string{"-c", "exit 3"})
+	if err == nil || !strings.Contains(err.Error(), "exited before handshake") {
+		t.Fatalf("want the missing handshake reported, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	pb "quanta/api/proto/v1"
+	"quanta/sdk"
+)
+
+var inproc = struct {
+	sync.RWMutex
+	factories map[string]func() Client
+}{factories: map[string]func() Client{}}
+
+// RegisterInProcess makes an in-process transformer available to specs as
+// type "inproc". f is called once per stage, so stages do not share state.
+func RegisterInProcess(name string, f func() Client) {
+	inproc.Lock()
+	defer inproc.Unlock()
+	inproc.factories[name] = f
+}
+
+// UnregisterInProcess removes a transformer registered with
+// RegisterInProcess. Stages already built keep their clients.
+func UnregisterInProcess(name string) {
+	inproc.Lock()
+	defer inproc.Unlock()
+	delete(inproc.factories, name)
+}
+
+// HasInProcess reports whether an in-process transformer is registered as
+// name.
+func HasInProcess(name string) bool {
+	inproc.RLock()
+	defer inproc.RUnlock()
+	_, ok := inproc.factories[name]
+	return ok
+}
+
+// NewInProcess makes a client for the in-process transformer name.
+func NewInProcess(name string) (Client, error) {
+	inproc.RLock()
+	f, ok := inproc.factories[name]
+	inproc.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown in-process transformer %q", name)
+	}
+	return f(), nil
+}
+
+// NewHandlerClient runs an SDK handler in the engine's process: the same
+// server a plugin binary would run, called directly instead of over gRPC.
+func NewHandlerClient(h sdk.HandlerFunc, opts ...sdk.Option) Client {
+	return NewInProcessClient(sdkServer{sdk.NewServer(h, opts...)})
+}
+
+// sdkServer adapts an SDK server to Transformer and Configurable.
+type sdkServer struct{ s *sdk.Server }
+
+func (a sdkServer) Metadata(ctx context.Context) (*pb.MetadataResponse, error) {
+	return a.s.Metadata(ctx, &pb.MetadataRequest{})
+}
+
+func (a sdkServer) Health(ctx context.Context) (*pb.HealthResponse, error) {
+	return a.s.Health(ctx, &pb.HealthRequest{})
+}
+
+func (a sdkServer) Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
+	return a.s.Transform(ctx, req)
+}
+
+func (a sdkServer) Configure(ctx context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	return a.s.Configure(ctx, req)
+}
@@ -7,12 +7,14 @@ import (
 	pb "quanta/api/proto/v1"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 )
 
 type Client interface {
 	Metadata(ctx context.Context) (*pb.MetadataResponse, error)
 	Health(ctx context.Context) (*pb.HealthResponse, error)
 	Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error)
+	Configure(ctx context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error)
 	Stream(ctx context.Context, opts ...grpc.CallOption) (pb.TransformService_TransformStreamClient, error)
 	Close() error
 }
@@ -36,8 +38,10 @@ func NewGRPCClient(ctx context.Context, target string, opts ...grpc.DialOption)
 	}, nil
 }
 
+func (c *GRPCClient) Service() pb.TransformServiceClient { return c.svc }
+
 func (c *GRPCClient) Metadata(ctx context.Context) (*pb.MetadataResponse, error) {
-	return c.svc.Metadata(ctx, &pb.MetadataRequest{})
+	return c.svc.Metadata(ctx, &pb.MetadataRequest{EngineVersion: EngineProtocol})
 }
 func (c *GRPCClient) Health(ctx context.Context) (*pb.HealthResponse, error) {
 	return c.svc.Health(ctx, &pb.HealthRequest{})
@@ -45,6 +49,42 @@ func (c *GRPCClient) Health(ctx context.Context) (*pb.HealthResponse, error) {
 func (c *GRPCClient) Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
 	return c.svc.Transform(ctx, req)
 }
+func (c *GRPCClient) Configure(ctx context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	return c.svc.Configure(ctx, req)
+}
+
+// NotifyReconnect calls fn each time the connection is ready again after
+// having been ready, until ctx is done or unregister is called. unregister
+// waits for the watcher to exit, so fn is not called once it returns. A
+// pooled connection outlives the stages using it, so each stage must
+// unregister when it closes.
+func (c *GRPCClient) NotifyReconnect(ctx context.Context, fn func()) (unregister func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		seenReady := false
+		for {
+			st := c.conn.GetState()
+			if st == connectivity.Shutdown {
+				return
+			}
+			if st == connectivity.Ready {
+				if seenReady {
+					fn()
+				}
+				seenReady = true
+			}
+			if !c.conn.WaitForStateChange(ctx, st) {
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
 func (c *GRPCClient) Stream(ctx context.Context, opts ...grpc.CallOption) (pb.TransformService_TransformStreamClient, error) {
 	return c.svc.TransformStream(ctx, opts...)
 }
@@ -64,6 +104,10 @@ type Transformer interface {
 	Transform(context.Context, *pb.TransformRequest) (*pb.TransformResponse, error)
 }
 
+type Configurable interface {
+	Configure(context.Context, *pb.ConfigureRequest) (*pb.ConfigureResponse, error)
+}
+
 func NewInProcessClient(impl Transformer) *InProcessClient { return &InProcessClient{impl: impl} }
 func (c *InProcessClient) Metadata(ctx context.Context) (*pb.MetadataResponse, error) {
 	return c.impl.Metadata(ctx)
@@ -74,6 +118,12 @@ func (c *InProcessClient) Health(ctx context.Context) (*pb.HealthResponse, error
 func (c *InProcessClient) Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
 	return c.impl.Transform(ctx, req)
 }
+func (c *InProcessClient) Configure(ctx context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	if cfg, ok := c.impl.(Configurable); ok {
+		return cfg.Configure(ctx, req)
+	}
+	return &pb.ConfigureResponse{Ok: true}, nil
+}
 func (c *InProcessClient) Stream(context.Context, ...grpc.CallOption) (pb.TransformService_TransformStreamClient, error) {
 	return nil, fmt.Errorf("streaming not supported for in‑proc client")
 }
@@ -0,0 +1,81 @@
+package transform
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool shares one gRPC connection per plugin target between stages, across
+// pipelines. Each Get returns a handle; the connection closes once every
+// handle has been closed.
+type Pool struct {
+	mu    sync.Mutex
+	conns map[string]*pooledConn
+}
+
+type pooledConn struct {
+	cli  *GRPCClient
+	refs int
+}
+
+// SharedPool is the pool the pipeline compiler dials through.
+var SharedPool = &Pool{}
+
+func (p *Pool) Get(ctx context.Context, target string) (Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc, ok := p.conns[target]
+	if !ok {
+		cli, err := NewGRPCClient(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		if p.conns == nil {
+			p.conns = map[string]*pooledConn{}
+		}
+		pc = &pooledConn{cli: cli}
+		p.conns[target] = pc
+	}
+	pc.refs++
+	return &pooledClient{GRPCClient: pc.cli, pool: p, target: target}, nil
+}
+
+// Refs reports how many open handles share the connection to target.
+func (p *Pool) Refs(target string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pc, ok := p.conns[target]; ok {
+		return pc.refs
+	}
+	return 0
+}
+
+func (p *Pool) release(target string) error {
+	p.mu.Lock()
+	pc, ok := p.conns[target]
+	if !ok {
+		p.mu.Unlock()
+		return nil
+	}
+	pc.refs--
+	if pc.refs > 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	delete(p.conns, target)
+	p.mu.Unlock()
+	return pc.cli.Close()
+}
+
+type pooledClient struct {
+	*GRPCClient
+	pool   *Pool
+	target string
+	once   sync.Once
+}
+
+func (c *pooledClient) Close() error {
+	var err error
+	c.once.Do(func() { err = c.pool.release(c.target) })
+	return err
+}
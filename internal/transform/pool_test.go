@@ -0,0 +1,60 @@
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestPool_SharesAndReleasesConnections(t *testing.T) {
+	p := &Pool{}
+	ctx := context.Background()
+
+	a, err := p.Get(ctx, "localhost:1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	b, _ := p.Get(ctx, "localhost:1")
+	other, _ := p.Get(ctx, "localhost:2")
+	if p.Refs("localhost:1") != 2 || p.Refs("localhost:2") != 1 {
+		t.Fatalf("unexpected refs: %d/%d", p.Refs("localhost:1"), p.Refs("localhost:2"))
+	}
+	conn := a.(*pooledClient).conn
+	if b.(*pooledClient).conn != conn {
+		t.Fatal("handles to the same target must share a connection")
+	}
+
+	_ = a.Close()
+	_ = a.Close()
+	if p.Refs("localhost:1") != 1 || conn.GetState() == connectivity.Shutdown {
+		t.Fatal("connection closed while still referenced")
+	}
+	_ = b.Close()
+	if p.Refs("localhost:1") != 0 || conn.GetState() != connectivity.Shutdown {
+		t.Fatal("connection should close with its last handle")
+	}
+	_ = other.Close()
+}
+
+func TestGRPCClient_UnregisterStopsReconnectWatcher(t *testing.T) {
+	p := &Pool{}
+	a, err := p.Get(context.Background(), "localhost:1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer a.Close()
+
+	unregister := a.(*pooledClient).NotifyReconnect(context.Background(), func() {})
+	done := make(chan struct{})
+	go func() { unregister(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher still running on an open connection after unregister")
+	}
+	if a.(*pooledClient).conn.GetState() == connectivity.Shutdown {
+		t.Fatal("unregister must not close the shared connection")
+	}
+}
@@ -0,0 +1,30 @@
+package transform
+
+import (
+	"context"
+
+	pb "quanta/api/proto/v1"
+)
+
+const (
+	ProtocolV1 int32 = 1
+	ProtocolV2 int32 = 2
+)
+
+var EngineProtocol = &pb.PluginVersion{Major: ProtocolV2, Minor: 0, Patch: 0}
+
+func Negotiate(ctx context.Context, c Client) (int32, error) {
+	md, err := c.Metadata(ctx)
+	if err != nil {
+		return ProtocolV1, err
+	}
+	major := md.GetProtocolVersion().GetMajor()
+	switch {
+	case major >= EngineProtocol.Major:
+		return EngineProtocol.Major, nil
+	case major < ProtocolV1:
+		return ProtocolV1, nil
+	default:
+		return major, nil
+	}
+}
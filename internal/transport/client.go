@@ -1,17 +1,88 @@
 package transport
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"os"
 
 	pb "quanta/api/proto/v1"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-func Dial(port int) (pb.ControlClient, error) {
-	cc, err := grpc.Dial(fmt.Sprintf("localhost:%d", port), grpc.WithInsecure())
+// Client is a connection to an engine's control plane.
+type Client struct {
+	pb.ControlClient
+	Health healthpb.HealthClient
+	conn   *grpc.ClientConn
+}
+
+// Dial connects to the engine at target (host:port). A nil tlsCfg dials in
+// plaintext. The connection is established lazily on the first call.
+func Dial(target string, tlsCfg *tls.Config, opts ...grpc.DialOption) (*Client, error) {
+	creds := insecure.NewCredentials()
+	if tlsCfg != nil {
+		creds = credentials.NewTLS(tlsCfg)
+	}
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts...)
+	cc, err := grpc.NewClient(target, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return pb.NewControlClient(cc), nil
+	return &Client{
+		ControlClient: pb.NewControlClient(cc),
+		Health:        healthpb.NewHealthClient(cc),
+		conn:          cc,
+	}, nil
+}
+
+func (c *Client) Close() error { return c.conn.Close() }
+
+// BearerToken sends token as "authorization: Bearer <token>" on every call.
+func BearerToken(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(bearer(token))
+}
+
+type bearer string
+
+func (b bearer) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(b)}, nil
+}
+
+// RequireTransportSecurity is false so a token also works against a
+// plaintext engine during development; the engine warns about it.
+func (bearer) RequireTransportSecurity() bool { return false }
+
+// ClientTLS builds a client TLS config. caFile replaces the system roots,
+// certFile and keyFile present a client certificate for mTLS.
+func ClientTLS(caFile, certFile, keyFile, serverName string, skipVerify bool) (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: serverName, InsecureSkipVerify: skipVerify, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("tls: client certificate and key must be given together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
 }
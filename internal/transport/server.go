@@ -1,12 +1,20 @@
 package transport
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
+	"os"
 
 	pb "quanta/api/proto/v1"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type Server struct {
@@ -14,25 +22,73 @@ type Server struct {
 	lis  net.Listener
 }
 
-func StartServer(port int) (*Server, error) {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+// StartServer listens on addr and registers ctl as the Control service and
+// hs as both the standard gRPC health service and quanta.v1.Health. A nil
+// tlsCfg serves plaintext; opts add interceptors and the like. A nil ctl
+// registers the unimplemented stub; a nil hs always reports SERVING.
+func StartServer(addr string, tlsCfg *tls.Config, ctl pb.ControlServer, hs healthpb.HealthServer, opts ...grpc.ServerOption) (*Server, error) {
+	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
+	if tlsCfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
 	s := &Server{
-		grpc: grpc.NewServer(),
+		grpc: grpc.NewServer(opts...),
 		lis:  lis,
 	}
 
-	pb.RegisterControlServer(s.grpc, UnimplementedControl{})
+	if ctl == nil {
+		ctl = UnimplementedControl{}
+	}
+	pb.RegisterControlServer(s.grpc, ctl)
+
+	if hs == nil {
+		hs = health.NewServer()
+	}
+	healthpb.RegisterHealthServer(s.grpc, hs)
+	pb.RegisterHealthServer(s.grpc, healthBridge{hs: hs})
 	return s, nil
 }
 
+// Addr is the address the server listens on.
+func (s *Server) Addr() net.Addr { return s.lis.Addr() }
+
+// ServerTLS builds the control plane TLS config. A clientCAFile requires
+// every client to present a certificate signed by it (mTLS).
+func ServerTLS(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("tls: server certificate and key are required")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates in %s", clientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
 func (s *Server) Serve() error {
 	return s.grpc.Serve(s.lis)
 }
+
+// Stop stops the server, and closes its listener if Serve never ran.
 func (s *Server) Stop() {
 	s.grpc.GracefulStop()
+	_ = s.lis.Close()
 }
 
 type UnimplementedConnector struct {
@@ -41,3 +97,18 @@ type UnimplementedConnector struct {
 type UnimplementedControl struct {
 	pb.UnimplementedControlServer
 }
+
+// healthBridge answers quanta.v1.Health.Check from the standard health
+// service's overall status.
+type healthBridge struct {
+	pb.UnimplementedHealthServer
+	hs healthpb.HealthServer
+}
+
+func (b healthBridge) Check(ctx context.Context, _ *pb.HealthCheckRequest) (*pb.HealthCheckReply, error) {
+	res, err := b.hs.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.HealthCheckReply{Ok: res.GetStatus() == healthpb.HealthCheckResponse_SERVING}, nil
+}
@@ -0,0 +1,11 @@
+// Package pipelinetest runs pipeline specs offline in Go tests. A Harness
+// compiles a spec with its source replaced by a scriptable in-memory Source
+// and every sink by a recording Sink; transformer stages can be swapped for
+// scripted fakes by name. Frames are sent one at a time and the harness
+// records acks and drops, so a test can assert which offsets a Kafka source
+// would commit, why a frame was dropped, and that no checkpoint was acked
+// twice.
+//
+// Sources, sinks and fakes are made available to the compiler through the
+// engine's registries under names unique to each harness.
+package pipelinetest
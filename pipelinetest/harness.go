@@ -0,0 +1,276 @@
+package pipelinetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/engine"
+	"quanta/internal/config"
+	"quanta/internal/pipeline"
+	"quanta/internal/spec"
+	"quanta/sink"
+	"quanta/source"
+)
+
+// Drop is a frame that left the pipeline without reaching a sink.
+type Drop = pipeline.Drop
+
+// Drop causes.
+const (
+	DropFiltered = pipeline.DropFiltered
+	DropFailed   = pipeline.DropFailed
+	DropUnrouted = pipeline.DropUnrouted
+)
+
+// Timeout bounds how long assertions wait for acks and deliveries that
+// happen asynchronously, such as delayed sink acks.
+var Timeout = 5 * time.Second
+
+// Option customises a Harness.
+type Option func(*options)
+
+type options struct {
+	fakes map[string]*Transformer
+	sinks map[string]*Sink
+}
+
+// WithTransformer runs tr in place of every stage called name, wherever it
+// appears in the spec.
+func WithTransformer(name string, tr *Transformer) Option {
+	return func(o *options) { o.fakes[name] = tr }
+}
+
+// WithSink uses s for the sink called name, so its acks can be delayed,
+// held or failed from the start.
+func WithSink(name string, s *Sink) Option {
+	return func(o *options) { o.sinks[name] = s }
+}
+
+// Harness runs one pipeline spec against a Source and recording sinks.
+type Harness struct {
+	t      testing.TB
+	Source *Source
+	sinks  map[string]*Sink
+	runner *pipeline.Runner
+
+	mu    sync.Mutex
+	drops []Drop
+}
+
+var seq atomic.Int64
+
+// Load runs the spec in path; see New.
+func Load(t testing.TB, path string, opts ...Option) *Harness {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("pipelinetest: %v", err)
+	}
+	return New(t, raw, filepath.Dir(path), opts...)
+}
+
+// New compiles the spec in raw, with relative paths resolved against
+// baseDir, and starts it. The spec's source is replaced by the harness's
+// Source and every sink by a Sink; the pipeline is closed when the test
+// ends.
+func New(t testing.TB, raw []byte, baseDir string, opts ...Option) *Harness {
+	t.Helper()
+	o := options{fakes: map[string]*Transformer{}, sinks: map[string]*Sink{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	cfg, confPath, err := config.ParsePipelineSpec(raw, baseDir)
+	if err != nil {
+		t.Fatalf("pipelinetest: %v", err)
+	}
+
+	h := &Harness{t: t, Source: NewSource(), sinks: map[string]*Sink{}}
+	prefix := fmt.Sprintf("pipelinetest-%d", seq.Add(1))
+	source.Register(prefix, func() source.Source { return h.Source })
+	t.Cleanup(func() { source.Unregister(prefix) })
+	cfg.Source = spec.SourceSpec{Kind: prefix}
+	for i, s := range cfg.Sinks {
+		rs := o.sinks[s.Name]
+		if rs == nil {
+			rs = NewSink()
+		}
+		h.sinks[s.Name] = rs
+		typ := prefix + "/" + s.Name
+		sink.Register(typ, func() sink.Adapter { return rs })
+		t.Cleanup(func() { sink.Unregister(typ) })
+		cfg.Sinks[i] = spec.SinkSpec{Name: s.Name, Type: typ}
+	}
+	for name, tr := range o.fakes {
+		engine.RegisterTransformer(prefix+"/"+name, tr.Handle)
+		t.Cleanup(func() { engine.UnregisterTransformer(prefix + "/" + name) })
+	}
+	fake := func(t *spec.TransformerSpec) {
+		if _, ok := o.fakes[t.Name]; ok && t.Type != "route" {
+			t.Type, t.Handler = "inproc", prefix+"/"+t.Name
+			t.Address, t.Command, t.Args = "", "", nil
+		}
+	}
+	fakeStages(cfg.Transformers, fake)
+	if cfg.Graph != nil {
+		for i := range cfg.Graph.Nodes {
+			if n := &cfg.Graph.Nodes[i]; n.Type != "sink" && n.Type != "merge" {
+				fake(&n.TransformerSpec)
+			}
+		}
+	}
+
+	r, err := pipeline.CompileSpec(cfg, confPath)
+	if err != nil {
+		t.Fatalf("pipelinetest: %v", err)
+	}
+	r.SubscribeDrop(func(d Drop) {
+		h.mu.Lock()
+		h.drops = append(h.drops, d)
+		h.mu.Unlock()
+	})
+	if err := r.Start(context.Background()); err != nil {
+		_ = r.Close()
+		t.Fatalf("pipelinetest: %v", err)
+	}
+	h.runner = r
+	t.Cleanup(func() { _ = r.Close() })
+	return h
+}
+
+func fakeStages(ts []spec.TransformerSpec, fn func(*spec.TransformerSpec)) {
+	for i := range ts {
+		fn(&ts[i])
+		for j := range ts[i].Routes {
+			fakeStages(ts[i].Routes[j].Transformers, fn)
+		}
+		fakeStages(ts[i].Default, fn)
+	}
+}
+
+// Sink returns the recording sink standing in for the spec's sink name.
+func (h *Harness) Sink(name string) *Sink {
+	h.t.Helper()
+	s, ok := h.sinks[name]
+	if !ok {
+		h.t.Fatalf("pipelinetest: no sink %q", name)
+	}
+	return s
+}
+
+// Send emits frames through the pipeline and fails the test if it reports
+// an error for one.
+func (h *Harness) Send(frames ...*pb.Frame) {
+	h.t.Helper()
+	if err := h.Source.Send(frames...); err != nil {
+		h.t.Fatalf("pipelinetest: send: %v", err)
+	}
+}
+
+// SendValues sends a record per value.
+func (h *Harness) SendValues(values ...string) {
+	h.t.Helper()
+	for _, v := range values {
+		h.Send(Record("", v))
+	}
+}
+
+// Drops returns the frames dropped so far.
+func (h *Harness) Drops() []Drop {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Drop(nil), h.drops...)
+}
+
+// LastError is the most recent stage or source error of the pipeline.
+func (h *Harness) LastError() error { return h.runner.LastError() }
+
+// AssertCommitted waits until every frame of partition on DefaultTopic up to
+// and including offset has been acked, so a Kafka source would commit past
+// it.
+func (h *Harness) AssertCommitted(partition int32, offset int64) {
+	h.t.Helper()
+	var got int64
+	if !eventually(func() bool {
+		got = h.Source.Committed(DefaultTopic, partition)
+		return got > offset
+	}) {
+		h.t.Fatalf("offset %d of partition %d not committed; committed up to %d", offset, partition, got)
+	}
+}
+
+// AssertNotCommitted fails if offset of partition on DefaultTopic has been
+// committed.
+func (h *Harness) AssertNotCommitted(partition int32, offset int64) {
+	h.t.Helper()
+	if got := h.Source.Committed(DefaultTopic, partition); got > offset {
+		h.t.Fatalf("offset %d of partition %d committed; committed up to %d", offset, partition, got)
+	}
+}
+
+// AssertDropped fails unless the frame at offset of partition on
+// DefaultTopic was dropped with reason: either the drop's cause, such as
+// DropFiltered, or text its reason contains.
+func (h *Harness) AssertDropped(partition int32, offset int64, reason string) {
+	h.t.Helper()
+	var seen []string
+	for _, d := range h.Drops() {
+		k := d.Frame.GetCheckpoint().GetKafka()
+		if k.GetTopic() != DefaultTopic || k.GetPartition() != partition || k.GetOffset() != offset {
+			continue
+		}
+		if d.Cause == reason || strings.Contains(d.Reason, reason) {
+			return
+		}
+		seen = append(seen, fmt.Sprintf("%s at %q: %s", d.Cause, d.Stage, d.Reason))
+	}
+	if len(seen) == 0 {
+		h.t.Fatalf("frame at %s was not dropped", checkpointString(DefaultTopic, partition, offset))
+	}
+	h.t.Fatalf("frame at %s dropped, but not with %q: %s", checkpointString(DefaultTopic, partition, offset), reason, strings.Join(seen, "; "))
+}
+
+// AssertNoDuplicateAcks fails if any checkpoint was acked more than once.
+func (h *Harness) AssertNoDuplicateAcks() {
+	h.t.Helper()
+	if dups := h.Source.Duplicates(); len(dups) > 0 {
+		h.t.Fatalf("checkpoints acked more than once: %s", strings.Join(dups, ", "))
+	}
+}
+
+// AssertDelivered waits until the sink name has received exactly values,
+// in order.
+func (h *Harness) AssertDelivered(name string, values ...string) {
+	h.t.Helper()
+	s := h.Sink(name)
+	var got []string
+	if !eventually(func() bool {
+		got = s.Values()
+		return slices.Equal(got, values)
+	}) {
+		h.t.Fatalf("sink %s received %q, want %q", name, got, values)
+	}
+}
+
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(Timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	return true
+}
+
+func checkpointString(topic string, partition int32, offset int64) string {
+	return fmt.Sprintf("%s/%d@%d", topic, partition, offset)
+}
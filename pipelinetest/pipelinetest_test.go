@@ -0,0 +1,176 @@
+package pipelinetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/transform"
+	"quanta/sdk"
+	"quanta/sink"
+	"quanta/source"
+)
+
+const filterSpec = `
+schema_version: v2
+name: orders
+source:
+  kind: kafka
+  driver: sarama
+  config:
+    brokers: ["localhost:9092"]
+    topics: [orders]
+    group_id: orders
+transformers:
+  - name: filter
+    type: grpc
+    address: localhost:1
+  - name: upper
+    type: grpc
+    address: localhost:1
+sinks:
+  - name: out
+    type: stdout
+`
+
+func upper(ev sdk.Event) []sdk.Event {
+	ev.Value = bytes.ToUpper(ev.Value)
+	return []sdk.Event{ev}
+}
+
+func TestHarness_RunsSpecWithFakes(t *testing.T) {
+	filter := Script(Step{}, Step{Status: sdk.StatusDrop, Reason: "spam"}, Step{})
+	h := New(t, []byte(filterSpec), t.TempDir(),
+		WithTransformer("filter", filter),
+		WithTransformer("upper", Script(Step{Map: upper})))
+
+	h.SendValues("a", "b", "c")
+
+	h.AssertDelivered("out", "A", "C")
+	h.AssertDropped(0, 1, "spam")
+	h.AssertDropped(0, 1, DropFiltered)
+	h.AssertCommitted(0, 2)
+	h.AssertNoDuplicateAcks()
+	if filter.Calls() != 3 {
+		t.Fatalf("filter called %d times, want 3", filter.Calls())
+	}
+}
+
+func TestHarness_HeldAcksCommitInOrder(t *testing.T) {
+	out := NewSink()
+	out.HoldAcks()
+	h := New(t, []byte(filterSpec), t.TempDir(),
+		WithTransformer("filter", Script()),
+		WithTransformer("upper", Script()),
+		WithSink("out", out))
+
+	h.SendValues("a", "b", "c")
+	h.AssertNotCommitted(0, 0)
+
+	// Acking the later frames first must not move the commit past the
+	// frame still held.
+	out.Release(2, 1)
+	h.AssertNotCommitted(0, 0)
+	out.Release(0)
+	h.AssertCommitted(0, 2)
+	h.AssertNoDuplicateAcks()
+	if out.Held() != 0 {
+		t.Fatalf("%d acks still held", out.Held())
+	}
+}
+
+func TestHarness_DelayedAcksAndFailedStages(t *testing.T) {
+	out := &Sink{AckDelay: 20 * time.Millisecond}
+	h := New(t, []byte(filterSpec), t.TempDir(),
+		WithTransformer("filter", Script(Step{}, Step{Status: sdk.StatusError, Reason: "boom"})),
+		WithTransformer("upper", Script()),
+		WithSink("out", out))
+
+	h.SendValues("a", "b")
+	h.AssertNotCommitted(0, 0)
+	h.AssertCommitted(0, 1)
+	h.AssertDropped(0, 1, DropFailed)
+	h.AssertDropped(0, 1, "boom")
+	if err := h.LastError(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("last error %v, want the stage failure", err)
+	}
+}
+
+func TestHarness_SlowTransformerTimesOut(t *testing.T) {
+	spec := strings.Replace(filterSpec, "address: localhost:1\n  - name: upper", "address: localhost:1\n    timeout_ms: 10\n  - name: upper", 1)
+	h := New(t, []byte(spec), t.TempDir(),
+		WithTransformer("filter", Script(Step{Latency: time.Second})),
+		WithTransformer("upper", Script()))
+
+	h.SendValues("a")
+	h.AssertDropped(0, 0, "deadline exceeded")
+	h.AssertDelivered("out")
+}
+
+func TestHarness_FailedSinkPushIsNotAcked(t *testing.T) {
+	out := &Sink{Fail: func(f *pb.Frame) error {
+		if string(f.Value) == "bad" {
+			return errors.New("disk full")
+		}
+		return nil
+	}}
+	h := New(t, []byte(filterSpec), t.TempDir(),
+		WithTransformer("filter", Script()),
+		WithTransformer("upper", Script()),
+		WithSink("out", out))
+
+	h.SendValues("ok")
+	if err := h.Source.Send(Record("", "bad")); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("send: got %v, want the sink error", err)
+	}
+	h.AssertCommitted(0, 0)
+	h.AssertNotCommitted(0, 1)
+	h.AssertDelivered("out", "ok")
+}
+
+func TestSource_CommittedAndDuplicates(t *testing.T) {
+	s := NewSource()
+	s.track(Record("", "a"))
+	s.track(Record("", "b"))
+	if got := s.Committed(DefaultTopic, 0); got != -1 {
+		t.Fatalf("committed %d before any ack, want -1", got)
+	}
+	s.OnAck(&pb.ConnectorAck{Checkpoint: Offset(0, 1)})
+	if got := s.Committed(DefaultTopic, 0); got != -1 {
+		t.Fatalf("committed %d with offset 0 unacked, want -1", got)
+	}
+	s.OnAck(&pb.ConnectorAck{Checkpoint: Offset(0, 0)})
+	s.OnAck(&pb.ConnectorAck{Checkpoint: Offset(0, 0)})
+	if got := s.Committed(DefaultTopic, 0); got != 2 {
+		t.Fatalf("committed %d, want 2", got)
+	}
+	if dups := s.Duplicates(); len(dups) != 1 || dups[0] != "pipelinetest/0@0" {
+		t.Fatalf("duplicates %v", dups)
+	}
+}
+
+func TestHarness_UnregistersItsComponents(t *testing.T) {
+	var prefix string
+	t.Run("pipeline", func(t *testing.T) {
+		New(t, []byte(filterSpec), t.TempDir(),
+			WithTransformer("filter", Script(Step{})),
+			WithTransformer("upper", Script(Step{Map: upper})))
+		prefix = fmt.Sprintf("pipelinetest-%d", seq.Load())
+		if !transform.HasInProcess(prefix + "/filter") {
+			t.Fatalf("%s/filter not registered while the test runs", prefix)
+		}
+	})
+	if _, err := source.New(prefix); err == nil {
+		t.Fatalf("source %s still registered after the test", prefix)
+	}
+	if _, err := sink.NewAdapter(prefix + "/out"); err == nil {
+		t.Fatalf("sink %s/out still registered after the test", prefix)
+	}
+	if transform.HasInProcess(prefix+"/filter") || transform.HasInProcess(prefix+"/upper") {
+		t.Fatalf("transformers under %s still registered after the test", prefix)
+	}
+}
@@ -0,0 +1,137 @@
+package pipelinetest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/sink"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Sink records every frame pushed to it and acks it, straight away, after
+// AckDelay, or when Release is called while acks are held. Fail, if set,
+// decides which pushes fail; a failed push is not recorded or acked.
+type Sink struct {
+	AckDelay time.Duration
+	Fail     func(*pb.Frame) error
+
+	mu     sync.Mutex
+	ack    sink.EmitFn
+	frames []*pb.Frame
+	hold   bool
+	held   []*pb.CheckpointToken
+}
+
+var _ sink.AckAware = (*Sink)(nil)
+
+// NewSink returns a sink that acks every frame as it is pushed.
+func NewSink() *Sink { return &Sink{} }
+
+func (s *Sink) Configure(any) error { return nil }
+func (s *Sink) Close() error        { return nil }
+
+func (s *Sink) BindAck(fn sink.EmitFn) {
+	s.mu.Lock()
+	s.ack = fn
+	s.mu.Unlock()
+}
+
+func (s *Sink) Push(f *pb.Frame) error {
+	if s.Fail != nil {
+		if err := s.Fail(f); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.frames = append(s.frames, proto.Clone(f).(*pb.Frame))
+	ack, tok := s.ack, f.Checkpoint
+	if s.hold {
+		s.held = append(s.held, tok)
+		ack = nil
+	}
+	s.mu.Unlock()
+	switch {
+	case ack == nil:
+	case s.AckDelay > 0:
+		time.AfterFunc(s.AckDelay, func() { ack(tok) })
+	default:
+		ack(tok)
+	}
+	return nil
+}
+
+// HoldAcks keeps acks for frames pushed from now on until Release.
+func (s *Sink) HoldAcks() {
+	s.mu.Lock()
+	s.hold = true
+	s.mu.Unlock()
+}
+
+// Release acks the held frames at the given positions, in the order given,
+// or every held frame in push order when none are given, and stops holding
+// acks once none are left. Positions count from the first frame held.
+func (s *Sink) Release(positions ...int) {
+	s.mu.Lock()
+	ack := s.ack
+	var toks []*pb.CheckpointToken
+	if len(positions) == 0 {
+		toks, s.held = s.held, nil
+	} else {
+		for _, i := range positions {
+			if i < 0 || i >= len(s.held) || s.held[i] == nil {
+				s.mu.Unlock()
+				panic(fmt.Sprintf("pipelinetest: no held ack at position %d", i))
+			}
+			toks = append(toks, s.held[i])
+			s.held[i] = nil
+		}
+	}
+	if s.pending() == 0 {
+		s.hold, s.held = false, nil
+	}
+	s.mu.Unlock()
+	if ack == nil {
+		return
+	}
+	for _, tok := range toks {
+		ack(tok)
+	}
+}
+
+func (s *Sink) pending() int {
+	n := 0
+	for _, tok := range s.held {
+		if tok != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Held returns how many acks are being held.
+func (s *Sink) Held() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending()
+}
+
+// Frames returns copies of the frames pushed so far.
+func (s *Sink) Frames() []*pb.Frame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*pb.Frame(nil), s.frames...)
+}
+
+// Values returns the values of the frames pushed so far.
+func (s *Sink) Values() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, len(s.frames))
+	for i, f := range s.frames {
+		out[i] = string(f.Value)
+	}
+	return out
+}
@@ -0,0 +1,204 @@
+package pipelinetest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	pb "quanta/api/proto/v1"
+	"quanta/source"
+)
+
+// DefaultTopic is the topic of checkpoints the Source assigns.
+const DefaultTopic = "pipelinetest"
+
+// ErrClosed is returned by Send once the source is closed.
+var ErrClosed = errors.New("pipelinetest: source closed")
+
+// Offset returns a Kafka checkpoint on DefaultTopic.
+func Offset(partition int32, offset int64) *pb.CheckpointToken {
+	return &pb.CheckpointToken{Kind: &pb.CheckpointToken_Kafka{Kafka: &pb.KafkaOffset{
+		Topic: DefaultTopic, Partition: partition, Offset: offset,
+	}}}
+}
+
+// Record returns a frame with key and value and no checkpoint; Send gives
+// it the next offset of partition 0.
+func Record(key, value string) *pb.Frame {
+	f := &pb.Frame{Value: []byte(value)}
+	if key != "" {
+		f.Key = []byte(key)
+	}
+	return f
+}
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+type sendReq struct {
+	frame *pb.Frame
+	done  chan error
+}
+
+// Source is an in-memory source that emits the frames handed to Send and
+// tracks every checkpoint it emitted and every ack it received, the way a
+// Kafka source decides what to commit.
+type Source struct {
+	in     chan sendReq
+	fail   chan error
+	closed chan struct{}
+	once   sync.Once
+
+	mu      sync.Mutex
+	next    map[int32]int64
+	emitted map[partitionKey]map[int64]bool
+	acks    map[partitionKey]map[int64]int
+}
+
+var _ source.AckAware = (*Source)(nil)
+
+// NewSource returns a source with nothing to emit.
+func NewSource() *Source {
+	return &Source{
+		in:      make(chan sendReq),
+		fail:    make(chan error),
+		closed:  make(chan struct{}),
+		next:    map[int32]int64{},
+		emitted: map[partitionKey]map[int64]bool{},
+		acks:    map[partitionKey]map[int64]int{},
+	}
+}
+
+// Run emits frames as Send hands them over until ctx is done or Fail is
+// called.
+func (s *Source) Run(ctx context.Context, emit source.EmitFunc) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.closed:
+			return ErrClosed
+		case err := <-s.fail:
+			return err
+		case req := <-s.in:
+			req.done <- emit(req.frame)
+		}
+	}
+}
+
+// Send emits each frame in turn, waiting until the pipeline has processed
+// it, and returns the first error the pipeline reported. Frames without a
+// checkpoint get the next offset of partition 0 on DefaultTopic.
+func (s *Source) Send(frames ...*pb.Frame) error {
+	for _, f := range frames {
+		s.track(f)
+		req := sendReq{frame: f, done: make(chan error, 1)}
+		select {
+		case s.in <- req:
+		case <-s.closed:
+			return ErrClosed
+		}
+		if err := <-req.done; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fail makes the running Run return err, as a lost connection would.
+func (s *Source) Fail(err error) {
+	select {
+	case s.fail <- err:
+	case <-s.closed:
+	}
+}
+
+func (s *Source) track(f *pb.Frame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f.Checkpoint == nil {
+		f.Checkpoint = Offset(0, s.next[0])
+	}
+	k := f.Checkpoint.GetKafka()
+	if k == nil {
+		return
+	}
+	if k.Topic == DefaultTopic && k.Offset >= s.next[k.Partition] {
+		s.next[k.Partition] = k.Offset + 1
+	}
+	key := partitionKey{k.Topic, k.Partition}
+	if s.emitted[key] == nil {
+		s.emitted[key] = map[int64]bool{}
+	}
+	s.emitted[key][k.Offset] = true
+}
+
+// OnAck records an ack for the frame's checkpoint.
+func (s *Source) OnAck(a *pb.ConnectorAck) {
+	k := a.GetCheckpoint().GetKafka()
+	if k == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := partitionKey{k.Topic, k.Partition}
+	if s.acks[key] == nil {
+		s.acks[key] = map[int64]int{}
+	}
+	s.acks[key][k.Offset]++
+}
+
+// Acks returns how many times the checkpoint at offset was acked.
+func (s *Source) Acks(topic string, partition int32, offset int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.acks[partitionKey{topic, partition}][offset]
+}
+
+// Committed returns the offset a Kafka source would commit for the
+// partition: one past the longest run of acked offsets from the first one
+// emitted, or -1 if nothing was acked yet.
+func (s *Source) Committed(topic string, partition int32) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := partitionKey{topic, partition}
+	offsets := make([]int64, 0, len(s.emitted[key]))
+	for off := range s.emitted[key] {
+		offsets = append(offsets, off)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	committed := int64(-1)
+	for _, off := range offsets {
+		if s.acks[key][off] == 0 {
+			break
+		}
+		committed = off + 1
+	}
+	return committed
+}
+
+// Duplicates lists the checkpoints acked more than once, as
+// "topic/partition@offset".
+func (s *Source) Duplicates() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for key, offs := range s.acks {
+		for off, n := range offs {
+			if n > 1 {
+				out = append(out, checkpointString(key.topic, key.partition, off))
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Close stops Run and makes further sends fail.
+func (s *Source) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
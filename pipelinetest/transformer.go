@@ -0,0 +1,82 @@
+package pipelinetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"quanta/sdk"
+)
+
+// Step is one scripted answer of a Transformer: wait Latency, or until the
+// call's deadline, then answer Status. Reason is sent as the error message of
+// any other status, so a DROP's reason shows up in the harness's drops. Map rewrites the
+// event for an OK answer; nil passes it through.
+type Step struct {
+	Status  sdk.Status
+	Latency time.Duration
+	Reason  string
+	Map     func(sdk.Event) []sdk.Event
+}
+
+// Transformer is a fake transformer that answers calls from a script, one
+// step per call; the last step repeats. An empty script passes every event
+// through.
+type Transformer struct {
+	mu     sync.Mutex
+	script []Step
+	events []sdk.Event
+}
+
+// Script returns a transformer answering with steps in turn.
+func Script(steps ...Step) *Transformer {
+	return &Transformer{script: steps}
+}
+
+// Handle is the transformer's SDK handler.
+func (tr *Transformer) Handle(ctx context.Context, ev sdk.Event) ([]sdk.Event, sdk.Status, error) {
+	tr.mu.Lock()
+	step := Step{Status: sdk.StatusOK}
+	if n := len(tr.script); n > 0 {
+		step = tr.script[min(len(tr.events), n-1)]
+	}
+	tr.events = append(tr.events, ev)
+	tr.mu.Unlock()
+
+	if step.Latency > 0 {
+		t := time.NewTimer(step.Latency)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil, sdk.StatusError, ctx.Err()
+		}
+	}
+	if step.Status != sdk.StatusOK {
+		var err error
+		if step.Reason != "" {
+			err = errors.New(step.Reason)
+		}
+		return nil, step.Status, err
+	}
+	if step.Map != nil {
+		return step.Map(ev), sdk.StatusOK, nil
+	}
+	return []sdk.Event{ev}, sdk.StatusOK, nil
+}
+
+// Calls returns how many times the transformer was called, retries
+// included.
+func (tr *Transformer) Calls() int {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return len(tr.events)
+}
+
+// Events returns the events the transformer was called with.
+func (tr *Transformer) Events() []sdk.Event {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]sdk.Event(nil), tr.events...)
+}
@@ -0,0 +1,362 @@
+package plugintest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "quanta/api/proto/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var checks = []check{
+	{"metadata", checkMetadata},
+	{"health", checkHealth},
+	{"configure", checkConfigure},
+	{"empty-payload", checkEmptyPayload},
+	{"large-payload", checkLargePayload},
+	{"deadline", checkDeadline},
+	{"status-semantics", checkStatusSemantics},
+	{"metadata-passthrough", checkPassthrough},
+	{"stream-credits", checkStream},
+	{"concurrency", checkConcurrency},
+}
+
+func validate(resp *pb.TransformResponse) error {
+	if resp == nil {
+		return errors.New("nil response")
+	}
+	n := len(resp.GetEvents())
+	switch resp.GetStatus() {
+	case pb.Status_OK:
+		if n == 0 {
+			return errors.New("status OK with zero events; filtering must use DROP or the frame is never acked")
+		}
+	case pb.Status_DROP:
+		if n > 0 {
+			return fmt.Errorf("status DROP carries %d events; they are discarded", n)
+		}
+	case pb.Status_RETRY, pb.Status_ERROR:
+		if resp.GetErrorMessage() == "" {
+			return fmt.Errorf("status %s without error_message", resp.GetStatus())
+		}
+	default:
+		return fmt.Errorf("unknown status %d", resp.GetStatus())
+	}
+	return nil
+}
+
+func (s *suite) call(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+	return s.cli.Transform(ctx, req)
+}
+
+var sampleHeaders = map[string][]byte{
+	"x-plugintest": []byte("1"),
+	"content-type": []byte("application/json"),
+	"x-binary":     {0xff, 0x00, 0xfe},
+}
+
+func (s *suite) sample() *pb.TransformRequest {
+	req := &pb.TransformRequest{
+		PipelineId: "plugintest",
+		PluginId:   "plugintest",
+		Payload:    s.opts.Payload,
+		Metadata: &pb.EventMetadata{
+			TimestampMs:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixMilli(),
+			SourcePartition: "3",
+			SourceOffset:    "42",
+			Attributes:      map[string]string{"source.topic": "plugintest"},
+		},
+	}
+	if s.protocol >= 2 {
+		req.Key = []byte("plugintest-key")
+		req.Metadata.BinaryHeaders = sampleHeaders
+	} else {
+		req.Metadata.Headers = map[string]string{"x-plugintest": "1", "content-type": "application/json"}
+	}
+	return req
+}
+
+func checkMetadata(ctx context.Context, s *suite) (Outcome, string) {
+	cctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+	md, err := s.cli.Metadata(cctx, &pb.MetadataRequest{})
+	if err != nil {
+		return Fail, err.Error()
+	}
+	s.meta = md
+	if md.GetName() == "" {
+		return Fail, "empty plugin name"
+	}
+	v := md.GetProtocolVersion()
+	if v == nil {
+		return Fail, "protocol_version not set"
+	}
+	if v.GetMajor() < 1 {
+		return Fail, fmt.Sprintf("protocol %d.%d.%d not supported", v.GetMajor(), v.GetMinor(), v.GetPatch())
+	}
+	s.protocol = min(v.GetMajor(), SupportedProtocolMajor)
+	return Pass, fmt.Sprintf("%s %s, protocol %d.%d.%d", md.GetName(), md.GetVersion(), v.GetMajor(), v.GetMinor(), v.GetPatch())
+}
+
+func checkHealth(ctx context.Context, s *suite) (Outcome, string) {
+	cctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+	h, err := s.cli.Health(cctx, &pb.HealthRequest{})
+	if err != nil {
+		return Fail, err.Error()
+	}
+	if !h.GetOk() {
+		return Fail, "not ok: " + h.GetDetails()
+	}
+	return Pass, h.GetDetails()
+}
+
+func checkConfigure(ctx context.Context, s *suite) (Outcome, string) {
+	cctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+	resp, err := s.cli.Configure(cctx, &pb.ConfigureRequest{PipelineId: "plugintest", PluginId: "plugintest", Config: s.opts.Config})
+	if status.Code(err) == codes.Unimplemented {
+		return Skip, "Configure not implemented; stage config is ignored"
+	}
+	if err != nil {
+		return Fail, err.Error()
+	}
+	if !resp.GetOk() {
+		return Fail, "config rejected: " + resp.GetErrorMessage()
+	}
+	return Pass, fmt.Sprintf("%d keys accepted", len(s.opts.Config.GetFields()))
+}
+
+func checkEmptyPayload(ctx context.Context, s *suite) (Outcome, string) {
+	req := s.sample()
+	req.Payload = nil
+	resp, err := s.call(ctx, req)
+	if err != nil {
+		return Fail, "rpc error: " + err.Error()
+	}
+	if err := validate(resp); err != nil {
+		return Fail, err.Error()
+	}
+	return Pass, resp.GetStatus().String()
+}
+
+func checkLargePayload(ctx context.Context, s *suite) (Outcome, string) {
+	req := s.sample()
+	req.Payload = bytes.Repeat([]byte("q"), s.opts.LargeBytes)
+	resp, err := s.call(ctx, req)
+	if err != nil {
+		return Fail, fmt.Sprintf("%d bytes: %v", s.opts.LargeBytes, err)
+	}
+	if err := validate(resp); err != nil {
+		return Fail, err.Error()
+	}
+	return Pass, fmt.Sprintf("%d bytes: %s", s.opts.LargeBytes, resp.GetStatus())
+}
+
+// checkDeadline sends a call with a short deadline. A call that runs past it
+// is only cancelled on the client, so the plugin must show through Health's
+// in_flight count that it stopped working on the call too.
+func checkDeadline(ctx context.Context, s *suite) (Outcome, string) {
+	const deadline = 20 * time.Millisecond
+	cctx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+	start := time.Now()
+	_, err := s.cli.Transform(cctx, s.sample())
+	took := time.Since(start)
+	if took > deadline+s.opts.DeadlineSlop {
+		return Fail, fmt.Sprintf("returned after %s with a %s deadline", took.Round(time.Millisecond), deadline)
+	}
+	if err == nil {
+		return Pass, fmt.Sprintf("answered within the deadline, after %s", took.Round(time.Millisecond))
+	}
+	if status.Code(err) != codes.DeadlineExceeded && status.Code(err) != codes.Canceled {
+		return Fail, "unexpected error: " + err.Error()
+	}
+
+	stop := time.Now().Add(s.opts.DeadlineSlop)
+	for {
+		hctx, hcancel := context.WithTimeout(ctx, s.opts.Timeout)
+		h, herr := s.cli.Health(hctx, &pb.HealthRequest{})
+		hcancel()
+		switch {
+		case herr != nil:
+			return Fail, "health after a missed deadline: " + herr.Error()
+		case h.InFlight == nil:
+			return Skip, "deadline hit; health does not report in_flight, so whether the plugin stopped work is unknown"
+		case h.GetInFlight() == 0:
+			return Pass, fmt.Sprintf("stopped work %s after the call was cancelled", time.Since(start.Add(took)).Round(time.Millisecond))
+		case time.Now().After(stop):
+			return Fail, fmt.Sprintf("still working on %d calls %s after the deadline", h.GetInFlight(), s.opts.DeadlineSlop)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func checkStatusSemantics(ctx context.Context, s *suite) (Outcome, string) {
+	resp, err := s.call(ctx, s.sample())
+	if err != nil {
+		return Fail, err.Error()
+	}
+	if err := validate(resp); err != nil {
+		return Fail, err.Error()
+	}
+	return Pass, fmt.Sprintf("%s with %d events", resp.GetStatus(), len(resp.GetEvents()))
+}
+
+func checkPassthrough(ctx context.Context, s *suite) (Outcome, string) {
+	req := s.sample()
+	resp, err := s.call(ctx, req)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	if resp.GetStatus() != pb.Status_OK {
+		return Skip, "sample payload not transformed (" + resp.GetStatus().String() + ")"
+	}
+	for i, ev := range resp.GetEvents() {
+		md := ev.GetMetadata()
+		if s.protocol >= 2 {
+			for k, v := range req.Metadata.BinaryHeaders {
+				if got, ok := md.GetBinaryHeaders()[k]; !ok || !bytes.Equal(got, v) {
+					return Fail, fmt.Sprintf("event %d lost binary header %q", i, k)
+				}
+			}
+			continue
+		}
+		for k, v := range req.Metadata.Headers {
+			if got, ok := md.GetHeaders()[k]; !ok || got != v {
+				return Fail, fmt.Sprintf("event %d lost header %q", i, k)
+			}
+		}
+	}
+	return Pass, fmt.Sprintf("headers preserved (protocol v%d)", s.protocol)
+}
+
+func checkStream(ctx context.Context, s *suite) (Outcome, string) {
+	if s.meta != nil && s.meta.GetCapabilities()["stream"] == "false" {
+		return Skip, "plugin declares stream=false"
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*s.opts.Timeout)
+	defer cancel()
+	st, err := s.cli.TransformStream(ctx)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	first, err := st.Recv()
+	if status.Code(err) == codes.Unimplemented {
+		return Skip, "TransformStream not implemented"
+	}
+	if err != nil {
+		return Fail, "waiting for GRANT: " + err.Error()
+	}
+	grant := first.GetControl()
+	if grant.GetType() != pb.ControlMessage_GRANT || grant.GetCredits() <= 0 {
+		return Fail, fmt.Sprintf("first message must be GRANT with credits > 0, got %v", first)
+	}
+
+	n := int(grant.GetCredits())
+	if n > s.opts.Requests {
+		n = s.opts.Requests
+	}
+	for i := 0; i < n; i++ {
+		req := s.sample()
+		req.Metadata.SourceOffset = strconv.Itoa(i)
+		if err := st.Send(&pb.TransformStreamMessage{Msg: &pb.TransformStreamMessage_Request{Request: req}}); err != nil {
+			return Fail, fmt.Sprintf("send %d: %v", i, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		m, err := st.Recv()
+		if err != nil {
+			return Fail, fmt.Sprintf("recv %d: %v", i, err)
+		}
+		resp := m.GetResponse()
+		if resp == nil {
+			return Fail, fmt.Sprintf("expected response %d, got %v", i, m)
+		}
+		if err := validate(resp); err != nil {
+			return Fail, fmt.Sprintf("response %d: %v", i, err)
+		}
+	}
+
+	ping := &pb.TransformStreamMessage{Msg: &pb.TransformStreamMessage_Control{Control: &pb.ControlMessage{Type: pb.ControlMessage_PING}}}
+	if err := st.Send(ping); err != nil {
+		return Fail, "send PING: " + err.Error()
+	}
+	m, err := st.Recv()
+	if err != nil || m.GetControl().GetType() != pb.ControlMessage_PONG {
+		return Fail, fmt.Sprintf("PING not answered with PONG: %v %v", m, err)
+	}
+
+	if err := st.CloseSend(); err != nil {
+		return Fail, "close send: " + err.Error()
+	}
+	if _, err := st.Recv(); !errors.Is(err, io.EOF) {
+		return Fail, fmt.Sprintf("stream not closed after half-close: %v", err)
+	}
+	return Pass, fmt.Sprintf("window %d, %d requests", grant.GetCredits(), n)
+}
+
+func checkConcurrency(ctx context.Context, s *suite) (Outcome, string) {
+	want, err := s.call(ctx, s.sample())
+	if err != nil {
+		return Fail, err.Error()
+	}
+	total := s.opts.Concurrency * s.opts.Requests
+	errs := make(chan error, total)
+	var wg sync.WaitGroup
+	for w := 0; w < s.opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < s.opts.Requests; i++ {
+				got, err := s.call(ctx, s.sample())
+				if err != nil {
+					errs <- err
+					continue
+				}
+				if err := sameResponse(want, got); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	failed := 0
+	var first error
+	for err := range errs {
+		if first == nil {
+			first = err
+		}
+		failed++
+	}
+	if failed > 0 {
+		return Fail, fmt.Sprintf("%d/%d concurrent calls diverged: %v", failed, total, first)
+	}
+	return Pass, fmt.Sprintf("%d calls across %d workers", total, s.opts.Concurrency)
+}
+
+func sameResponse(want, got *pb.TransformResponse) error {
+	if want.GetStatus() != got.GetStatus() {
+		return fmt.Errorf("status %s, sequential call gave %s", got.GetStatus(), want.GetStatus())
+	}
+	if len(want.GetEvents()) != len(got.GetEvents()) {
+		return fmt.Errorf("%d events, sequential call gave %d", len(got.GetEvents()), len(want.GetEvents()))
+	}
+	for i := range want.GetEvents() {
+		if !bytes.Equal(want.Events[i].GetValue(), got.Events[i].GetValue()) {
+			return fmt.Errorf("event %d value differs from sequential call", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+// Package plugintest runs the transformer contract checks against any
+// TransformService endpoint: metadata and protocol version, health, empty
+// and large payloads, deadlines, status semantics, metadata passthrough,
+// streaming credits and concurrent use. Run returns a Report; RunT wires the
+// same checks into a Go test. cmd/plugintest is the command-line front-end.
+package plugintest
@@ -0,0 +1,144 @@
+package plugintest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	pb "quanta/api/proto/v1"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const SupportedProtocolMajor = 2
+
+type Options struct {
+	Timeout      time.Duration
+	Payload      []byte
+	LargeBytes   int
+	Concurrency  int
+	Requests     int
+	DeadlineSlop time.Duration
+	Config       *structpb.Struct
+}
+
+func (o *Options) applyDefaults() {
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+	if len(o.Payload) == 0 {
+		o.Payload = []byte(`{"context":{"event":"conformance"},"n":1}`)
+	}
+	if o.LargeBytes <= 0 {
+		o.LargeBytes = 1 << 20
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 16
+	}
+	if o.Requests <= 0 {
+		o.Requests = 8
+	}
+	if o.DeadlineSlop <= 0 {
+		o.DeadlineSlop = 250 * time.Millisecond
+	}
+}
+
+type Outcome string
+
+const (
+	Pass Outcome = "PASS"
+	Fail Outcome = "FAIL"
+	Skip Outcome = "SKIP"
+)
+
+type Result struct {
+	Name     string        `json:"name"`
+	Outcome  Outcome       `json:"outcome"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+type Report struct {
+	Target  string   `json:"target"`
+	Plugin  string   `json:"plugin,omitempty"`
+	Results []Result `json:"results"`
+}
+
+func (r Report) Passed() bool {
+	for _, res := range r.Results {
+		if res.Outcome == Fail {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "target:\t%s\n", r.Target)
+	if r.Plugin != "" {
+		fmt.Fprintf(tw, "plugin:\t%s\n", r.Plugin)
+	}
+	fmt.Fprintln(tw, "CHECK\tRESULT\tTIME\tDETAIL")
+	for _, res := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.Name, res.Outcome, res.Duration.Round(time.Millisecond), res.Detail)
+	}
+	verdict := "PASS"
+	if !r.Passed() {
+		verdict = "FAIL"
+	}
+	fmt.Fprintf(tw, "verdict:\t%s\n", verdict)
+	return tw.Flush()
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type check struct {
+	name string
+	run  func(context.Context, *suite) (Outcome, string)
+}
+
+type suite struct {
+	cli      pb.TransformServiceClient
+	opts     Options
+	meta     *pb.MetadataResponse
+	protocol int32
+}
+
+func Run(ctx context.Context, target string, cli pb.TransformServiceClient, opts Options) Report {
+	opts.applyDefaults()
+	s := &suite{cli: cli, opts: opts, protocol: 1}
+	rep := Report{Target: target}
+	for _, c := range checks {
+		start := time.Now()
+		cctx, cancel := context.WithTimeout(ctx, 4*opts.Timeout)
+		out, detail := c.run(cctx, s)
+		cancel()
+		rep.Results = append(rep.Results, Result{Name: c.name, Outcome: out, Detail: detail, Duration: time.Since(start)})
+	}
+	if s.meta != nil {
+		rep.Plugin = s.meta.Name + "@" + s.meta.Version
+	}
+	return rep
+}
+
+func RunT(t testing.TB, cli pb.TransformServiceClient, opts Options) {
+	t.Helper()
+	rep := Run(context.Background(), "test", cli, opts)
+	for _, res := range rep.Results {
+		switch res.Outcome {
+		case Fail:
+			t.Errorf("plugintest %s: %s", res.Name, res.Detail)
+		case Skip:
+			t.Logf("plugintest %s skipped: %s", res.Name, res.Detail)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package plugintest
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/sdk"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func serve(t *testing.T, srv pb.TransformServiceServer) pb.TransformServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(4 << 20)
+	g := grpc.NewServer()
+	pb.RegisterTransformServiceServer(g, srv)
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+	return pb.NewTransformServiceClient(cc)
+}
+
+func TestRun_SDKEchoPasses(t *testing.T) {
+	cli := serve(t, sdk.NewServer(func(_ context.Context, ev sdk.Event) ([]sdk.Event, sdk.Status, error) {
+		return []sdk.Event{ev}, sdk.StatusOK, nil
+	}, sdk.WithName("echo"), sdk.WithVersion("1.0.0")))
+
+	rep := Run(context.Background(), "bufnet", cli, Options{})
+	if !rep.Passed() {
+		var sb strings.Builder
+		_ = rep.WriteText(&sb)
+		t.Fatalf("expected echo plugin to pass:\n%s", sb.String())
+	}
+	if rep.Plugin != "echo@1.0.0" {
+		t.Fatalf("unexpected plugin label %q", rep.Plugin)
+	}
+}
+
+type filterOK struct {
+	pb.UnimplementedTransformServiceServer
+}
+
+func (filterOK) Metadata(context.Context, *pb.MetadataRequest) (*pb.MetadataResponse, error) {
+	return &pb.MetadataResponse{Name: "filter", ProtocolVersion: &pb.PluginVersion{Major: 1}}, nil
+}
+func (filterOK) Health(context.Context, *pb.HealthRequest) (*pb.HealthResponse, error) {
+	return &pb.HealthResponse{Ok: true}, nil
+}
+func (filterOK) Transform(context.Context, *pb.TransformRequest) (*pb.TransformResponse, error) {
+	return &pb.TransformResponse{Status: pb.Status_OK}, nil
+}
+
+func TestRun_FlagsOKWithoutEventsAndSkipsStream(t *testing.T) {
+	rep := Run(context.Background(), "bufnet", serve(t, filterOK{}), Options{Concurrency: 2, Requests: 2})
+	if rep.Passed() {
+		t.Fatal("expected failure for OK responses without events")
+	}
+	outcomes := map[string]Outcome{}
+	for _, r := range rep.Results {
+		outcomes[r.Name] = r.Outcome
+	}
+	if outcomes["status-semantics"] != Fail {
+		t.Fatalf("status-semantics: want FAIL, got %s", outcomes["status-semantics"])
+	}
+	if outcomes["stream-credits"] != Skip {
+		t.Fatalf("stream-credits: want SKIP, got %s", outcomes["stream-credits"])
+	}
+	if outcomes["metadata"] != Pass || outcomes["health"] != Pass {
+		t.Fatalf("metadata/health should pass: %+v", outcomes)
+	}
+}
+
+func TestCheckDeadline_NeedsThePluginToStopWork(t *testing.T) {
+	slow := func(honor bool) sdk.HandlerFunc {
+		return func(ctx context.Context, ev sdk.Event) ([]sdk.Event, sdk.Status, error) {
+			wait := 400 * time.Millisecond
+			if honor {
+				select {
+				case <-ctx.Done():
+					return nil, sdk.StatusError, ctx.Err()
+				case <-time.After(wait):
+				}
+			} else {
+				time.Sleep(wait)
+			}
+			return []sdk.Event{ev}, sdk.StatusOK, nil
+		}
+	}
+	cases := []struct {
+		name string
+		srv  pb.TransformServiceServer
+		want Outcome
+	}{
+		{"honors deadline", sdk.NewServer(slow(true)), Pass},
+		{"ignores deadline", sdk.NewServer(slow(false)), Fail},
+		{"no in_flight", sleepyOK{}, Skip},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{DeadlineSlop: 100 * time.Millisecond}
+			opts.applyDefaults()
+			s := &suite{cli: serve(t, tc.srv), opts: opts, protocol: 2}
+			if out, detail := checkDeadline(context.Background(), s); out != tc.want {
+				t.Fatalf("want %s, got %s: %s", tc.want, out, detail)
+			}
+		})
+	}
+}
+
+// sleepyOK answers only once its deadline passes and does not report
+// in_flight.
+type sleepyOK struct{ filterOK }
+
+func (sleepyOK) Transform(ctx context.Context, _ *pb.TransformRequest) (*pb.TransformResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
@@ -0,0 +1,21 @@
+// Package sdk is the Go toolkit for writing Quanta transformer plugins.
+// A plugin implements a single HandlerFunc; the SDK serves it as a
+// TransformService over TCP or a unix socket, answers Metadata and Health,
+// recovers handler panics, runs the TransformStream credit protocol and
+// performs the exec handshake when the engine launches the plugin itself.
+//
+// Streaming protocol: on open the server sends GRANT with its window; the
+// client keeps at most that many requests outstanding and every response
+// returns one credit. Responses are written in request order. PING is
+// answered with PONG, FLUSH is echoed once all earlier requests are answered
+// and STOP ends the stream after the outstanding requests are answered.
+//
+// The SDK speaks protocol v2: handlers see the record key and lossless
+// binary headers, may replace the key, and may route an event to a named
+// sink or topic. Responses also carry string headers so v1 engines keep
+// working.
+//
+// Static stage settings from the pipeline spec arrive through Configure
+// before the first event; handlers read them with StageFrom(ctx), keyed by
+// pipeline and stage name so one binary can serve several pipelines.
+package sdk
@@ -0,0 +1,133 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	pb "quanta/api/proto/v1"
+)
+
+type Status int32
+
+const (
+	StatusOK    = Status(pb.Status_OK)
+	StatusDrop  = Status(pb.Status_DROP)
+	StatusRetry = Status(pb.Status_RETRY)
+	StatusError = Status(pb.Status_ERROR)
+)
+
+func (s Status) String() string { return pb.Status(s).String() }
+
+type Route struct {
+	Sink  string
+	Topic string
+}
+
+type Event struct {
+	ID         string
+	Key        []byte
+	Value      []byte
+	Timestamp  time.Time
+	Headers    map[string][]byte
+	Attributes map[string]string
+	Partition  string
+	Offset     string
+	Route      Route
+}
+
+type HandlerFunc func(ctx context.Context, ev Event) ([]Event, Status, error)
+
+type BatchResult struct {
+	Events []Event
+	Status Status
+	Err    error
+}
+
+type BatchHandlerFunc func(ctx context.Context, evs []Event) []BatchResult
+
+type retryError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryError) Error() string { return e.err.Error() }
+func (e *retryError) Unwrap() error { return e.err }
+
+func RetryAfter(err error, after time.Duration) error {
+	if err == nil {
+		err = errors.New("retry requested")
+	}
+	return &retryError{err: err, after: after}
+}
+
+func eventFromRequest(req *pb.TransformRequest) Event {
+	ev := Event{Key: req.GetKey(), Value: req.GetPayload()}
+	md := req.GetMetadata()
+	if md == nil {
+		return ev
+	}
+	if md.TimestampMs > 0 {
+		ev.Timestamp = time.UnixMilli(md.TimestampMs)
+	}
+	if len(md.BinaryHeaders) > 0 {
+		ev.Headers = md.BinaryHeaders
+	} else if len(md.Headers) > 0 {
+		ev.Headers = make(map[string][]byte, len(md.Headers))
+		for k, v := range md.Headers {
+			ev.Headers[k] = []byte(v)
+		}
+	}
+	ev.Attributes = md.Attributes
+	ev.Partition = md.SourcePartition
+	ev.Offset = md.SourceOffset
+	return ev
+}
+
+func (ev Event) toProto() *pb.Event {
+	md := &pb.EventMetadata{
+		SourcePartition: ev.Partition,
+		SourceOffset:    ev.Offset,
+		Attributes:      ev.Attributes,
+	}
+	if !ev.Timestamp.IsZero() {
+		md.TimestampMs = ev.Timestamp.UnixMilli()
+	}
+	if len(ev.Headers) > 0 {
+		md.BinaryHeaders = ev.Headers
+		md.Headers = make(map[string]string, len(ev.Headers))
+		for k, v := range ev.Headers {
+			md.Headers[k] = strings.ToValidUTF8(string(v), "\uFFFD")
+		}
+	}
+	out := &pb.Event{Id: ev.ID, Key: ev.Key, Value: ev.Value, Metadata: md}
+	if ev.Route != (Route{}) {
+		out.Route = &pb.Route{Sink: ev.Route.Sink, Topic: ev.Route.Topic}
+	}
+	return out
+}
+
+func toResponse(evs []Event, st Status, err error) *pb.TransformResponse {
+	resp := &pb.TransformResponse{Status: pb.Status(st)}
+	if err != nil {
+		resp.ErrorMessage = err.Error()
+		var re *retryError
+		switch {
+		case errors.As(err, &re):
+			resp.Status = pb.Status_RETRY
+			resp.RetryAfterMs = int32(re.after / time.Millisecond)
+		case st == StatusOK:
+			resp.Status = pb.Status_ERROR
+		}
+		return resp
+	}
+	if resp.Status != pb.Status_OK {
+		return resp
+	}
+	resp.Events = make([]*pb.Event, 0, len(evs))
+	for _, ev := range evs {
+		resp.Events = append(resp.Events, ev.toProto())
+	}
+	return resp
+}
@@ -0,0 +1,55 @@
+package sdk
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	HandshakeCookieKey   = "QUANTA_PLUGIN_COOKIE"
+	HandshakeCookieValue = "3f9c1d7e-quanta-transformer"
+	HandshakeVersion     = 1
+
+	handshakePrefix = "quanta"
+)
+
+type Handshake struct {
+	Version int
+	Network string
+	Address string
+}
+
+func (h Handshake) String() string {
+	return fmt.Sprintf("%s|%d|%s|%s", handshakePrefix, h.Version, h.Network, h.Address)
+}
+
+func (h Handshake) Target() string {
+	if h.Network == "unix" {
+		return "unix://" + h.Address
+	}
+	return h.Address
+}
+
+func ParseHandshake(line string) (Handshake, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 4 || parts[0] != handshakePrefix {
+		return Handshake{}, fmt.Errorf("sdk: malformed handshake %q", line)
+	}
+	v, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Handshake{}, fmt.Errorf("sdk: handshake version %q: %w", parts[1], err)
+	}
+	if v != HandshakeVersion {
+		return Handshake{}, fmt.Errorf("sdk: handshake version %d not supported (want %d)", v, HandshakeVersion)
+	}
+	if parts[2] != "unix" && parts[2] != "tcp" {
+		return Handshake{}, fmt.Errorf("sdk: handshake network %q not supported", parts[2])
+	}
+	return Handshake{Version: v, Network: parts[2], Address: parts[3]}, nil
+}
+
+func Launched() bool {
+	return os.Getenv(HandshakeCookieKey) == HandshakeCookieValue
+}
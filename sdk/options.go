@@ -0,0 +1,72 @@
+package sdk
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DefaultAddress = ":50052"
+	DefaultWindow  = 64
+)
+
+type options struct {
+	name            string
+	version         string
+	capabilities    map[string]string
+	address         string
+	window          int
+	batch           BatchHandlerFunc
+	maxBatch        int
+	health          func(context.Context) error
+	configure       func(context.Context, Stage) error
+	shutdownTimeout time.Duration
+}
+
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		name:            "plugin",
+		version:         "0.0.0",
+		capabilities:    map[string]string{},
+		address:         DefaultAddress,
+		window:          DefaultWindow,
+		shutdownTimeout: 10 * time.Second,
+	}
+}
+
+func WithName(name string) Option       { return func(o *options) { o.name = name } }
+func WithVersion(version string) Option { return func(o *options) { o.version = version } }
+func WithAddress(addr string) Option    { return func(o *options) { o.address = addr } }
+
+func WithCapability(key, value string) Option {
+	return func(o *options) { o.capabilities[key] = value }
+}
+
+func WithWindow(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.window = n
+		}
+	}
+}
+
+func WithBatchHandler(fn BatchHandlerFunc, maxBatch int) Option {
+	return func(o *options) {
+		o.batch = fn
+		o.maxBatch = maxBatch
+	}
+}
+
+func WithHealthCheck(fn func(context.Context) error) Option {
+	return func(o *options) { o.health = fn }
+}
+
+func WithConfigure(fn func(context.Context, Stage) error) Option {
+	return func(o *options) { o.configure = fn }
+}
+
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) { o.shutdownTimeout = d }
+}
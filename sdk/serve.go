@@ -0,0 +1,107 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/logging"
+
+	"google.golang.org/grpc"
+)
+
+func Serve(h HandlerFunc, opts ...Option) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return ServeContext(ctx, h, opts...)
+}
+
+func ServeContext(ctx context.Context, h HandlerFunc, opts ...Option) error {
+	srv, err := newServer(h, opts...)
+	if err != nil {
+		return err
+	}
+	log := logging.L().With("component", "sdk", "plugin", srv.opts.name)
+
+	lis, cleanup, err := srv.listen()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	g := grpc.NewServer()
+	pb.RegisterTransformServiceServer(g, srv)
+
+	if Launched() {
+		hs := Handshake{Version: HandshakeVersion, Network: lis.Addr().Network(), Address: lis.Addr().String()}
+		if _, err := fmt.Fprintln(os.Stdout, hs.String()); err != nil {
+			return fmt.Errorf("sdk: write handshake: %w", err)
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- g.Serve(lis) }()
+	log.Info("plugin listening", "addr", lis.Addr().String())
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("plugin shutting down")
+	done := make(chan struct{})
+	go func() {
+		g.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(srv.opts.shutdownTimeout):
+		log.Warn("graceful stop timed out; closing open streams")
+		g.Stop()
+		<-done
+	}
+	return nil
+}
+
+func (s *Server) listen() (net.Listener, func(), error) {
+	if Launched() {
+		dir, err := os.MkdirTemp("", "quanta-plugin-")
+		if err != nil {
+			return nil, nil, err
+		}
+		lis, err := net.Listen("unix", filepath.Join(dir, "plugin.sock"))
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			return nil, nil, err
+		}
+		return lis, func() { _ = os.RemoveAll(dir) }, nil
+	}
+	network, addr := splitAddress(s.opts.address)
+	lis, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lis, func() {}, nil
+}
+
+func splitAddress(addr string) (network, address string) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		return "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "unix:"):
+		return "unix", strings.TrimPrefix(addr, "unix:")
+	case strings.HasPrefix(addr, "tcp://"):
+		return "tcp", strings.TrimPrefix(addr, "tcp://")
+	default:
+		return "tcp", addr
+	}
+}
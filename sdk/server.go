@@ -0,0 +1,322 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	pb "quanta/api/proto/v1"
+	"quanta/internal/logging"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ProtocolVersion = &pb.PluginVersion{Major: 2, Minor: 0, Patch: 0}
+
+// ErrNoHandler is reported for a server with neither a HandlerFunc nor a
+// batch handler.
+var ErrNoHandler = errors.New("sdk: no handler; pass a HandlerFunc or WithBatchHandler")
+
+type Server struct {
+	pb.UnimplementedTransformServiceServer
+
+	h      HandlerFunc
+	opts   options
+	stages stageStore
+	// inFlight counts handler calls still running, including ones whose
+	// caller gave up; Health reports it.
+	inFlight atomic.Int64
+}
+
+// NewServer panics with ErrNoHandler if h is nil and no batch handler is
+// set; CheckHandler reports the same as an error.
+func NewServer(h HandlerFunc, opts ...Option) *Server {
+	s, err := newServer(h, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// CheckHandler returns ErrNoHandler if NewServer(h, opts...) would panic.
+func CheckHandler(h HandlerFunc, opts ...Option) error {
+	_, err := newServer(h, opts...)
+	return err
+}
+
+func newServer(h HandlerFunc, opts ...Option) (*Server, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if h == nil && o.batch == nil {
+		return nil, ErrNoHandler
+	}
+	if o.maxBatch <= 0 {
+		o.maxBatch = o.window
+	}
+	return &Server{h: h, opts: o}, nil
+}
+
+func (s *Server) Metadata(context.Context, *pb.MetadataRequest) (*pb.MetadataResponse, error) {
+	caps := make(map[string]string, len(s.opts.capabilities)+3)
+	for k, v := range s.opts.capabilities {
+		caps[k] = v
+	}
+	caps["stream"] = "true"
+	caps["batch"] = strconv.FormatBool(s.opts.batch != nil)
+	caps["window"] = strconv.Itoa(s.opts.window)
+	return &pb.MetadataResponse{
+		Name:            s.opts.name,
+		Version:         s.opts.version,
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    caps,
+	}, nil
+}
+
+func (s *Server) Health(ctx context.Context, _ *pb.HealthRequest) (*pb.HealthResponse, error) {
+	n := s.inFlight.Load()
+	if s.opts.health != nil {
+		if err := s.opts.health(ctx); err != nil {
+			return &pb.HealthResponse{Ok: false, Details: err.Error(), InFlight: &n}, nil
+		}
+	}
+	return &pb.HealthResponse{Ok: true, Details: "OK", InFlight: &n}, nil
+}
+
+func (s *Server) Transform(ctx context.Context, req *pb.TransformRequest) (*pb.TransformResponse, error) {
+	ctx = withTraceContext(ctx)
+	done := make(chan *pb.TransformResponse, 1)
+	s.inFlight.Add(1)
+	go func() {
+		defer s.inFlight.Add(-1)
+		done <- s.handle(ctx, req)
+	}()
+	select {
+	case resp := <-done:
+		return resp, nil
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (s *Server) handle(ctx context.Context, req *pb.TransformRequest) (resp *pb.TransformResponse) {
+	if s.h == nil {
+		return s.handleBatch(ctx, []*pb.TransformRequest{req})[0]
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			resp = panicResponse(p)
+		}
+	}()
+	evs, st, err := s.h(s.stages.context(ctx, req), eventFromRequest(req))
+	return toResponse(evs, st, err)
+}
+
+func (s *Server) handleBatch(ctx context.Context, reqs []*pb.TransformRequest) (resps []*pb.TransformResponse) {
+	if s.opts.batch == nil {
+		resps = make([]*pb.TransformResponse, len(reqs))
+		for i, req := range reqs {
+			resps[i] = s.handle(ctx, req)
+		}
+		return resps
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			resp := panicResponse(p)
+			resps = make([]*pb.TransformResponse, len(reqs))
+			for i := range resps {
+				resps[i] = resp
+			}
+		}
+	}()
+	evs := make([]Event, len(reqs))
+	for i, req := range reqs {
+		evs[i] = eventFromRequest(req)
+	}
+	results := s.opts.batch(s.stages.context(ctx, reqs[0]), evs)
+	resps = make([]*pb.TransformResponse, len(reqs))
+	for i := range reqs {
+		if i >= len(results) {
+			resps[i] = toResponse(nil, StatusError, fmt.Errorf("batch handler returned %d results for %d events", len(results), len(reqs)))
+			continue
+		}
+		resps[i] = toResponse(results[i].Events, results[i].Status, results[i].Err)
+	}
+	return resps
+}
+
+func panicResponse(p any) *pb.TransformResponse {
+	logging.L().With("component", "sdk").Error("handler panic", "panic", p, "stack", string(debug.Stack()))
+	return &pb.TransformResponse{Status: pb.Status_ERROR, ErrorMessage: fmt.Sprintf("panic: %v", p)}
+}
+
+type streamJob struct {
+	req  *pb.TransformRequest
+	slot chan *pb.TransformStreamMessage
+}
+
+func control(t pb.ControlMessage_Type, credits int32) *pb.TransformStreamMessage {
+	return &pb.TransformStreamMessage{Msg: &pb.TransformStreamMessage_Control{Control: &pb.ControlMessage{Type: t, Credits: credits}}}
+}
+
+func filled(msg *pb.TransformStreamMessage) chan *pb.TransformStreamMessage {
+	slot := make(chan *pb.TransformStreamMessage, 1)
+	slot <- msg
+	return slot
+}
+
+func (s *Server) TransformStream(stream pb.TransformService_TransformStreamServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	window := s.opts.window
+	if err := stream.Send(control(pb.ControlMessage_GRANT, int32(window))); err != nil {
+		return err
+	}
+
+	var outstanding atomic.Int64
+	out := make(chan chan *pb.TransformStreamMessage, 2*window+16)
+	jobs := make(chan streamJob, window)
+
+	writerDone := make(chan error, 1)
+	go func() {
+		for slot := range out {
+			var msg *pb.TransformStreamMessage
+			select {
+			case msg = <-slot:
+			case <-ctx.Done():
+				writerDone <- ctx.Err()
+				return
+			}
+			if msg.GetResponse() != nil {
+				outstanding.Add(-1)
+			}
+			if err := stream.Send(msg); err != nil {
+				writerDone <- err
+				cancel()
+				return
+			}
+		}
+		writerDone <- nil
+	}()
+
+	var wg sync.WaitGroup
+	if s.opts.batch != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runBatches(ctx, jobs)
+		}()
+	} else {
+		for i := 0; i < window; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					s.inFlight.Add(1)
+					resp := s.handle(ctx, j.req)
+					s.inFlight.Add(-1)
+					j.slot <- responseMsg(resp)
+				}
+			}()
+		}
+	}
+
+	finish := func(err error) error {
+		close(jobs)
+		close(out)
+		if err != nil {
+			cancel()
+		}
+		werr := <-writerDone
+		wg.Wait()
+		if err != nil {
+			return err
+		}
+		return werr
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return finish(nil)
+		}
+		if err != nil {
+			return finish(err)
+		}
+		switch m := msg.Msg.(type) {
+		case *pb.TransformStreamMessage_Request:
+			if outstanding.Add(1) > int64(window) {
+				return finish(status.Errorf(codes.ResourceExhausted, "credit window of %d exceeded", window))
+			}
+			slot := make(chan *pb.TransformStreamMessage, 1)
+			if !enqueue(ctx, out, slot) {
+				return finish(ctx.Err())
+			}
+			jobs <- streamJob{req: m.Request, slot: slot}
+		case *pb.TransformStreamMessage_Control:
+			switch m.Control.GetType() {
+			case pb.ControlMessage_PING:
+				if !enqueue(ctx, out, filled(control(pb.ControlMessage_PONG, 0))) {
+					return finish(ctx.Err())
+				}
+			case pb.ControlMessage_FLUSH:
+				if !enqueue(ctx, out, filled(control(pb.ControlMessage_FLUSH, 0))) {
+					return finish(ctx.Err())
+				}
+			case pb.ControlMessage_STOP:
+				return finish(nil)
+			}
+		default:
+			return finish(status.Error(codes.InvalidArgument, "plugin streams accept only requests and control messages"))
+		}
+	}
+}
+
+func enqueue(ctx context.Context, out chan<- chan *pb.TransformStreamMessage, slot chan *pb.TransformStreamMessage) bool {
+	select {
+	case out <- slot:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Server) runBatches(ctx context.Context, jobs <-chan streamJob) {
+	for j := range jobs {
+		batch := []streamJob{j}
+	drain:
+		for len(batch) < s.opts.maxBatch {
+			select {
+			case next, ok := <-jobs:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+		reqs := make([]*pb.TransformRequest, len(batch))
+		for i, b := range batch {
+			reqs[i] = b.req
+		}
+		s.inFlight.Add(int64(len(reqs)))
+		resps := s.handleBatch(ctx, reqs)
+		s.inFlight.Add(-int64(len(reqs)))
+		for i, resp := range resps {
+			batch[i].slot <- responseMsg(resp)
+		}
+	}
+}
+
+func responseMsg(resp *pb.TransformResponse) *pb.TransformStreamMessage {
+	return &pb.TransformStreamMessage{Msg: &pb.TransformStreamMessage_Response{Response: resp}}
+}
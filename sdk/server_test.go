@@ -0,0 +1,284 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	pb "quanta/api/proto/v1"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func startServer(t *testing.T, h HandlerFunc, opts ...Option) pb.TransformServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	pb.RegisterTransformServiceServer(g, NewServer(h, opts...))
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+	return pb.NewTransformServiceClient(cc)
+}
+
+func echo(_ context.Context, ev Event) ([]Event, Status, error) {
+	return []Event{ev}, StatusOK, nil
+}
+
+func TestServer_UnaryEchoAndMetadata(t *testing.T) {
+	cli := startServer(t, echo, WithName("echo"), WithCapability("x", "y"))
+	ctx := context.Background()
+
+	md, err := cli.Metadata(ctx, &pb.MetadataRequest{})
+	if err != nil {
+		t.Fatalf("metadata: %v", err)
+	}
+	if md.Name != "echo" || md.GetProtocolVersion().GetMajor() != ProtocolVersion.Major || md.Capabilities["x"] != "y" {
+		t.Fatalf("unexpected metadata: %+v", md)
+	}
+
+	resp, err := cli.Transform(ctx, &pb.TransformRequest{Payload: []byte("hi"), Metadata: &pb.EventMetadata{Headers: map[string]string{"h": "v"}}})
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	if resp.Status != pb.Status_OK || len(resp.Events) != 1 || string(resp.Events[0].Value) != "hi" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.Events[0].GetMetadata().GetHeaders()["h"] != "v" {
+		t.Fatalf("headers not passed through: %+v", resp.Events[0].GetMetadata())
+	}
+}
+
+func TestServer_PanicAndErrorStatuses(t *testing.T) {
+	cli := startServer(t, func(_ context.Context, ev Event) ([]Event, Status, error) {
+		switch string(ev.Value) {
+		case "panic":
+			panic("boom")
+		case "retry":
+			return nil, StatusOK, RetryAfter(errors.New("busy"), 250*time.Millisecond)
+		case "fail":
+			return nil, StatusOK, errors.New("bad input")
+		}
+		return nil, StatusDrop, nil
+	})
+	ctx := context.Background()
+
+	cases := []struct {
+		in    string
+		want  pb.Status
+		retry int32
+	}{
+		{"panic", pb.Status_ERROR, 0},
+		{"retry", pb.Status_RETRY, 250},
+		{"fail", pb.Status_ERROR, 0},
+		{"other", pb.Status_DROP, 0},
+	}
+	for _, tc := range cases {
+		resp, err := cli.Transform(ctx, &pb.TransformRequest{Payload: []byte(tc.in)})
+		if err != nil {
+			t.Fatalf("%s: transform: %v", tc.in, err)
+		}
+		if resp.Status != tc.want || resp.RetryAfterMs != tc.retry {
+			t.Fatalf("%s: got status %v retry %d", tc.in, resp.Status, resp.RetryAfterMs)
+		}
+	}
+}
+
+func TestServer_UnaryHonorsDeadline(t *testing.T) {
+	cli := startServer(t, func(context.Context, Event) ([]Event, Status, error) {
+		time.Sleep(time.Second)
+		return nil, StatusOK, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := cli.Transform(ctx, &pb.TransformRequest{})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("want DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestServer_StreamCreditsAndOrder(t *testing.T) {
+	cli := startServer(t, func(_ context.Context, ev Event) ([]Event, Status, error) {
+		if string(ev.Value) == "slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return []Event{ev}, StatusOK, nil
+	}, WithWindow(2))
+
+	st, err := cli.TransformStream(context.Background())
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	first, err := st.Recv()
+	if err != nil {
+		t.Fatalf("recv grant: %v", err)
+	}
+	if c := first.GetControl(); c.GetType() != pb.ControlMessage_GRANT || c.GetCredits() != 2 {
+		t.Fatalf("want GRANT 2, got %+v", first)
+	}
+
+	send := func(v string) {
+		if err := st.Send(&pb.TransformStreamMessage{Msg: &pb.TransformStreamMessage_Request{Request: &pb.TransformRequest{Payload: []byte(v)}}}); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	send("slow")
+	send("fast")
+	var got []string
+	for i := 0; i < 2; i++ {
+		m, err := st.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		got = append(got, string(m.GetResponse().GetEvents()[0].GetValue()))
+	}
+	if strings.Join(got, ",") != "slow,fast" {
+		t.Fatalf("responses out of order: %v", got)
+	}
+
+	if err := st.Send(control(pb.ControlMessage_PING, 0)); err != nil {
+		t.Fatalf("send ping: %v", err)
+	}
+	if m, err := st.Recv(); err != nil || m.GetControl().GetType() != pb.ControlMessage_PONG {
+		t.Fatalf("want PONG, got %+v (%v)", m, err)
+	}
+
+	send("a")
+	send("b")
+	send("c")
+	for {
+		_, err := st.Recv()
+		if err == nil {
+			continue
+		}
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("want ResourceExhausted after exceeding window, got %v", err)
+		}
+		break
+	}
+}
+
+func TestServer_BatchHandler(t *testing.T) {
+	var sizes []int
+	cli := startServer(t, nil, WithBatchHandler(func(_ context.Context, evs []Event) []BatchResult {
+		sizes = append(sizes, len(evs))
+		out := make([]BatchResult, len(evs))
+		for i, ev := range evs {
+			out[i] = BatchResult{Events: []Event{ev}, Status: StatusOK}
+		}
+		return out
+	}, 8))
+
+	resp, err := cli.Transform(context.Background(), &pb.TransformRequest{Payload: []byte("one")})
+	if err != nil || len(resp.Events) != 1 {
+		t.Fatalf("unary via batch handler: %+v %v", resp, err)
+	}
+	if len(sizes) != 1 || sizes[0] != 1 {
+		t.Fatalf("unexpected batch sizes %v", sizes)
+	}
+}
+
+func TestNewServer_RejectsMissingHandler(t *testing.T) {
+	if err := CheckHandler(nil); !errors.Is(err, ErrNoHandler) {
+		t.Fatalf("want ErrNoHandler, got %v", err)
+	}
+	if err := ServeContext(context.Background(), nil); !errors.Is(err, ErrNoHandler) {
+		t.Fatalf("Serve without a handler: want ErrNoHandler, got %v", err)
+	}
+	defer func() {
+		if p := recover(); p != ErrNoHandler {
+			t.Fatalf("want a panic with ErrNoHandler, got %v", p)
+		}
+	}()
+	NewServer(nil)
+}
+
+func TestHandshake_RoundTrip(t *testing.T) {
+	hs := Handshake{Version: HandshakeVersion, Network: "unix", Address: "/tmp/p.sock"}
+	got, err := ParseHandshake(hs.String() + "\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got != hs || got.Target() != "unix:///tmp/p.sock" {
+		t.Fatalf("unexpected handshake %+v", got)
+	}
+	if _, err := ParseHandshake("hello"); err == nil {
+		t.Fatal("expected error for malformed handshake")
+	}
+}
+
+func TestServer_ConfigurePerStage(t *testing.T) {
+	cli := startServer(t, func(ctx context.Context, ev Event) ([]Event, Status, error) {
+		st, _ := StageFrom(ctx)
+		var cfg struct {
+			Prefix string `json:"prefix"`
+		}
+		if err := st.Decode(&cfg); err != nil {
+			return nil, StatusError, err
+		}
+		ev.Value = append([]byte(cfg.Prefix), ev.Value...)
+		return []Event{ev}, StatusOK, nil
+	}, WithConfigure(func(_ context.Context, st Stage) error {
+		if _, ok := st.Config["prefix"]; !ok {
+			return errors.New("prefix is required")
+		}
+		return nil
+	}))
+	ctx := context.Background()
+
+	bad, err := cli.Configure(ctx, &pb.ConfigureRequest{PipelineId: "p", PluginId: "s"})
+	if err != nil || bad.Ok || !strings.Contains(bad.ErrorMessage, "prefix") {
+		t.Fatalf("expected rejected config, got %+v %v", bad, err)
+	}
+	for pipeline, prefix := range map[string]string{"a": "A:", "b": "B:"} {
+		cfg, _ := structpb.NewStruct(map[string]any{"prefix": prefix})
+		resp, err := cli.Configure(ctx, &pb.ConfigureRequest{PipelineId: pipeline, PluginId: "s", Config: cfg})
+		if err != nil || !resp.Ok {
+			t.Fatalf("configure %s: %+v %v", pipeline, resp, err)
+		}
+	}
+	for pipeline, want := range map[string]string{"a": "A:x", "b": "B:x"} {
+		resp, err := cli.Transform(ctx, &pb.TransformRequest{PipelineId: pipeline, PluginId: "s", Payload: []byte("x")})
+		if err != nil {
+			t.Fatalf("transform: %v", err)
+		}
+		if got := string(resp.Events[0].Value); got != want {
+			t.Fatalf("pipeline %s: want %q, got %q", pipeline, want, got)
+		}
+	}
+}
+
+func TestServer_TransformJoinsEngineTrace(t *testing.T) {
+	got := make(chan string, 1)
+	cli := startServer(t, func(ctx context.Context, ev Event) ([]Event, Status, error) {
+		got <- trace.SpanContextFromContext(ctx).TraceID().String()
+		return []Event{ev}, StatusOK, nil
+	})
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err := cli.Transform(ctx, &pb.TransformRequest{Payload: []byte("hi")}); err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	if id := <-got; id != traceID {
+		t.Fatalf("handler ctx trace = %s, want %s", id, traceID)
+	}
+}
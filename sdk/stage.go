@@ -0,0 +1,67 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	pb "quanta/api/proto/v1"
+)
+
+type Stage struct {
+	PipelineID string
+	Name       string
+	Config     map[string]any
+}
+
+func (s Stage) Decode(v any) error {
+	raw, err := json.Marshal(s.Config)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+type stageKey struct{ pipeline, name string }
+
+type stageCtxKey struct{}
+
+func StageFrom(ctx context.Context) (Stage, bool) {
+	st, ok := ctx.Value(stageCtxKey{}).(Stage)
+	return st, ok
+}
+
+type stageStore struct {
+	mu     sync.RWMutex
+	stages map[stageKey]Stage
+}
+
+func (s *stageStore) put(st Stage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stages == nil {
+		s.stages = map[stageKey]Stage{}
+	}
+	s.stages[stageKey{st.PipelineID, st.Name}] = st
+}
+
+func (s *stageStore) context(ctx context.Context, req *pb.TransformRequest) context.Context {
+	s.mu.RLock()
+	st, ok := s.stages[stageKey{req.GetPipelineId(), req.GetPluginId()}]
+	s.mu.RUnlock()
+	if !ok {
+		st = Stage{PipelineID: req.GetPipelineId(), Name: req.GetPluginId()}
+	}
+	return context.WithValue(ctx, stageCtxKey{}, st)
+}
+
+func (s *Server) Configure(ctx context.Context, req *pb.ConfigureRequest) (*pb.ConfigureResponse, error) {
+	st := Stage{PipelineID: req.GetPipelineId(), Name: req.GetPluginId(), Config: req.GetConfig().AsMap()}
+	if s.opts.configure != nil {
+		if err := s.opts.configure(ctx, st); err != nil {
+			return &pb.ConfigureResponse{Ok: false, ErrorMessage: err.Error()}, nil
+		}
+	}
+	s.stages.put(st)
+	return &pb.ConfigureResponse{Ok: true}, nil
+}
@@ -2,6 +2,8 @@ package sink
 
 import (
 	"fmt"
+	"sync"
+
 	pb "quanta/api/proto/v1"
 )
 
@@ -17,14 +19,37 @@ type AckAware interface {
 	BindAck(EmitFn)
 }
 
+// Flusher is implemented by sinks that buffer writes or acks; Flush pushes
+// everything buffered out before a drain completes.
+type Flusher interface {
+	Flush() error
+}
+
 type factory = func() Adapter
 
-var reg = map[string]factory{}
+var (
+	mu  sync.RWMutex
+	reg = map[string]factory{}
+)
 
-func Register(name string, f factory) { reg[name] = f }
+func Register(name string, f factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	reg[name] = f
+}
+
+// Unregister removes a sink type registered with Register.
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(reg, name)
+}
 
 func NewAdapter(name string) (Adapter, error) {
-	if f, ok := reg[name]; ok {
+	mu.RLock()
+	f, ok := reg[name]
+	mu.RUnlock()
+	if ok {
 		return f(), nil
 	}
 	return nil, fmt.Errorf("unknown sink %q", name)
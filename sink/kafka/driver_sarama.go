@@ -33,11 +33,19 @@ func (d *driver) Configure(c any) error {
 }
 
 func (d *driver) Push(f *pb.Frame) error {
-	d.p.Input() <- &sarama.ProducerMessage{
-		Topic: d.cfg.Topic,
+	topic := d.cfg.Topic
+	if t := f.GetRoute().GetTopic(); t != "" {
+		topic = t
+	}
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
 		Key:   sarama.ByteEncoder(f.Key),
 		Value: sarama.ByteEncoder(f.Value),
 	}
+	for k, v := range f.Headers {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(k), Value: v})
+	}
+	d.p.Input() <- msg
 	return nil
 }
 
@@ -97,6 +97,11 @@ func (d *driver) Close() error {
 
 func (d *driver) BindAck(fn sink.EmitFn) { d.ack = fn }
 
+func (d *driver) Flush() error {
+	d.timerFlush()
+	return nil
+}
+
 func (d *driver) timerFlush() {
 	d.mu.Lock()
 	d.flushLocked()
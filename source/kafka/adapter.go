@@ -2,13 +2,59 @@ package kafka
 
 import (
 	"context"
-	pb "quanta/api/proto/v1"
+	"quanta/source"
+
+	"github.com/rcrowley/go-metrics"
 )
 
-type EmitFunc func(*pb.Frame) error
+type EmitFunc = source.EmitFunc
 
 type Adapter interface {
 	Configure(Config) error
 	Run(context.Context, EmitFunc) error
 	Close() error
 }
+
+// Pausable is implemented by adapters that can stop fetching without leaving
+// their consumer group.
+type Pausable = source.Pausable
+
+type Stats struct {
+	Lag      int64
+	InFlight int64
+
+	// BackpressureAvailable is the number of fetch tokens left and
+	// CheckpointPending the records tracked but not yet resolved.
+	BackpressureAvailable int64
+	CheckpointPending     int64
+	Partitions            []PartitionStats
+}
+
+// PartitionStats describes one claimed partition. Committed is the next
+// offset marked for commit, -1 before anything was marked.
+type PartitionStats struct {
+	Topic     string
+	Partition int32
+	Lag       int64
+	Committed int64
+}
+
+// StatsReporter is implemented by adapters that can report consumer lag and
+// records awaiting an ack.
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// Committer is implemented by adapters that can flush marked offsets on
+// demand, used for the final commit during a drain.
+type Committer = source.Committer
+
+// ReadyReporter is implemented by adapters that can tell whether they are
+// connected and have partitions to consume; Ready returns why not.
+type ReadyReporter = source.ReadyReporter
+
+// MetricsReporter is implemented by adapters whose client library keeps a
+// go-metrics registry, so it can be exported with the engine's metrics.
+type MetricsReporter interface {
+	Metrics() metrics.Registry
+}
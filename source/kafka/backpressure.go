@@ -67,6 +67,13 @@ func (c *Controller) TryAcquire(n int64) bool {
 	return true
 }
 
+// Available returns the number of tokens that can be acquired right now.
+func (c *Controller) Available() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens
+}
+
 func (c *Controller) Close() {
 	c.mu.Lock()
 	c.closed = true
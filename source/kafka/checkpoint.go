@@ -98,10 +98,14 @@ func (c *Capped[T]) Track(ctx context.Context, p T, batch int64) (func() *T, err
 }
 
 func (c *Capped[T]) Pending() int64 {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
 	return c.u.Pending()
 }
 
 func (c *Capped[T]) Highest() *T {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
 	return c.u.Highest()
 }
 
@@ -133,3 +137,5 @@ func (m *Manager[T]) Track(ctx context.Context, payload T) (resolveFn func() (hi
 		return highest, false
 	}, nil
 }
+
+func (m *Manager[T]) Pending() int64 { return m.capped.Pending() }
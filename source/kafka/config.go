@@ -4,8 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"time"
 
+	"quanta/internal/secrets"
+
+	"github.com/knadh/koanf/maps"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/file"
@@ -43,6 +49,9 @@ type Config struct {
 	Checkpoint   CheckpointCfg   `koanf:"checkpoint"`
 }
 
+// LoadConfig reads the source config at path, tolerating a missing file,
+// and overlays QUANTA_KAFKA__ variables. ${ENV:NAME} and ${FILE:path}
+// references are resolved last, so sasl_pass can come from a mounted secret.
 func LoadConfig(path string) (Config, error) {
 	k := koanf.New(".")
 	if path != "" {
@@ -58,7 +67,35 @@ func LoadConfig(path string) (Config, error) {
 	}
 
 	_ = k.Load(env.Provider("QUANTA_KAFKA__", "__", nil), nil)
+	if err := secrets.ExpandKoanf(k, filepath.Dir(path)); err != nil {
+		return Config{}, err
+	}
+	return unmarshal(k)
+}
+
+// ConfigFromMap builds a config from a v2 pipeline spec's inline source
+// config, whose references the spec loader has already resolved, and
+// overlays QUANTA_KAFKA__ variables. Unknown keys are rejected.
+func ConfigFromMap(m map[string]any) (Config, error) {
+	k := koanf.New(".")
+	if err := k.Load(mapProvider(m), nil); err != nil {
+		return Config{}, err
+	}
+	known := configKeys(reflect.TypeOf(Config{}), "")
+	var unknown []string
+	for _, key := range k.Keys() {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		return Config{}, fmt.Errorf("unknown kafka config keys: %s", strings.Join(unknown, ", "))
+	}
+	_ = k.Load(env.Provider("QUANTA_KAFKA__", "__", nil), nil)
+	return unmarshal(k)
+}
 
+func unmarshal(k *koanf.Koanf) (Config, error) {
 	var cfg Config
 	if err := k.Unmarshal("", &cfg); err != nil {
 		return cfg, err
@@ -70,6 +107,35 @@ func LoadConfig(path string) (Config, error) {
 	return cfg, nil
 }
 
+// configKeys lists the dotted koanf keys of a config struct.
+func configKeys(t reflect.Type, prefix string) map[string]bool {
+	keys := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := prefix + f.Tag.Get("koanf")
+		if f.Type.Kind() == reflect.Struct {
+			for k := range configKeys(f.Type, key+".") {
+				keys[k] = true
+			}
+			continue
+		}
+		keys[key] = true
+	}
+	return keys
+}
+
+// mapProvider feeds an already decoded map to koanf.
+type mapProvider map[string]any
+
+func (m mapProvider) ReadBytes() ([]byte, error) {
+	return nil, errors.New("kafka: map provider does not support ReadBytes")
+}
+
+func (m mapProvider) Read() (map[string]any, error) {
+	// Copy, so later overlays cannot write into the spec.
+	return maps.Unflatten(maps.Copy(m), "."), nil
+}
+
 func applyDefaults(c *Config) {
 	if c.BackPressure.Capacity == 0 {
 		c.BackPressure.Capacity = 30_000
@@ -2,12 +2,15 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 
 	pb "quanta/api/proto/v1"
 	"quanta/internal/logging"
 
 	"github.com/IBM/sarama"
+	"github.com/rcrowley/go-metrics"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -27,19 +30,58 @@ type SaramaDriver struct {
 
 	mu      sync.Mutex
 	pending map[recordID]func()
+	claims  map[partitionKey]*claimProgress
 
-	ackCh chan recordID
+	ackCh  chan recordID
+	paused atomic.Bool
+	wake   chan struct{}
+	sess   sarama.ConsumerGroupSession
+
+	metrics metrics.Registry
 }
 
-func (d *SaramaDriver) Configure(config Config) error {
-	d.cfg, d.mode = config, config.CommitMode
-	d.pending = make(map[recordID]func())
+type partitionKey struct {
+	topic     string
+	partition int32
+}
 
-	d.bp = NewController(config.BackPressure.Capacity, config.BackPressure.Capacity/10, config.BackPressure.CheckInt)
-	d.cp = NewManager[struct{}](config.BackPressure.Capacity, config.Checkpoint.CommitInt)
+// claimProgress tracks one claimed partition. offsets holds the records
+// emitted in e2e mode in offset order, so acks arriving out of order only
+// move the mark once every earlier record is acked.
+type claimProgress struct {
+	claim  sarama.ConsumerGroupClaim
+	next   atomic.Int64
+	marked atomic.Int64
 
-	d.ackCh = make(chan recordID, int(config.BackPressure.Capacity))
+	mu      sync.Mutex
+	offsets *Uncapped[int64]
+}
+
+func newClaimProgress(claim sarama.ConsumerGroupClaim) *claimProgress {
+	return &claimProgress{claim: claim, offsets: NewUncapped[int64]()}
+}
+
+// markOffset marks the partition as consumed up to and including offset.
+func (p *claimProgress) markOffset(sess sarama.ConsumerGroupSession, offset int64) {
+	sess.MarkOffset(p.claim.Topic(), p.claim.Partition(), offset+1, "")
+	p.marked.Store(offset + 1)
+}
+
+// track records an emitted offset and returns the function that acks it,
+// which returns the highest offset acked with every one before it, or nil
+// while the first record is unacked.
+func (p *claimProgress) track(offset int64) func() *int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	resolve := p.offsets.Track(offset, 1)
+	return func() *int64 {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return resolve()
+	}
+}
 
+func (d *SaramaDriver) Configure(config Config) error {
 	ver, err := sarama.ParseKafkaVersion(config.Version)
 	if err != nil {
 		return err
@@ -61,11 +103,33 @@ func (d *SaramaDriver) Configure(config Config) error {
 		sc.Consumer.Offsets.Initial = sarama.OffsetNewest
 	}
 
-	if d.cl, err = sarama.NewClient(config.Brokers, sc); err != nil {
+	cl, err := sarama.NewClient(config.Brokers, sc)
+	if err != nil {
 		return err
 	}
-	d.group, err = sarama.NewConsumerGroupFromClient(config.GroupID, d.cl)
-	return err
+	group, err := sarama.NewConsumerGroupFromClient(config.GroupID, cl)
+	if err != nil {
+		_ = cl.Close()
+		return err
+	}
+	d.init(config, group)
+	d.cl, d.metrics = cl, sc.MetricRegistry
+	return nil
+}
+
+// init prepares the driver to consume through group. Configure passes a
+// Sarama consumer group; tests pass an in-process one.
+func (d *SaramaDriver) init(config Config, group sarama.ConsumerGroup) {
+	d.cfg, d.mode = config, config.CommitMode
+	d.group = group
+	d.pending = make(map[recordID]func())
+	d.claims = make(map[partitionKey]*claimProgress)
+	d.wake = make(chan struct{})
+
+	d.bp = NewController(config.BackPressure.Capacity, config.BackPressure.Capacity/10, config.BackPressure.CheckInt)
+	d.cp = NewManager[struct{}](config.BackPressure.Capacity, config.Checkpoint.CommitInt)
+
+	d.ackCh = make(chan recordID, int(config.BackPressure.Capacity))
 }
 
 func (d *SaramaDriver) Run(ctx context.Context, emit EmitFunc) error {
@@ -81,10 +145,96 @@ func (d *SaramaDriver) Run(ctx context.Context, emit EmitFunc) error {
 	}
 }
 
+// Pause stops fetching on every claimed partition and stops emitting records
+// already buffered; acks keep being processed. Partitions claimed after a
+// rebalance start paused too.
+func (d *SaramaDriver) Pause() {
+	d.paused.Store(true)
+	d.group.PauseAll()
+}
+
+func (d *SaramaDriver) Resume() {
+	d.paused.Store(false)
+	d.group.ResumeAll()
+	d.mu.Lock()
+	close(d.wake)
+	d.wake = make(chan struct{})
+	d.mu.Unlock()
+}
+
+func (d *SaramaDriver) resumed() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.wake
+}
+
+// Commit synchronously commits the offsets marked so far in the current
+// session.
+func (d *SaramaDriver) Commit() {
+	d.mu.Lock()
+	sess := d.sess
+	d.mu.Unlock()
+	if sess != nil {
+		sess.Commit()
+	}
+}
+
+func (d *SaramaDriver) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := Stats{
+		InFlight:              int64(len(d.pending)),
+		BackpressureAvailable: d.bp.Available(),
+		CheckpointPending:     d.cp.Pending(),
+	}
+	for key, p := range d.claims {
+		next := p.next.Load()
+		if next == 0 {
+			next = p.claim.InitialOffset()
+		}
+		lag := max(p.claim.HighWaterMarkOffset()-next, 0)
+		st.Lag += lag
+		committed := p.marked.Load()
+		if committed == 0 {
+			committed = -1
+		}
+		st.Partitions = append(st.Partitions, PartitionStats{
+			Topic: key.topic, Partition: key.partition, Lag: lag, Committed: committed,
+		})
+	}
+	return st
+}
+
+// Metrics returns the Sarama client's go-metrics registry.
+func (d *SaramaDriver) Metrics() metrics.Registry { return d.metrics }
+
+// Ready reports whether the driver has joined its consumer group and been
+// assigned at least one partition.
+func (d *SaramaDriver) Ready() error {
+	d.mu.Lock()
+	sess := d.sess
+	d.mu.Unlock()
+	if sess == nil {
+		return errors.New("sarama-driver: no consumer group session")
+	}
+	for _, parts := range sess.Claims() {
+		if len(parts) > 0 {
+			return nil
+		}
+	}
+	return errors.New("sarama-driver: no partitions assigned")
+}
+
 func (d *SaramaDriver) Close() error {
-	_ = d.group.Close()
-	_ = d.cl.Close()
-	d.bp.Close()
+	if d.group != nil {
+		_ = d.group.Close()
+	}
+	if d.cl != nil {
+		_ = d.cl.Close()
+	}
+	if d.bp != nil {
+		d.bp.Close()
+	}
 	return nil
 }
 
@@ -93,17 +243,30 @@ type groupHandler struct {
 	emit   EmitFunc
 }
 
-func (*groupHandler) Setup(sarama.ConsumerGroupSession) error {
+func (h *groupHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	h.driver.mu.Lock()
+	h.driver.sess = sess
+	h.driver.mu.Unlock()
 	return nil
 }
 
+// Cleanup runs once every ConsumeClaim of the session has returned. Records
+// still awaiting an ack belong to partitions that may now be someone else's,
+// so their acks are ignored and their backpressure tokens and checkpoint
+// capacity are returned; the records are consumed again from the last
+// commit.
 func (h *groupHandler) Cleanup(_ sarama.ConsumerGroupSession) error {
-	h.driver.mu.Lock()
-	defer h.driver.mu.Unlock()
+	d := h.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sess = nil
 
-	dropped := len(h.driver.pending)
-
-	h.driver.pending = make(map[recordID]func())
+	dropped := len(d.pending)
+	d.pending = make(map[recordID]func())
+	if dropped > 0 {
+		d.bp.Release(int64(dropped))
+	}
+	d.cp = NewManager[struct{}](d.cfg.BackPressure.Capacity, d.cfg.Checkpoint.CommitInt)
 
 	if dropped > 0 {
 		logging.L().Info("sarama-driver: rebalance – cleared pending callbacks", "count", dropped)
@@ -115,53 +278,58 @@ func (h *groupHandler) ConsumeClaim(
 	sess sarama.ConsumerGroupSession,
 	claim sarama.ConsumerGroupClaim,
 ) error {
+	key := partitionKey{claim.Topic(), claim.Partition()}
+	progress := newClaimProgress(claim)
+	h.driver.mu.Lock()
+	h.driver.claims[key] = progress
+	h.driver.mu.Unlock()
+	defer func() {
+		h.driver.mu.Lock()
+		delete(h.driver.claims, key)
+		h.driver.mu.Unlock()
+	}()
+	if h.driver.paused.Load() {
+		h.driver.group.Pause(map[string][]int32{key.topic: {key.partition}})
+	}
+
 	for {
 
-		if !h.driver.bp.TryAcquire(1) {
+		// Wait for acks while the window is full: no backpressure token, or
+		// as many records tracked since the oldest unacked one as the
+		// checkpoint manager holds, where Track would block this loop and
+		// with it the acks that would unblock it.
+		if h.driver.cp.Pending() >= h.driver.cfg.BackPressure.Capacity || !h.driver.bp.TryAcquire(1) {
 
 			select {
 			case rec := <-h.driver.ackCh:
-
-				h.driver.mu.Lock()
-				cb, ok := h.driver.pending[rec]
-				if ok {
-					delete(h.driver.pending, rec)
-				}
-				h.driver.mu.Unlock()
-				if ok {
-					cb()
-					h.driver.bp.Release(1)
-					logging.L().Info("kafka ack released", "topic", rec.topic, "partition", rec.partition, "offset", rec.offset)
-				}
+				h.driver.handleAck(rec)
 				continue
 			case <-sess.Context().Done():
 				return sess.Context().Err()
 			}
 		}
 
+		msgs := claim.Messages()
+		if h.driver.paused.Load() {
+			msgs = nil
+		}
+
 		select {
 		case <-sess.Context().Done():
 
 			h.driver.bp.Release(1)
 			return sess.Context().Err()
 
-		case rec := <-h.driver.ackCh:
-
-			h.driver.mu.Lock()
-			cb, ok := h.driver.pending[rec]
-			if ok {
-				delete(h.driver.pending, rec)
-			}
-			h.driver.mu.Unlock()
-			if ok {
-				cb()
-				h.driver.bp.Release(1)
-				logging.L().Info("kafka ack released", "topic", rec.topic, "partition", rec.partition, "offset", rec.offset)
-			}
+		case <-h.driver.resumed():
+			h.driver.bp.Release(1)
+			continue
 
+		case rec := <-h.driver.ackCh:
+			h.driver.bp.Release(1)
+			h.driver.handleAck(rec)
 			continue
 
-		case msg, ok := <-claim.Messages():
+		case msg, ok := <-msgs:
 			if !ok {
 
 				h.driver.bp.Release(1)
@@ -181,38 +349,65 @@ func (h *groupHandler) ConsumeClaim(
 				},
 			}
 			frame := &pb.Frame{Key: msg.Key, Value: msg.Value, Headers: toHeaderMap(msg.Headers), Ts: timestamppb.New(msg.Timestamp), Checkpoint: token}
-			if err := h.emit(frame); err != nil {
 
-				h.driver.bp.Release(1)
+			rec := recordID{msg.Topic, msg.Partition, msg.Offset}
+			if h.driver.mode != CommitAuto {
+				// Registered before emitting: a sink may ack while emit is
+				// still running, and another claim's loop may handle it.
+				acked := progress.track(msg.Offset)
+				h.driver.mu.Lock()
+				h.driver.pending[rec] = func() {
+					_, due := resolve()
+					if upTo := acked(); upTo != nil {
+						progress.markOffset(sess, *upTo)
+					}
+					if due {
+						sess.Commit()
+					}
+				}
+				h.driver.mu.Unlock()
+			}
+			if err := h.emit(frame); err != nil {
+				h.driver.mu.Lock()
+				_, held := h.driver.pending[rec]
+				delete(h.driver.pending, rec)
+				h.driver.mu.Unlock()
+				if held || h.driver.mode == CommitAuto {
+					h.driver.bp.Release(1)
+				}
 				return err
 			}
 
-			rec := recordID{msg.Topic, msg.Partition, msg.Offset}
+			progress.next.Store(msg.Offset + 1)
 			if h.driver.mode == CommitAuto {
 
 				_, due := resolve()
-				sess.MarkMessage(msg, "")
+				progress.markOffset(sess, msg.Offset)
 				if due {
 					sess.Commit()
 				}
 
 				h.driver.bp.Release(1)
-			} else {
-
-				h.driver.mu.Lock()
-				h.driver.pending[rec] = func() {
-					_, due := resolve()
-					sess.MarkMessage(msg, "")
-					if due {
-						sess.Commit()
-					}
-				}
-				h.driver.mu.Unlock()
 			}
 		}
 	}
 }
 
+func (d *SaramaDriver) handleAck(rec recordID) {
+	d.mu.Lock()
+	cb, ok := d.pending[rec]
+	if ok {
+		delete(d.pending, rec)
+	}
+	d.mu.Unlock()
+	if !ok {
+		return
+	}
+	cb()
+	d.bp.Release(1)
+	logging.L().Info("kafka ack released", "topic", rec.topic, "partition", rec.partition, "offset", rec.offset)
+}
+
 func (d *SaramaDriver) OnAck(ack *pb.ConnectorAck) {
 	if ack == nil || ack.Checkpoint == nil {
 		return
@@ -1,8 +1,11 @@
 package kafka
 
 import (
+	"context"
+	"errors"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	pb "quanta/api/proto/v1"
 )
@@ -54,3 +57,320 @@ func TestSaramaDriver_AckCallbackProcessed(t *testing.T) {
 		t.Fatal("callback was not executed exactly once")
 	}
 }
+
+// consumer runs a SaramaDriver against a fakeBroker. Emitted frames are
+// queued on frames and stay unacked until the test acks them, as with a
+// slow sink.
+type consumer struct {
+	t      *testing.T
+	d      *SaramaDriver
+	b      *fakeBroker
+	frames chan *pb.Frame
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func startConsumer(t *testing.T, mode CommitMode, capacity int64, parts ...int32) *consumer {
+	t.Helper()
+	if len(parts) == 0 {
+		parts = []int32{0}
+	}
+	b := newFakeBroker("orders", parts...)
+	d := &SaramaDriver{}
+	d.init(Config{
+		Topics:       []string{"orders"},
+		CommitMode:   mode,
+		BackPressure: BackPressureCfg{Capacity: capacity, CheckInt: time.Hour},
+		Checkpoint:   CheckpointCfg{CommitInt: time.Hour},
+	}, &fakeGroup{b: b})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &consumer{t: t, d: d, b: b, frames: make(chan *pb.Frame, 64), cancel: cancel, done: make(chan error, 1)}
+	go func() {
+		c.done <- d.Run(ctx, func(f *pb.Frame) error {
+			c.frames <- f
+			return nil
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-c.done
+		_ = d.Close()
+	})
+	c.session()
+	return c
+}
+
+// session waits for the next consumer group session to be set up.
+func (c *consumer) session() {
+	c.t.Helper()
+	select {
+	case <-c.b.sessions:
+	case <-time.After(time.Second):
+		c.t.Fatal("no consumer group session")
+	}
+}
+
+// next returns the next emitted frame's offset and partition.
+func (c *consumer) next() (int32, int64) {
+	c.t.Helper()
+	select {
+	case f := <-c.frames:
+		k := f.Checkpoint.GetKafka()
+		return k.Partition, k.Offset
+	case <-time.After(time.Second):
+		c.t.Fatal("no frame emitted")
+		return 0, 0
+	}
+}
+
+// quiet fails if a frame is emitted within a short while.
+func (c *consumer) quiet() {
+	c.t.Helper()
+	select {
+	case f := <-c.frames:
+		c.t.Fatalf("unexpected frame at offset %d", f.Checkpoint.GetKafka().GetOffset())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (c *consumer) ack(partition int32, offset int64) {
+	c.d.OnAck(&pb.ConnectorAck{Checkpoint: makeKafkaToken("orders", partition, offset)})
+}
+
+// settle waits until n records are awaiting an ack, so acks sent so far
+// have been handled.
+func (c *consumer) settle(n int64) {
+	c.t.Helper()
+	waitUntil(c.t, func() bool { return c.d.Stats().InFlight == n }, "in-flight records to reach %d", n)
+}
+
+func waitUntil(t *testing.T, cond func() bool, format string, args ...any) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for "+format, args...)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSaramaDriver_E2E_OutOfOrderAcksCommitContiguousOffsets(t *testing.T) {
+	c := startConsumer(t, CommitE2E, 8)
+	for i := 0; i < 4; i++ {
+		c.b.produce(0, "v")
+	}
+	for want := int64(0); want < 4; want++ {
+		if _, off := c.next(); off != want {
+			t.Fatalf("emitted offset %d, want %d", off, want)
+		}
+	}
+
+	c.ack(0, 2)
+	c.ack(0, 1)
+	c.settle(2)
+	c.d.Commit()
+	if got := c.b.committedOffset(0); got != -1 {
+		t.Fatalf("committed %d while offset 0 is unacked", got)
+	}
+
+	c.ack(0, 0)
+	c.settle(1)
+	c.d.Commit()
+	if got := c.b.committedOffset(0); got != 3 {
+		t.Fatalf("committed %d, want 3", got)
+	}
+
+	c.ack(0, 3)
+	c.settle(0)
+	c.d.Commit()
+	if got := c.b.committedOffset(0); got != 4 {
+		t.Fatalf("committed %d, want 4", got)
+	}
+}
+
+func TestSaramaDriver_E2E_PartitionsCommitIndependently(t *testing.T) {
+	c := startConsumer(t, CommitE2E, 8, 0, 1)
+	c.b.produce(0, "a")
+	c.b.produce(1, "b")
+	c.next()
+	c.next()
+
+	c.ack(1, 0)
+	c.settle(1)
+	if got := c.b.markedOffset(1); got != 1 {
+		t.Fatalf("partition 1 marked %d, want 1", got)
+	}
+	if got := c.b.markedOffset(0); got != -1 {
+		t.Fatalf("partition 0 marked %d before its ack", got)
+	}
+}
+
+func TestSaramaDriver_AutoCommitMarksOnEmit(t *testing.T) {
+	c := startConsumer(t, CommitAuto, 4)
+	c.b.produce(0, "a")
+	c.b.produce(0, "b")
+	c.next()
+	c.next()
+
+	waitUntil(t, func() bool { return c.b.markedOffset(0) == 2 }, "offset 2 to be marked")
+	// The claim's loop holds one token while it waits for the next record.
+	if st := c.d.Stats(); st.InFlight != 0 || st.BackpressureAvailable != 3 {
+		t.Fatalf("auto mode holds records: %+v", st)
+	}
+	// Acks are not needed in auto mode and must be harmless.
+	c.ack(0, 0)
+	c.quiet()
+}
+
+func TestSaramaDriver_SlowSinkHoldsFetchUntilAck(t *testing.T) {
+	c := startConsumer(t, CommitE2E, 2)
+	for i := 0; i < 5; i++ {
+		c.b.produce(0, "v")
+	}
+	c.next()
+	c.next()
+	c.quiet()
+	if st := c.d.Stats(); st.BackpressureAvailable != 0 || st.InFlight != 2 {
+		t.Fatalf("unexpected stats with the window full: %+v", st)
+	}
+
+	// Offset 0 still bounds the window, so acking offset 1 frees a token but
+	// fetches nothing.
+	c.ack(0, 1)
+	c.settle(1)
+	c.quiet()
+	c.d.Commit()
+	if got := c.b.committedOffset(0); got != -1 {
+		t.Fatalf("committed %d while offset 0 is unacked", got)
+	}
+
+	c.ack(0, 0)
+	for want := int64(2); want < 4; want++ {
+		if _, off := c.next(); off != want {
+			t.Fatalf("emitted offset %d, want %d", off, want)
+		}
+	}
+	c.quiet()
+	c.d.Commit()
+	if got := c.b.committedOffset(0); got != 2 {
+		t.Fatalf("committed %d, want 2", got)
+	}
+
+	// Acks handled while the loop waits must not leak tokens.
+	c.ack(0, 2)
+	if _, off := c.next(); off != 4 {
+		t.Fatalf("emitted offset %d, want 4", off)
+	}
+	c.ack(0, 3)
+	c.ack(0, 4)
+	c.settle(0)
+	c.d.Commit()
+	if got := c.b.committedOffset(0); got != 5 {
+		t.Fatalf("committed %d, want 5", got)
+	}
+	waitUntil(t, func() bool { return c.d.Stats().BackpressureAvailable == 1 }, "tokens to be returned")
+}
+
+func TestSaramaDriver_RebalanceDropsPendingAndRedelivers(t *testing.T) {
+	c := startConsumer(t, CommitE2E, 4)
+	for i := 0; i < 3; i++ {
+		c.b.produce(0, "v")
+	}
+	c.next()
+	c.next()
+	c.next()
+	c.ack(0, 0)
+	c.settle(2)
+
+	c.b.rebalance()
+	c.session()
+	// The session committed its mark on the way out; unacked records come
+	// back and the old session's tokens were returned.
+	if got := c.b.committedOffset(0); got != 1 {
+		t.Fatalf("committed %d across the rebalance, want 1", got)
+	}
+	for want := int64(1); want < 3; want++ {
+		if _, off := c.next(); off != want {
+			t.Fatalf("redelivered offset %d, want %d", off, want)
+		}
+	}
+	c.settle(2)
+	// Two records in flight and one token held by the waiting loop.
+	if got := c.d.Stats().BackpressureAvailable; got != 1 {
+		t.Fatalf("backpressure tokens available %d, want 1", got)
+	}
+
+	c.ack(0, 2)
+	c.ack(0, 1)
+	c.settle(0)
+	c.d.Commit()
+	if got := c.b.committedOffset(0); got != 3 {
+		t.Fatalf("committed %d, want 3", got)
+	}
+	waitUntil(t, func() bool { return c.d.Stats().BackpressureAvailable == 3 }, "tokens to be returned")
+}
+
+func TestSaramaDriver_ShutdownCommitsOnlyAckedOffsets(t *testing.T) {
+	c := startConsumer(t, CommitE2E, 4)
+	for i := 0; i < 3; i++ {
+		c.b.produce(0, "v")
+	}
+	c.next()
+	c.next()
+	c.next()
+	c.ack(0, 0)
+	c.ack(0, 2)
+	c.settle(1)
+
+	c.cancel()
+	select {
+	case err := <-c.done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run returned %v, want context.Canceled", err)
+		}
+		c.done <- err
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	if got := c.b.committedOffset(0); got != 1 {
+		t.Fatalf("committed %d at shutdown, want 1", got)
+	}
+
+	// A late ack after shutdown is dropped, not committed.
+	c.ack(0, 1)
+	c.d.Commit()
+	if got := c.b.committedOffset(0); got != 1 {
+		t.Fatalf("late ack moved the commit to %d", got)
+	}
+}
+
+func TestSaramaDriver_CommitsOncePerInterval(t *testing.T) {
+	c := startConsumer(t, CommitE2E, 4)
+	c.b.produce(0, "a")
+	c.b.produce(0, "b")
+	c.next()
+	c.next()
+
+	// The first resolved record is always due; the next one within the
+	// hour-long interval is only marked.
+	c.ack(0, 0)
+	c.settle(1)
+	if got := c.b.committedOffset(0); got != 1 {
+		t.Fatalf("committed %d, want 1", got)
+	}
+	commits := c.b.commitCount()
+	c.ack(0, 1)
+	c.settle(0)
+	if got := c.b.markedOffset(0); got != 2 {
+		t.Fatalf("marked %d, want 2", got)
+	}
+	if got := c.b.committedOffset(0); got != 1 || c.b.commitCount() != commits {
+		t.Fatalf("committed %d (%d commits) before the interval elapsed", got, c.b.commitCount())
+	}
+	c.d.Commit()
+	if got := c.b.committedOffset(0); got != 2 {
+		t.Fatalf("committed %d after Commit, want 2", got)
+	}
+}
@@ -0,0 +1,48 @@
+package kafka
+
+import (
+	"errors"
+
+	"quanta/source"
+
+	"github.com/IBM/sarama"
+)
+
+// Fatal marks err as one restarting the source cannot fix, so the
+// supervisor fails the pipeline instead of retrying.
+func Fatal(err error) error { return source.Fatal(err) }
+
+// IsFatal reports whether a source error needs operator action: errors
+// marked with Fatal, invalid configuration, authentication and authorization
+// failures, missing topics and a closed consumer group. Anything else, such
+// as lost brokers or a rebalance gone wrong, is worth retrying.
+func IsFatal(err error) bool {
+	if err == nil {
+		return false
+	}
+	if source.IsFatal(err) {
+		return true
+	}
+	var ce sarama.ConfigurationError
+	if errors.As(err, &ce) {
+		return true
+	}
+	if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+		return true
+	}
+	var kerr sarama.KError
+	if errors.As(err, &kerr) {
+		switch kerr {
+		case sarama.ErrTopicAuthorizationFailed,
+			sarama.ErrGroupAuthorizationFailed,
+			sarama.ErrClusterAuthorizationFailed,
+			sarama.ErrSASLAuthenticationFailed,
+			sarama.ErrUnsupportedSASLMechanism,
+			sarama.ErrIllegalSASLState,
+			sarama.ErrUnknownTopicOrPartition,
+			sarama.ErrInvalidTopic:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+func TestIsFatal(t *testing.T) {
+	cases := []struct {
+		err   error
+		fatal bool
+	}{
+		{sarama.ErrOutOfBrokers, false},
+		{errors.New("connection reset"), false},
+		{sarama.ErrNotCoordinatorForConsumer, false},
+		{sarama.ErrSASLAuthenticationFailed, true},
+		{sarama.ErrTopicAuthorizationFailed, true},
+		{sarama.ErrUnknownTopicOrPartition, true},
+		{sarama.ConfigurationError("bad"), true},
+		{sarama.ErrClosedConsumerGroup, true},
+		{Fatal(errors.New("custom")), true},
+	}
+	for _, c := range cases {
+		if got := IsFatal(c.err); got != c.fatal {
+			t.Errorf("IsFatal(%v) = %v, want %v", c.err, got, c.fatal)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// fakeBroker is an in-process stand-in for a Kafka cluster and consumer
+// group coordinator: partition logs, committed offsets and one group member
+// whose session is rebalanced on demand. Like Sarama with auto-commit, a
+// session commits its marked offsets when it ends.
+type fakeBroker struct {
+	mu        sync.Mutex
+	topic     string
+	parts     []int32
+	logs      map[int32][]*sarama.ConsumerMessage
+	committed map[int32]int64
+	commits   int
+	sess      *fakeSession
+	cancel    context.CancelFunc
+	sessions  chan *fakeSession
+}
+
+func newFakeBroker(topic string, parts ...int32) *fakeBroker {
+	return &fakeBroker{
+		topic:     topic,
+		parts:     parts,
+		logs:      map[int32][]*sarama.ConsumerMessage{},
+		committed: map[int32]int64{},
+		sessions:  make(chan *fakeSession, 16),
+	}
+}
+
+// produce appends a record to partition and delivers it to the claim
+// consuming it, if any.
+func (b *fakeBroker) produce(partition int32, value string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msg := &sarama.ConsumerMessage{
+		Topic: b.topic, Partition: partition, Offset: int64(len(b.logs[partition])), Value: []byte(value),
+	}
+	b.logs[partition] = append(b.logs[partition], msg)
+	if b.sess != nil {
+		b.sess.claims[partition].msgs <- msg
+	}
+}
+
+// rebalance ends the current session; the member rejoins with the same
+// partitions, which resume from their committed offsets.
+func (b *fakeBroker) rebalance() {
+	b.mu.Lock()
+	cancel := b.cancel
+	b.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// committedOffset is the next offset the group would consume from
+// partition, or -1 if nothing was committed.
+func (b *fakeBroker) committedOffset(partition int32) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if off, ok := b.committed[partition]; ok {
+		return off
+	}
+	return -1
+}
+
+// markedOffset is the offset the current session has marked for
+// partition, or -1.
+func (b *fakeBroker) markedOffset(partition int32) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.sess == nil {
+		return -1
+	}
+	if off, ok := b.sess.marks[partition]; ok {
+		return off
+	}
+	return -1
+}
+
+func (b *fakeBroker) commitCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.commits
+}
+
+// fakeGroup implements sarama.ConsumerGroup on a fakeBroker.
+type fakeGroup struct{ b *fakeBroker }
+
+func (g *fakeGroup) Consume(ctx context.Context, _ []string, h sarama.ConsumerGroupHandler) error {
+	b := g.b
+	sctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sess := &fakeSession{ctx: sctx, b: b, marks: map[int32]int64{}, claims: map[int32]*fakeClaim{}}
+
+	b.mu.Lock()
+	for _, p := range b.parts {
+		c := &fakeClaim{topic: b.topic, partition: p, msgs: make(chan *sarama.ConsumerMessage, 1024)}
+		from, ok := b.committed[p]
+		if !ok {
+			from = 0
+		}
+		c.initial = from
+		for _, msg := range b.logs[p][from:] {
+			c.msgs <- msg
+		}
+		sess.claims[p] = c
+	}
+	b.sess, b.cancel = sess, cancel
+	b.mu.Unlock()
+
+	if err := h.Setup(sess); err != nil {
+		return err
+	}
+	b.sessions <- sess
+	var wg sync.WaitGroup
+	for _, c := range sess.claims {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = h.ConsumeClaim(sess, c)
+		}()
+	}
+	wg.Wait()
+	err := h.Cleanup(sess)
+
+	b.mu.Lock()
+	sess.commitLocked()
+	b.sess, b.cancel = nil, nil
+	b.mu.Unlock()
+	return err
+}
+
+func (g *fakeGroup) Errors() <-chan error      { return nil }
+func (g *fakeGroup) Close() error              { return nil }
+func (g *fakeGroup) Pause(map[string][]int32)  {}
+func (g *fakeGroup) Resume(map[string][]int32) {}
+func (g *fakeGroup) PauseAll()                 {}
+func (g *fakeGroup) ResumeAll()                {}
+
+type fakeSession struct {
+	ctx    context.Context
+	b      *fakeBroker
+	marks  map[int32]int64
+	claims map[int32]*fakeClaim
+}
+
+func (s *fakeSession) Claims() map[string][]int32 {
+	out := map[string][]int32{}
+	for p := range s.claims {
+		out[s.b.topic] = append(out[s.b.topic], p)
+	}
+	return out
+}
+
+func (s *fakeSession) MemberID() string                         { return "member-1" }
+func (s *fakeSession) GenerationID() int32                      { return 1 }
+func (s *fakeSession) Context() context.Context                 { return s.ctx }
+func (s *fakeSession) ResetOffset(string, int32, int64, string) {}
+
+// MarkOffset only moves a partition's mark forward, as Sarama does.
+func (s *fakeSession) MarkOffset(_ string, partition int32, offset int64, _ string) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	if cur, ok := s.marks[partition]; !ok || offset > cur {
+		s.marks[partition] = offset
+	}
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, md string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, md)
+}
+
+func (s *fakeSession) Commit() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	s.commitLocked()
+}
+
+func (s *fakeSession) commitLocked() {
+	for p, off := range s.marks {
+		s.b.committed[p] = off
+	}
+	s.b.commits++
+}
+
+type fakeClaim struct {
+	topic     string
+	partition int32
+	initial   int64
+	msgs      chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string                            { return c.topic }
+func (c *fakeClaim) Partition() int32                         { return c.partition }
+func (c *fakeClaim) InitialOffset() int64                     { return c.initial }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return c.initial + int64(len(c.msgs)) }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.msgs }
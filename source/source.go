@@ -0,0 +1,115 @@
+// Package source defines what a pipeline reads from. Kafka is built in
+// (source/kafka); other kinds register a factory here and are named by
+// source.kind in a pipeline spec or set directly on a pipeline builder.
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	pb "quanta/api/proto/v1"
+)
+
+// EmitFunc hands a frame to the pipeline. It blocks while the frame is
+// processed and returns an error only when the pipeline could not take it.
+type EmitFunc func(*pb.Frame) error
+
+// Source produces frames until ctx is done. Run returning early with an
+// error makes the pipeline restart it with backoff, unless the error is
+// marked Fatal. Frames should carry a checkpoint; it comes back through
+// AckAware once every sink has the frame.
+type Source interface {
+	Run(ctx context.Context, emit EmitFunc) error
+	Close() error
+}
+
+// Configurable is implemented by registered sources that take settings: a
+// v2 spec's source.config block, as decoded from YAML.
+type Configurable interface {
+	Configure(config map[string]any) error
+}
+
+// AckAware is implemented by sources that release or commit a frame's
+// checkpoint once the pipeline is done with it.
+type AckAware interface {
+	OnAck(*pb.ConnectorAck)
+}
+
+// Pausable is implemented by sources that can stop fetching without
+// giving up what they own, such as a consumer group membership.
+type Pausable interface {
+	Pause()
+	Resume()
+}
+
+// Committer is implemented by sources that can flush acked checkpoints on
+// demand, used for the final commit during a drain.
+type Committer interface {
+	Commit()
+}
+
+// ReadyReporter is implemented by sources that can tell whether they are
+// connected and have something to consume; Ready returns why not.
+type ReadyReporter interface {
+	Ready() error
+}
+
+type fatalError struct{ err error }
+
+func (e *fatalError) Error() string { return e.err.Error() }
+func (e *fatalError) Unwrap() error { return e.err }
+
+// Fatal marks err as one restarting the source cannot fix, so the
+// supervisor fails the pipeline instead of retrying.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fatalError{err: err}
+}
+
+// IsFatal reports whether err was marked with Fatal.
+func IsFatal(err error) bool {
+	var fe *fatalError
+	return errors.As(err, &fe)
+}
+
+// Factory makes a new, unconfigured source.
+type Factory func() Source
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Factory{}
+)
+
+// Register makes a source kind available to pipeline specs and builders.
+// Registering a kind again replaces it. "kafka" is reserved for the built-in
+// source, whose drivers register with kafka.Register.
+func Register(kind string, f Factory) {
+	if kind == "" || kind == "kafka" {
+		panic(fmt.Sprintf("source: cannot register kind %q", kind))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	registry[kind] = f
+}
+
+// Unregister removes a kind registered with Register.
+func Unregister(kind string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(registry, kind)
+}
+
+// New makes a source of a registered kind.
+func New(kind string) (Source, error) {
+	mu.RLock()
+	f, ok := registry[kind]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported source %q", kind)
+	}
+	return f(), nil
+}